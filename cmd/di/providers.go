@@ -1,31 +1,48 @@
 package di
 
 import (
+	"MoonAgent/internal/agents/orchestration"
+	"MoonAgent/pkg/approval"
 	"MoonAgent/pkg/config"
 	"MoonAgent/pkg/embedder"
+	"MoonAgent/pkg/knowledge"
+	"MoonAgent/pkg/mcpclient"
 	userClient "MoonAgent/pkg/milvus"
+	"MoonAgent/pkg/telemetry"
+	"MoonAgent/pkg/toolcheck"
+	"MoonAgent/pkg/tooloutput"
+	"MoonAgent/pkg/toolpolicy"
 	"context"
 
-	retriever "MoonAgent/pkg/retriever"
-
-	indexer "github.com/cloudwego/eino-ext/components/indexer/milvus"
-
-	fields "MoonAgent/pkg/indexer"
-
-	"github.com/cloudwego/eino-ext/components/embedding/ark"
-	"github.com/cloudwego/eino-ext/components/retriever/milvus"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/retriever"
 	"github.com/google/wire"
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
 )
 
 // Application 应用程序结构体
 type Application struct {
-	ServerConfig  *config.ServerConfig
-	MilvusClient  *client.Client
-	Embedder      *ark.Embedder
-	IndexerConfig *indexer.IndexerConfig
-	Indexer       *indexer.Indexer
-	Retriever     *milvus.Retriever
+	ServerConfig *config.ServerConfig
+	// 使用本地向量库时为 nil
+	MilvusClient *client.Client
+	Embedder     embedding.Embedder
+	// 未启用向量缓存时为 nil
+	EmbeddingCache *embedder.Cache
+	KnowledgeBases *knowledge.Manager
+	Retriever      retriever.Retriever
+	Sessions       *orchestration.SessionStore
+	// 未启用链路追踪时为 nil
+	Telemetry *telemetry.Provider
+	// 没有启用外部 MCP 服务时为 nil
+	MCP *mcpclient.Manager
+	// 工具参数校验和统计
+	ToolValidator *toolcheck.Validator
+	// 被截断的工具输出原文
+	ToolOutputs *tooloutput.Store
+	// 工具权限策略
+	ToolPolicy *toolpolicy.Policy
+	// 等待人工审批的危险操作
+	Approvals *approval.Manager
 }
 
 // ProvideContext 提供上下文
@@ -37,18 +54,32 @@ func ProvideContext() context.Context {
 func ProvideApplication(
 	serverConfig *config.ServerConfig,
 	milvusClient *client.Client,
-	embedder *ark.Embedder,
-	retriever *milvus.Retriever,
-	indexerConfig *indexer.IndexerConfig,
-	indexer *indexer.Indexer,
+	embedder embedding.Embedder,
+	embeddingCache *embedder.Cache,
+	knowledgeBases *knowledge.Manager,
+	retriever retriever.Retriever,
+	sessions *orchestration.SessionStore,
+	telemetryProvider *telemetry.Provider,
+	mcpManager *mcpclient.Manager,
+	toolValidator *toolcheck.Validator,
+	toolOutputs *tooloutput.Store,
+	toolPolicy *toolpolicy.Policy,
+	approvals *approval.Manager,
 ) *Application {
 	return &Application{
-		ServerConfig:  serverConfig,
-		MilvusClient:  milvusClient,
-		Embedder:      embedder,
-		Retriever:     retriever,
-		IndexerConfig: indexerConfig,
-		Indexer:       indexer,
+		ServerConfig:   serverConfig,
+		MilvusClient:   milvusClient,
+		Embedder:       embedder,
+		EmbeddingCache: embeddingCache,
+		KnowledgeBases: knowledgeBases,
+		Retriever:      retriever,
+		Sessions:       sessions,
+		Telemetry:      telemetryProvider,
+		MCP:            mcpManager,
+		ToolValidator:  toolValidator,
+		ToolOutputs:    toolOutputs,
+		ToolPolicy:     toolPolicy,
+		Approvals:      approvals,
 	}
 }
 
@@ -57,18 +88,23 @@ var ProviderSet = wire.NewSet(
 	// 1. 首先提供基础依赖
 	ProvideContext,
 	config.NewConfig,
+	telemetry.ProvideTelemetry,
 
 	// 2. 提供中间依赖
 	userClient.ProvideMilvusClient,
+	embedder.ProvideCache,
 	embedder.ProvideEmbedder,
 
-	// 3. 提供配置
-	fields.NewIndexerConfig,
-
-	// 4. 提供主要组件
-	indexer.NewIndexer,
-	retriever.ProvideRetriever,
+	// 3. 提供主要组件
+	knowledge.ProvideManager,
+	knowledge.ProvideRetriever,
+	orchestration.ProvideSessionStore,
+	mcpclient.ProvideManager,
+	toolcheck.ProvideValidator,
+	tooloutput.ProvideStore,
+	toolpolicy.ProvidePolicy,
+	approval.ProvideManager,
 
-	// 5. 最后提供应用实例
+	// 4. 最后提供应用实例
 	ProvideApplication,
 )
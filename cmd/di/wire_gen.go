@@ -7,12 +7,17 @@
 package di
 
 import (
+	"MoonAgent/internal/agents/orchestration"
+	"MoonAgent/pkg/approval"
 	"MoonAgent/pkg/config"
 	"MoonAgent/pkg/embedder"
-	"MoonAgent/pkg/indexer"
+	"MoonAgent/pkg/knowledge"
+	"MoonAgent/pkg/mcpclient"
 	"MoonAgent/pkg/milvus"
-	"MoonAgent/pkg/retriever"
-	milvus2 "github.com/cloudwego/eino-ext/components/indexer/milvus"
+	"MoonAgent/pkg/telemetry"
+	"MoonAgent/pkg/toolcheck"
+	"MoonAgent/pkg/tooloutput"
+	"MoonAgent/pkg/toolpolicy"
 )
 
 // Injectors from wire.go:
@@ -28,21 +33,48 @@ func InitializeApplication() (*Application, func(), error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	arkEmbedder, err := embedder.ProvideEmbedder(serverConfig)
+	cache, cleanup, err := embedder.ProvideCache(serverConfig)
 	if err != nil {
 		return nil, nil, err
 	}
-	milvusRetriever, err := retriever.ProvideRetriever(client, arkEmbedder)
+	embeddingEmbedder, err := embedder.ProvideEmbedder(serverConfig, cache)
 	if err != nil {
+		cleanup()
 		return nil, nil, err
 	}
-	indexerConfig := indexer.NewIndexerConfig(client, arkEmbedder)
-	context := ProvideContext()
-	milvusIndexer, err := milvus2.NewIndexer(context, indexerConfig)
+	manager, err := knowledge.ProvideManager(serverConfig, client, cache)
 	if err != nil {
+		cleanup()
 		return nil, nil, err
 	}
-	application := ProvideApplication(serverConfig, client, arkEmbedder, milvusRetriever, indexerConfig, milvusIndexer)
+	retriever := knowledge.ProvideRetriever(manager)
+	sessionStore := orchestration.ProvideSessionStore(serverConfig)
+	provider, cleanup2, err := telemetry.ProvideTelemetry(serverConfig)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	mcpclientManager, cleanup3, err := mcpclient.ProvideManager(serverConfig)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	validator := toolcheck.ProvideValidator(serverConfig)
+	store := tooloutput.ProvideStore(serverConfig)
+	policy, cleanup4, err := toolpolicy.ProvidePolicy(serverConfig)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	approvalManager := approval.ProvideManager(serverConfig)
+	application := ProvideApplication(serverConfig, client, embeddingEmbedder, cache, manager, retriever, sessionStore, provider, mcpclientManager, validator, store, policy, approvalManager)
 	return application, func() {
+		cleanup4()
+		cleanup3()
+		cleanup2()
+		cleanup()
 	}, nil
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"MoonAgent/cmd/di"
+	"MoonAgent/internal/eval"
+	"MoonAgent/internal/pipeline"
+	"MoonAgent/pkg/knowledge"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 离线评测：读取 JSONL 评测集，输出检索指标和回答忠实度报告
+//
+//	go run . -golden ../../assets/eval/golden.jsonl -k 5 -judge scripted -script ../../assets/eval/judge_script.json -out report.json
+func main() {
+	golden := flag.String("golden", "../../assets/eval/golden.jsonl", "评测集路径，JSONL 格式")
+	k := flag.Int("k", eval.DefaultK, "计算 recall@k 等指标使用的检索数量")
+	answer := flag.Bool("answer", true, "是否运行完整流水线生成回答")
+	judgeName := flag.String("judge", "llm", "回答评审方式: llm / scripted / none")
+	script := flag.String("script", "../../assets/eval/judge_script.json", "scripted 评审的脚本文件")
+	out := flag.String("out", "", "报告输出路径，为空时输出到标准输出")
+	flag.Parse()
+
+	ctx := context.Background()
+	cases, err := eval.LoadGoldenSet(*golden)
+	if err != nil {
+		panic(err)
+	}
+
+	app, clear, err := di.InitializeApplication()
+	if err != nil {
+		panic(err)
+	}
+	defer clear()
+	// 评测时没有 HTTP 审批接口，需要审批的命令直接拒绝，不必等到超时
+	app.Approvals.Close()
+
+	runner := &eval.Runner{
+		Retriever: app.Retriever,
+		K:         *k,
+	}
+	if *answer {
+		// 流水线只编译一次，所有样本共用
+		runnable, err := pipeline.BuildAssitant(ctx, app)
+		if err != nil {
+			panic(err)
+		}
+		runner.Answerer = &pipelineAnswerer{app: app, runnable: runnable}
+		switch *judgeName {
+		case "llm":
+			chatModel, err := pipeline.NewChatModel(ctx, app)
+			if err != nil {
+				panic(err)
+			}
+			runner.Judge = eval.NewLLMJudge(chatModel)
+		case "scripted":
+			judge, err := eval.LoadScriptedJudge(*script)
+			if err != nil {
+				panic(err)
+			}
+			runner.Judge = judge
+		case "none":
+		default:
+			panic(fmt.Sprintf("unknown judge: %s", *judgeName))
+		}
+	} else {
+		*judgeName = "none"
+	}
+
+	report := runner.Run(ctx, *golden, *judgeName, cases)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	data = append(data, '\n')
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		panic(err)
+	}
+	fmt.Printf("recall@%d=%.4f mrr=%.4f hit_rate=%.4f errors=%d, report written to %s\n",
+		report.K, report.Summary.RecallAtK, report.Summary.MRR, report.Summary.HitRate, report.Summary.Errors, *out)
+}
+
+// pipelineAnswerer 使用与聊天接口相同的流水线生成回答
+type pipelineAnswerer struct {
+	app      *di.Application
+	runnable compose.Runnable[string, *schema.Message]
+}
+
+func (a *pipelineAnswerer) Answer(ctx context.Context, c *eval.Case) (string, []string, error) {
+	ctx, trace := pipeline.WithTrace(ctx)
+
+	var opts []compose.Option
+	if len(c.KnowledgeBases) > 0 {
+		opts = append(opts, compose.WithRetrieverOption(knowledge.WithKnowledgeBases(c.KnowledgeBases...)))
+	}
+	if handler := a.app.Telemetry.Handler(); handler != nil {
+		opts = append(opts, compose.WithCallbacks(handler))
+	}
+	out, err := a.runnable.Invoke(ctx, c.Question, opts...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sources := trace.Sources()
+	contexts := make([]string, 0, len(sources))
+	for _, source := range sources {
+		contexts = append(contexts, source.Content)
+	}
+	return out.Content, contexts, nil
+}
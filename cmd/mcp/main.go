@@ -0,0 +1,42 @@
+package main
+
+import (
+	"MoonAgent/cmd/di"
+	"MoonAgent/internal/mcpserver"
+	"MoonAgent/internal/pipeline"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 以 MCP 服务的形式通过标准输入输出提供知识库和智能体，供 IDE 助手等 MCP 客户端调用
+//
+//	cd cmd/mcp && go run .
+func main() {
+	// 标准输出只用于 MCP 协议消息，其他组件写到标准输出的内容改写到标准错误
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	app, clear, err := di.InitializeApplication()
+	if err != nil {
+		panic(err)
+	}
+	defer clear()
+	// 这种运行方式没有 HTTP 审批接口，需要审批的命令直接拒绝，不必等到超时
+	app.Approvals.Close()
+	pipelines, err := pipeline.LoadPipelines(ctx, app)
+	if err != nil {
+		panic(err)
+	}
+
+	srv := server.NewStdioServer(mcpserver.NewServer(app, pipelines))
+	if err := srv.Listen(ctx, os.Stdin, stdout); err != nil && ctx.Err() == nil {
+		panic(err)
+	}
+}
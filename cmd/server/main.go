@@ -3,6 +3,8 @@ package main
 import (
 	"MoonAgent/cmd/di"
 	"MoonAgent/internal/api/router"
+	"MoonAgent/internal/pipeline"
+	"context"
 
 	"github.com/cloudwego/hertz/pkg/app/server"
 )
@@ -13,7 +15,13 @@ func main() {
 		panic(err)
 	}
 	defer clear()
-	H := server.Default()
-	router.InitRouter(H, app)
+	// 配置化流水线在启动时编译，定义有误时直接退出
+	pipelines, err := pipeline.LoadPipelines(context.Background(), app)
+	if err != nil {
+		panic(err)
+	}
+	// 客户端断开时取消请求的 ctx，停止仍在运行的流水线
+	H := server.Default(server.WithSenseClientDisconnection(true))
+	router.InitRouter(H, app, pipelines)
 	H.Spin()
 }
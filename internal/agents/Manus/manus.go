@@ -3,10 +3,13 @@ package manus
 import (
 	toolcallagent "MoonAgent/internal/agents/toolcall"
 	"MoonAgent/internal/agents/orchestration"
+	"MoonAgent/pkg/toolcheck"
 	"context"
 	"fmt"
 
+	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
 	"go.uber.org/zap"
 )
@@ -119,6 +122,29 @@ func (m *Manus) AddTool(tool schema.ToolInfo) {
 	m.logger.Info("添加工具", zap.String("tool", tool.Name))
 }
 
+// RegisterTool 注册带实现的工具
+func (m *Manus) RegisterTool(ctx context.Context, t tool.InvokableTool) error {
+	info, err := t.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.ToolCallAgent.RegisterTool(ctx, t); err != nil {
+		return err
+	}
+	m.logger.Info("注册工具", zap.String("tool", info.Name))
+	return nil
+}
+
+// SetCallbacks 设置 eino 回调，用于追踪智能体的每一步及其中的模型和工具调用
+func (m *Manus) SetCallbacks(handlers ...callbacks.Handler) {
+	m.ToolCallAgent.ReActAgent.BaseAgent.SetCallbacks(handlers...)
+}
+
+// SetValidator 设置工具参数校验
+func (m *Manus) SetValidator(v *toolcheck.Validator) {
+	m.ToolCallAgent.SetValidator(v)
+}
+
 // RemoveTool 移除工具
 func (m *Manus) RemoveTool(toolName string) {
 	m.ToolCallAgent.RemoveTool(toolName)
@@ -2,11 +2,13 @@ package baseagent
 
 import (
 	"MoonAgent/internal/constants"
+	"context"
 	"MoonAgent/internal/agents/orchestration"
 	"errors"
 	"strconv"
 	"strings"
 
+	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
 	"go.uber.org/zap"
@@ -45,6 +47,8 @@ type BaseAgent struct {
 	StepFunc func(octx *orchestration.OrchestrationContext) (*schema.Message, error)
 
 	chatModel model.ToolCallingChatModel
+	//运行时触发的eino回调
+	callbacks []callbacks.Handler
 }
 
 //返回新的BaseAgent结构体
@@ -71,6 +75,12 @@ func (a *BaseAgent) Run(octx *orchestration.OrchestrationContext, userInput stri
 	a.Reset()
 	a.state = constants.AgentStateRunning
 
+	// 触发运行回调，结束后恢复原来的上下文
+	parentCtx := octx.Context()
+	runCtx := a.onRunStart(parentCtx, userInput)
+	octx.SetContext(runCtx)
+	defer octx.SetContext(parentCtx)
+
 	// 设置用户输入到编排上下文
 	octx.SetInput("userPrompt", userInput)
 	octx.AddUserMessage(userInput)
@@ -95,9 +105,10 @@ func (a *BaseAgent) Run(octx *orchestration.OrchestrationContext, userInput stri
 			zap.Int("step", stepNumber),
 			zap.Int("maxSteps", a.maxSteps))
 
-		stepResult, err := a.StepFunc(octx)
+		stepResult, err := a.runStep(octx, runCtx, stepNumber)
 		if err != nil {
 			a.state = constants.AgentStateError
+			a.onRunEnd(runCtx, nil, err)
 			return nil, err
 		}
 
@@ -133,9 +144,21 @@ func (a *BaseAgent) Run(octx *orchestration.OrchestrationContext, userInput stri
 	// 添加最终响应到内存
 	octx.AddAssistantMessage(finalMessage.Content)
 
+	a.onRunEnd(runCtx, finalMessage, nil)
 	return finalMessage, nil
 }
 
+// runStep 执行一步并触发该步的回调
+func (a *BaseAgent) runStep(octx *orchestration.OrchestrationContext, runCtx context.Context, step int) (*schema.Message, error) {
+	stepCtx := a.onStepStart(runCtx, step)
+	octx.SetContext(stepCtx)
+	defer octx.SetContext(runCtx)
+
+	result, err := a.StepFunc(octx)
+	a.onStepEnd(stepCtx, result, err)
+	return result, err
+}
+
 //流式实现
 func (a *BaseAgent) RunStream(octx *orchestration.OrchestrationContext, input string) (<-chan *schema.Message, error) {
 	if a.state != constants.AgentStateIdle {
@@ -151,6 +174,11 @@ func (a *BaseAgent) RunStream(octx *orchestration.OrchestrationContext, input st
 		a.Reset()
 		a.state = constants.AgentStateRunning
 
+		parentCtx := octx.Context()
+		runCtx := a.onRunStart(parentCtx, input)
+		octx.SetContext(runCtx)
+		defer octx.SetContext(parentCtx)
+
 		// 设置用户输入到编排上下文
 		octx.SetInput("userPrompt", input)
 		octx.AddUserMessage(input)
@@ -165,9 +193,10 @@ func (a *BaseAgent) RunStream(octx *orchestration.OrchestrationContext, input st
 			stepNumber := i + 1
 			a.currentStep = stepNumber
 
-			stepResult, err := a.StepFunc(octx)
+			stepResult, err := a.runStep(octx, runCtx, stepNumber)
 			if err != nil {
 				a.state = constants.AgentStateError
+				a.onRunEnd(runCtx, nil, err)
 				errorMessage := &schema.Message{
 					Role:    "assistant",
 					Content: "Error: " + err.Error(),
@@ -195,6 +224,7 @@ func (a *BaseAgent) RunStream(octx *orchestration.OrchestrationContext, input st
 			Content: "Task completed",
 		}
 		octx.AddAssistantMessage(completionMessage.Content)
+		a.onRunEnd(runCtx, completionMessage, nil)
 		resultChan <- completionMessage
 	}()
 
@@ -0,0 +1,79 @@
+package baseagent
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 智能体在 eino 回调中的组件类别
+const (
+	ComponentOfAgent     components.Component = "Agent"
+	ComponentOfAgentStep components.Component = "AgentStep"
+)
+
+// SetCallbacks 设置 eino 回调，智能体的运行、每一步以及其中的模型和工具调用都会触发，例如用于链路追踪
+func (a *BaseAgent) SetCallbacks(handlers ...callbacks.Handler) {
+	a.callbacks = handlers
+}
+
+// onRunStart 在运行开始时初始化回调，没有回调时原样返回
+func (a *BaseAgent) onRunStart(ctx context.Context, input string) context.Context {
+	if len(a.callbacks) == 0 {
+		return ctx
+	}
+	ctx = callbacks.InitCallbacks(ctx, &callbacks.RunInfo{
+		Name:      a.name,
+		Type:      "BaseAgent",
+		Component: ComponentOfAgent,
+	}, a.callbacks...)
+	return callbacks.OnStart(ctx, input)
+}
+
+func (a *BaseAgent) onRunEnd(ctx context.Context, output *schema.Message, err error) {
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return
+	}
+	callbacks.OnEnd(ctx, output)
+}
+
+// onStepStart 每一步作为运行的子节点
+func (a *BaseAgent) onStepStart(ctx context.Context, step int) context.Context {
+	ctx = callbacks.ReuseHandlers(ctx, &callbacks.RunInfo{
+		Name:      a.name + ".step" + strconv.Itoa(step),
+		Type:      "BaseAgent",
+		Component: ComponentOfAgentStep,
+	})
+	return callbacks.OnStart(ctx, step)
+}
+
+func (a *BaseAgent) onStepEnd(ctx context.Context, result *schema.Message, err error) {
+	a.onRunEnd(ctx, result, err)
+}
+
+// Generate 调用模型并触发模型回调，模型自身未实现回调时在这里补上
+func Generate(ctx context.Context, cm model.BaseChatModel, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	typ, _ := components.GetType(cm)
+	ctx = callbacks.ReuseHandlers(ctx, &callbacks.RunInfo{
+		Name:      typ,
+		Type:      typ,
+		Component: components.ComponentOfChatModel,
+	})
+	if components.IsCallbacksEnabled(cm) {
+		return cm.Generate(ctx, input, opts...)
+	}
+
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: input})
+	resp, err := cm.Generate(ctx, input, opts...)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	callbacks.OnEnd(ctx, &model.CallbackOutput{Message: resp})
+	return resp, nil
+}
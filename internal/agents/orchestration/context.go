@@ -140,9 +140,18 @@ func NewOrchestrationContextWithMemory(ctx context.Context, memory MemoryState)
 
 // Context 获取底层的context.Context
 func (oc *OrchestrationContext) Context() context.Context {
+	oc.mu.RLock()
+	defer oc.mu.RUnlock()
 	return oc.ctx
 }
 
+// SetContext 替换底层的context.Context，用于在运行过程中挂载回调等信息
+func (oc *OrchestrationContext) SetContext(ctx context.Context) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	oc.ctx = ctx
+}
+
 // WithContext 返回使用新context的副本
 func (oc *OrchestrationContext) WithContext(ctx context.Context) *OrchestrationContext {
 	oc.mu.Lock()
@@ -0,0 +1,121 @@
+package orchestration
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"MoonAgent/pkg/config"
+)
+
+const (
+	defaultSessionTTL  = 30 * time.Minute
+	defaultMaxMessages = 100
+)
+
+// session 会话及其最近访问时间
+type session struct {
+	octx       *OrchestrationContext
+	lastAccess time.Time
+	// disabledTools 会话中禁用的工具
+	disabledTools []string
+}
+
+// SessionStore 按会话ID保存编排上下文，使多轮请求共享对话记忆
+// 超过 TTL 未访问的会话会在下一次访问时被清理
+type SessionStore struct {
+	ttl         time.Duration
+	maxMessages int
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// NewSessionStore 创建会话存储
+func NewSessionStore(ttl time.Duration, maxMessages int) *SessionStore {
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	if maxMessages <= 0 {
+		maxMessages = defaultMaxMessages
+	}
+	return &SessionStore{
+		ttl:         ttl,
+		maxMessages: maxMessages,
+		sessions:    make(map[string]*session),
+	}
+}
+
+// ProvideSessionStore 提供会话存储
+func ProvideSessionStore(cfg *config.ServerConfig) *SessionStore {
+	return NewSessionStore(cfg.SessionConfig.TTL, cfg.SessionConfig.MaxMessages)
+}
+
+// Get 获取会话的编排上下文，不存在或已过期时创建新的会话
+func (s *SessionStore) Get(id string) *OrchestrationContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(id).octx
+}
+
+// DisabledTools 会话中禁用的工具
+func (s *SessionStore) DisabledTools(id string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.get(id).disabledTools...)
+}
+
+// UpdateTools 在会话中启用或禁用工具，同时出现在两个列表中的工具以禁用为准，返回更新后禁用的工具
+func (s *SessionStore) UpdateTools(id string, enable, disable []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.get(id)
+	disabled := make([]string, 0, len(sess.disabledTools)+len(disable))
+	for _, name := range sess.disabledTools {
+		if !slices.Contains(enable, name) {
+			disabled = append(disabled, name)
+		}
+	}
+	for _, name := range disable {
+		if !slices.Contains(disabled, name) {
+			disabled = append(disabled, name)
+		}
+	}
+	sort.Strings(disabled)
+	sess.disabledTools = disabled
+	return append([]string(nil), disabled...)
+}
+
+// get 获取会话，不存在或已过期时创建新的会话（内部使用，需要持有锁）
+func (s *SessionStore) get(id string) *session {
+	now := time.Now()
+	s.expire(now)
+	if sess, ok := s.sessions[id]; ok {
+		sess.lastAccess = now
+		return sess
+	}
+
+	octx := NewOrchestrationContextWithMemory(context.Background(), NewSimpleMemoryState(s.maxMessages))
+	octx.SetMetadata("session_id", id)
+	sess := &session{octx: octx, lastAccess: now}
+	s.sessions[id] = sess
+	return sess
+}
+
+// Delete 删除会话
+func (s *SessionStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// expire 清理过期会话（内部使用，需要持有锁）
+func (s *SessionStore) expire(now time.Time) {
+	for id, sess := range s.sessions {
+		if now.Sub(sess.lastAccess) > s.ttl {
+			delete(s.sessions, id)
+		}
+	}
+}
@@ -128,7 +128,7 @@ func (ra *ReActAgent) Think(octx *orchestration.OrchestrationContext) (*schema.M
 
 	prompt := ra.buildThinkPrompt(userInput)
 
-	resp, err := ra.BaseAgent.GetChatModel().Generate(octx.Context(), []*schema.Message{
+	resp, err := baseagent.Generate(octx.Context(), ra.BaseAgent.GetChatModel(), []*schema.Message{
 		{Role: "system", Content: ra.BaseAgent.GetSystemPrompt()},
 		{Role: "user", Content: prompt},
 	})
@@ -148,7 +148,7 @@ func (ra *ReActAgent) Act(octx *orchestration.OrchestrationContext, thought stri
 	// 默认的行动实现
 	prompt := ra.buildActPrompt(thought)
 
-	resp, err := ra.BaseAgent.GetChatModel().Generate(octx.Context(), []*schema.Message{
+	resp, err := baseagent.Generate(octx.Context(), ra.BaseAgent.GetChatModel(), []*schema.Message{
 		{Role: "system", Content: ra.BaseAgent.GetSystemPrompt()},
 		{Role: "user", Content: prompt},
 	})
@@ -168,7 +168,7 @@ func (ra *ReActAgent) Observe(octx *orchestration.OrchestrationContext, action s
 	// 默认的观察实现
 	prompt := ra.buildObservePrompt(action)
 
-	resp, err := ra.BaseAgent.GetChatModel().Generate(octx.Context(), []*schema.Message{
+	resp, err := baseagent.Generate(octx.Context(), ra.BaseAgent.GetChatModel(), []*schema.Message{
 		{Role: "system", Content: ra.BaseAgent.GetSystemPrompt()},
 		{Role: "user", Content: prompt},
 	})
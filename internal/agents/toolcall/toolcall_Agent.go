@@ -1,22 +1,36 @@
 package toolcallagent
 
 import (
+	baseagent "MoonAgent/internal/agents/base"
 	reactagent "MoonAgent/internal/agents/reAct"
 	"MoonAgent/internal/agents/orchestration"
+	"MoonAgent/pkg/toolcheck"
+	"MoonAgent/pkg/toolpolicy"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
 	"github.com/cloudwego/eino/components/model"
+	einotool "github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
 	"go.uber.org/zap"
 )
 
+// pendingToolCallsKey 模型在思考阶段给出的结构化工具调用
+const pendingToolCallsKey = "pendingToolCalls"
+
 type ToolCallAgent struct {
 	ReActAgent *reactagent.ReActAgent
 	Tools      []schema.ToolInfo
 	toolMap    map[string]schema.ToolInfo
+	// executors 已注册实现的工具，注册后模型可直接发起结构化工具调用
+	executors map[string]einotool.InvokableTool
+	// validator 执行前校验工具参数，为 nil 时只校验不统计
+	validator *toolcheck.Validator
 }
 
 func NewToolCallAgent(name string, systemPrompt string, nextPrompt string, chatModel model.ToolCallingChatModel, tools []schema.ToolInfo) *ToolCallAgent {
@@ -24,6 +38,7 @@ func NewToolCallAgent(name string, systemPrompt string, nextPrompt string, chatM
 		ReActAgent: reactagent.NewReActAgent(name, systemPrompt, nextPrompt, chatModel),
 		Tools:      tools,
 		toolMap:    make(map[string]schema.ToolInfo),
+		executors:  make(map[string]einotool.InvokableTool),
 	}
 
 	// 构建工具映射
@@ -54,7 +69,17 @@ func (ta *ToolCallAgent) Think(octx *orchestration.OrchestrationContext, history
 	// 构建包含工具信息的系统提示
 	systemPrompt := ta.buildSystemPromptWithTools()
 
-	resp, err := ta.ReActAgent.BaseAgent.GetChatModel().Generate(octx.Context(), []*schema.Message{
+	chatModel := ta.ReActAgent.BaseAgent.GetChatModel()
+	if len(ta.executors) > 0 {
+		// 绑定可执行工具，让模型直接给出结构化的工具调用
+		boundModel, err := chatModel.WithTools(ta.executableToolInfos())
+		if err != nil {
+			return nil, err
+		}
+		chatModel = boundModel
+	}
+
+	resp, err := baseagent.Generate(octx.Context(), chatModel, []*schema.Message{
 		{Role: "system", Content: systemPrompt},
 		{Role: "user", Content: prompt},
 	})
@@ -63,11 +88,30 @@ func (ta *ToolCallAgent) Think(octx *orchestration.OrchestrationContext, history
 		return nil, err
 	}
 
+	if len(resp.ToolCalls) > 0 {
+		octx.SetInput(pendingToolCallsKey, resp.ToolCalls)
+		if resp.Content == "" {
+			// 仅返回工具调用时补充思考内容，保证进入行动阶段
+			var calls []string
+			for _, call := range resp.ToolCalls {
+				calls = append(calls, fmt.Sprintf("%s(%s)", call.Function.Name, call.Function.Arguments))
+			}
+			resp.Content = "需要调用工具: " + strings.Join(calls, ", ")
+		}
+	}
+
 	zap.L().Info("ToolCallAgent Think", zap.String("thought", resp.Content))
 	return resp, nil
 }
 
 func (ta *ToolCallAgent) Act(octx *orchestration.OrchestrationContext, thought string) (*schema.Message, error) {
+	if pending, ok := octx.GetInput(pendingToolCallsKey); ok {
+		octx.SetInput(pendingToolCallsKey, nil)
+		if toolCalls, ok := pending.([]schema.ToolCall); ok && len(toolCalls) > 0 {
+			return ta.actToolCalls(octx, toolCalls)
+		}
+	}
+
 	// 分析思考内容，确定需要调用的工具
 	toolCall := ta.parseToolCall(thought)
 
@@ -81,6 +125,9 @@ func (ta *ToolCallAgent) Act(octx *orchestration.OrchestrationContext, thought s
 
 	// 执行工具调用
 	result, err := ta.executeToolCall(octx, toolCall)
+	if errors.Is(err, toolcheck.ErrTooManyInvalidCalls) {
+		return nil, err
+	}
 	if err != nil {
 		return &schema.Message{
 			Role:    "assistant",
@@ -98,6 +145,32 @@ func (ta *ToolCallAgent) Act(octx *orchestration.OrchestrationContext, thought s
 	}, nil
 }
 
+// actToolCalls 依次执行模型给出的结构化工具调用，结果合并后供观察阶段使用
+func (ta *ToolCallAgent) actToolCalls(octx *orchestration.OrchestrationContext, toolCalls []schema.ToolCall) (*schema.Message, error) {
+	var actions, results []string
+	for i := range toolCalls {
+		call := &toolCalls[i]
+		actions = append(actions, fmt.Sprintf("调用工具 %s，参数: %s", call.Function.Name, call.Function.Arguments))
+
+		result, err := ta.executeToolCall(octx, call)
+		if errors.Is(err, toolcheck.ErrTooManyInvalidCalls) {
+			return nil, err
+		}
+		if err != nil {
+			result = fmt.Sprintf("工具调用失败: %s", err.Error())
+		}
+		results = append(results, fmt.Sprintf("[%s] %s", call.Function.Name, result))
+	}
+
+	octx.SetInput("lastToolResult", strings.Join(results, "\n\n"))
+
+	return &schema.Message{
+		Role:      "assistant",
+		Content:   strings.Join(actions, "\n"),
+		ToolCalls: toolCalls,
+	}, nil
+}
+
 func (ta *ToolCallAgent) Observe(octx *orchestration.OrchestrationContext, action string) (*schema.Message, error) {
 	// 观察工具调用的结果
 	var observation string
@@ -220,9 +293,28 @@ func (ta *ToolCallAgent) executeToolCall(octx *orchestration.OrchestrationContex
 		return "", fmt.Errorf("tool %s not found", toolCall.Function.Name)
 	}
 
-	// 这里应该调用实际的工具执行逻辑
-	// 目前返回模拟结果
-	result := fmt.Sprintf("工具 %s 执行成功，参数: %s", tool.Name, toolCall.Function.Arguments)
+	executor, exists := ta.executors[tool.Name]
+	if !exists {
+		return "", fmt.Errorf("tool %s has no executor, register it with RegisterTool", tool.Name)
+	}
+
+	// 参数不符合工具定义时不执行，把错误交给模型修正
+	feedback, err := ta.validator.Check(octx.Context(), &tool, toolCall.Function.Arguments)
+	if err != nil {
+		return "", err
+	}
+	if feedback != "" {
+		return feedback, nil
+	}
+
+	result, err := invokeTool(octx.Context(), tool.Name, executor, toolCall.Function.Arguments)
+	if err != nil {
+		zap.L().Error("Tool execution failed",
+			zap.String("tool", tool.Name),
+			zap.String("arguments", toolCall.Function.Arguments),
+			zap.Error(err))
+		return "", err
+	}
 
 	zap.L().Info("Tool executed",
 		zap.String("tool", tool.Name),
@@ -232,14 +324,59 @@ func (ta *ToolCallAgent) executeToolCall(octx *orchestration.OrchestrationContex
 	return result, nil
 }
 
-// Run 重写Run方法以支持工具调用
+// invokeTool 调用工具并触发工具回调，工具自身未实现回调时在这里补上
+func invokeTool(ctx context.Context, name string, t einotool.InvokableTool, arguments string) (string, error) {
+	typ, _ := components.GetType(t)
+	ctx = callbacks.ReuseHandlers(ctx, &callbacks.RunInfo{
+		Name:      name,
+		Type:      typ,
+		Component: components.ComponentOfTool,
+	})
+	if components.IsCallbacksEnabled(t) {
+		return t.InvokableRun(ctx, arguments)
+	}
+
+	ctx = callbacks.OnStart(ctx, &einotool.CallbackInput{ArgumentsInJSON: arguments})
+	result, err := t.InvokableRun(ctx, arguments)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return "", err
+	}
+	callbacks.OnEnd(ctx, &einotool.CallbackOutput{Response: result})
+	return result, nil
+}
+
+// Run 重写Run方法以支持工具调用，结束后恢复原来的上下文
 func (ta *ToolCallAgent) Run(octx *orchestration.OrchestrationContext, input string) (*schema.Message, error) {
+	parentCtx := octx.Context()
+	octx.SetContext(ta.runContext(parentCtx))
+	defer octx.SetContext(parentCtx)
 	return ta.ReActAgent.BaseAgent.Run(octx, input)
 }
 
-// RunStream 重写RunStream方法以支持流式工具调用
+// RunStream 重写RunStream方法以支持流式工具调用，流式运行在后台进行，输出结束后再恢复原来的上下文
 func (ta *ToolCallAgent) RunStream(octx *orchestration.OrchestrationContext, input string) (<-chan *schema.Message, error) {
-	return ta.ReActAgent.BaseAgent.RunStream(octx, input)
+	parentCtx := octx.Context()
+	octx.SetContext(ta.runContext(parentCtx))
+	stream, err := ta.ReActAgent.BaseAgent.RunStream(octx, input)
+	if err != nil {
+		octx.SetContext(parentCtx)
+		return nil, err
+	}
+	out := make(chan *schema.Message, cap(stream))
+	go func() {
+		defer close(out)
+		defer octx.SetContext(parentCtx)
+		for msg := range stream {
+			out <- msg
+		}
+	}()
+	return out, nil
+}
+
+// runContext 挂载本次运行的参数错误计数器，以及按智能体名称匹配工具权限规则所需的名称
+func (ta *ToolCallAgent) runContext(ctx context.Context) context.Context {
+	return toolpolicy.WithAgent(toolcheck.WithBudget(ctx), ta.ReActAgent.BaseAgent.GetName())
 }
 
 // Reset 重置状态
@@ -247,6 +384,11 @@ func (ta *ToolCallAgent) Reset() {
 	ta.ReActAgent.Reset()
 }
 
+// SetValidator 设置工具参数校验，参数错误计入其统计并使用其重试上限
+func (ta *ToolCallAgent) SetValidator(v *toolcheck.Validator) {
+	ta.validator = v
+}
+
 // GetTools 获取可用工具列表
 func (ta *ToolCallAgent) GetTools() []schema.ToolInfo {
 	return ta.Tools
@@ -258,9 +400,35 @@ func (ta *ToolCallAgent) AddTool(tool schema.ToolInfo) {
 	ta.toolMap[tool.Name] = tool
 }
 
+// RegisterTool 注册带实现的工具，执行时调用其 InvokableRun
+func (ta *ToolCallAgent) RegisterTool(ctx context.Context, t einotool.InvokableTool) error {
+	info, err := t.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if _, exists := ta.toolMap[info.Name]; exists {
+		ta.RemoveTool(info.Name)
+	}
+	ta.AddTool(*info)
+	ta.executors[info.Name] = t
+	return nil
+}
+
+// executableToolInfos 已注册实现的工具描述
+func (ta *ToolCallAgent) executableToolInfos() []*schema.ToolInfo {
+	var infos []*schema.ToolInfo
+	for i := range ta.Tools {
+		if _, ok := ta.executors[ta.Tools[i].Name]; ok {
+			infos = append(infos, &ta.Tools[i])
+		}
+	}
+	return infos
+}
+
 // RemoveTool 移除工具
 func (ta *ToolCallAgent) RemoveTool(toolName string) {
 	delete(ta.toolMap, toolName)
+	delete(ta.executors, toolName)
 
 	// 从切片中移除
 	for i, tool := range ta.Tools {
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/approval"
+	"context"
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+type ApprovalHandler struct {
+	app *di.Application
+}
+
+func NewApprovalHandler(app *di.Application) *ApprovalHandler {
+	return &ApprovalHandler{app: app}
+}
+
+type ApprovalDecisionReq struct {
+	// 审批意见，拒绝时会告诉智能体
+	Reason string `json:"reason,omitempty"`
+}
+
+// List 返回等待审批的操作
+func (h *ApprovalHandler) List(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, map[string]any{
+		"approvals": h.app.Approvals.Pending(),
+	})
+}
+
+// Approve 批准操作，等待中的工具调用继续执行
+func (h *ApprovalHandler) Approve(ctx context.Context, c *app.RequestContext) {
+	h.decide(c, true)
+}
+
+// Reject 拒绝操作，工具调用不执行并把原因返回给智能体
+func (h *ApprovalHandler) Reject(ctx context.Context, c *app.RequestContext) {
+	h.decide(c, false)
+}
+
+func (h *ApprovalHandler) decide(c *app.RequestContext, approved bool) {
+	var req ApprovalDecisionReq
+	if len(c.Request.Body()) > 0 {
+		if err := c.BindAndValidate(&req); err != nil {
+			c.JSON(consts.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	id := c.Param("id")
+	err := h.app.Approvals.Decide(id, approval.Decision{Approved: approved, Reason: req.Reason})
+	if errors.Is(err, approval.ErrNotFound) {
+		c.JSON(consts.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(consts.StatusOK, map[string]any{
+		"id":       id,
+		"approved": approved,
+	})
+}
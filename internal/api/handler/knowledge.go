@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/knowledge"
+	"context"
+	"errors"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+type KnowledgeHandler struct {
+	app *di.Application
+}
+
+func NewKnowledgeHandler(app *di.Application) *KnowledgeHandler {
+	return &KnowledgeHandler{app: app}
+}
+
+type IngestReq struct {
+	Documents []IngestDocument `json:"documents"`
+}
+
+type IngestDocument struct {
+	ID       string         `json:"id"`
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+func (h *KnowledgeHandler) List(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, map[string]any{
+		"default":        h.app.KnowledgeBases.DefaultName(),
+		"knowledgeBases": h.app.KnowledgeBases.List(),
+	})
+}
+
+func (h *KnowledgeHandler) Get(ctx context.Context, c *app.RequestContext) {
+	kb, err := h.app.KnowledgeBases.Get(c.Param("name"))
+	if err != nil {
+		writeKnowledgeError(c, err)
+		return
+	}
+	c.JSON(consts.StatusOK, kb)
+}
+
+func (h *KnowledgeHandler) Create(ctx context.Context, c *app.RequestContext) {
+	var req knowledge.KnowledgeBase
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	kb, err := h.app.KnowledgeBases.Create(ctx, &req)
+	if err != nil {
+		writeKnowledgeError(c, err)
+		return
+	}
+	c.JSON(consts.StatusCreated, kb)
+}
+
+func (h *KnowledgeHandler) Update(ctx context.Context, c *app.RequestContext) {
+	var req knowledge.UpdateRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	kb, err := h.app.KnowledgeBases.Update(c.Param("name"), &req)
+	if err != nil {
+		writeKnowledgeError(c, err)
+		return
+	}
+	c.JSON(consts.StatusOK, kb)
+}
+
+func (h *KnowledgeHandler) Delete(ctx context.Context, c *app.RequestContext) {
+	purge := c.Query("purge") == "true"
+	if err := h.app.KnowledgeBases.Delete(ctx, c.Param("name"), purge); err != nil {
+		writeKnowledgeError(c, err)
+		return
+	}
+	c.Status(consts.StatusNoContent)
+}
+
+// Ingest 向知识库写入文档
+func (h *KnowledgeHandler) Ingest(ctx context.Context, c *app.RequestContext) {
+	var req IngestReq
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if len(req.Documents) == 0 {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": "documents cannot be empty",
+		})
+		return
+	}
+
+	docs := make([]*schema.Document, 0, len(req.Documents))
+	for _, doc := range req.Documents {
+		if doc.ID == "" || doc.Content == "" {
+			c.JSON(consts.StatusBadRequest, map[string]string{
+				"error": "document id and content cannot be empty",
+			})
+			return
+		}
+		docs = append(docs, &schema.Document{
+			ID:       doc.ID,
+			Content:  doc.Content,
+			MetaData: doc.Metadata,
+		})
+	}
+
+	ids, err := h.app.KnowledgeBases.Ingest(ctx, c.Param("name"), docs)
+	if err != nil {
+		writeKnowledgeError(c, err)
+		return
+	}
+	c.JSON(consts.StatusOK, map[string]any{
+		"chunkIds": ids,
+	})
+}
+
+func writeKnowledgeError(c *app.RequestContext, err error) {
+	status := consts.StatusInternalServerError
+	switch {
+	case errors.Is(err, knowledge.ErrNotFound):
+		status = consts.StatusNotFound
+	case errors.Is(err, knowledge.ErrAlreadyExists):
+		status = consts.StatusConflict
+	case errors.Is(err, knowledge.ErrInvalid):
+		status = consts.StatusBadRequest
+	}
+	c.JSON(status, map[string]string{
+		"error": err.Error(),
+	})
+}
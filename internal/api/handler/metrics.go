@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"MoonAgent/cmd/di"
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+type MetricsHandler struct {
+	app *di.Application
+}
+
+func NewMetricsHandler(app *di.Application) *MetricsHandler {
+	return &MetricsHandler{app: app}
+}
+
+// Get 返回运行指标
+func (h *MetricsHandler) Get(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, map[string]any{
+		"embeddingCache": map[string]any{
+			"enabled": h.app.EmbeddingCache != nil,
+			"stats":   h.app.EmbeddingCache.Stats(),
+		},
+		"toolValidation": h.app.ToolValidator.Stats(),
+		"toolPolicy": map[string]any{
+			"denied": h.app.ToolPolicy.Denied(),
+		},
+	})
+}
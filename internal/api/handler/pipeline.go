@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"MoonAgent/internal/pipeline"
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+type PipelineHandler struct {
+	pipelines *pipeline.Pipelines
+}
+
+func NewPipelineHandler(pipelines *pipeline.Pipelines) *PipelineHandler {
+	return &PipelineHandler{pipelines: pipelines}
+}
+
+// List 返回可选择的配置化流水线
+func (h *PipelineHandler) List(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, map[string]any{
+		"pipelines": h.pipelines.List(),
+	})
+}
@@ -2,25 +2,82 @@ package handler
 
 import (
 	"MoonAgent/cmd/di"
+	"MoonAgent/internal/agents/orchestration"
 	"MoonAgent/internal/pipeline"
+	"MoonAgent/pkg/knowledge"
+	moonretriever "MoonAgent/pkg/retriever"
+	"MoonAgent/pkg/toolpolicy"
 	"context"
+	"encoding/json"
 	"net/http"
+	"strings"
 
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"github.com/hertz-contrib/sse"
 )
 
 type ChatHandler struct {
-	app *di.Application
+	app       *di.Application
+	pipelines *pipeline.Pipelines
 }
 
-func NewChatHandler(app *di.Application) *ChatHandler {
-	return &ChatHandler{app: app}
+func NewChatHandler(app *di.Application, pipelines *pipeline.Pipelines) *ChatHandler {
+	return &ChatHandler{app: app, pipelines: pipelines}
 }
 
+const defaultHistoryMessages = 6
+
 type Req struct {
 	UserInput string `json:"userInput"`
+	// 会话ID，设置后同一会话的请求共享对话历史
+	SessionID string `json:"sessionId,omitempty"`
+	// 使用的流水线，为空时使用默认流水线
+	Pipeline string `json:"pipeline,omitempty"`
+	// 检索的知识库，为空时使用默认知识库
+	KnowledgeBases []string `json:"knowledgeBases,omitempty"`
+	// 以下检索参数可选，未设置时使用配置文件中的默认值
+	TopK           int            `json:"topK,omitempty"`
+	ScoreThreshold *float64       `json:"scoreThreshold,omitempty"`
+	Partitions     []string       `json:"partitions,omitempty"`
+	Filter         string         `json:"filter,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+}
+
+// retrieverOptions 把请求中的检索参数转换为检索器选项
+func (r *Req) retrieverOptions() []retriever.Option {
+	opts := make([]retriever.Option, 0)
+	if len(r.KnowledgeBases) > 0 {
+		opts = append(opts, knowledge.WithKnowledgeBases(r.KnowledgeBases...))
+	}
+	if r.TopK > 0 {
+		opts = append(opts, retriever.WithTopK(r.TopK))
+	}
+	if r.ScoreThreshold != nil {
+		opts = append(opts, retriever.WithScoreThreshold(*r.ScoreThreshold))
+	}
+	if len(r.Partitions) > 0 {
+		opts = append(opts, moonretriever.WithPartitions(r.Partitions...))
+	}
+	if r.Filter != "" {
+		opts = append(opts, moonretriever.WithFilter(r.Filter))
+	}
+	if len(r.Metadata) > 0 {
+		opts = append(opts, moonretriever.WithMetadataFilter(r.Metadata))
+	}
+	return opts
+}
+
+// runOptions 流水线的运行选项，包括请求中的检索参数以及开启链路追踪时的回调
+func (h *ChatHandler) runOptions(req *Req) []compose.Option {
+	opts := []compose.Option{compose.WithRetrieverOption(req.retrieverOptions()...)}
+	if handler := h.app.Telemetry.Handler(); handler != nil {
+		opts = append(opts, compose.WithCallbacks(handler))
+	}
+	return opts
 }
 
 func (h *ChatHandler) ChatWithModel(ctx context.Context, c *app.RequestContext) {
@@ -40,17 +97,19 @@ func (h *ChatHandler) ChatWithModel(ctx context.Context, c *app.RequestContext)
 		return
 	}
 
-	ctx = context.WithValue(context.Background(), "user_input", req.UserInput)
+	// 使用请求的 ctx，客户端断开时取消流水线和其中等待审批的工具调用
+	ctx, trace := pipeline.WithTrace(ctx)
+	ctx, octx := h.withSession(ctx, &req)
 
-	runnable, err := pipeline.BuildAssitant(ctx, h.app)
+	runnable, err := h.pipelines.Get(req.Pipeline)
 	if err != nil {
-		c.JSON(consts.StatusInternalServerError, map[string]string{
+		c.JSON(consts.StatusBadRequest, map[string]string{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	out, err := runnable.Invoke(ctx, req.UserInput)
+	out, err := runnable.Invoke(ctx, req.UserInput, h.runOptions(&req)...)
 	if err != nil {
 		c.JSON(consts.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -58,8 +117,12 @@ func (h *ChatHandler) ChatWithModel(ctx context.Context, c *app.RequestContext)
 		return
 	}
 
-	c.JSON(consts.StatusOK, map[string]string{
+	saveTurn(octx, req.UserInput, out.Content)
+
+	c.JSON(consts.StatusOK, map[string]any{
 		"message": out.Content,
+		"sources": trace.Sources(),
+		"trace":   trace.Info(),
 	})
 }
 
@@ -84,11 +147,11 @@ func (h *ChatHandler) StreamChatWithModel(ctx context.Context, c *app.RequestCon
 	c.SetStatusCode(http.StatusOK)
 	stream := sse.NewStream(c)
 
-	// 创建带有用户输入的上下文
-	ctx = context.WithValue(context.Background(), "user_input", req.UserInput)
+	ctx, trace := pipeline.WithTrace(ctx)
+	ctx, octx := h.withSession(ctx, &req)
 
-	// 构建助手
-	runnable, err := pipeline.BuildAssitant(ctx, h.app)
+	// 选择启动时编译好的流水线，用户输入通过流水线的输入传递
+	runnable, err := h.pipelines.Get(req.Pipeline)
 	if err != nil {
 		// 发送错误事件
 		errorEvent := &sse.Event{
@@ -100,7 +163,7 @@ func (h *ChatHandler) StreamChatWithModel(ctx context.Context, c *app.RequestCon
 	}
 
 	// 调用流式处理
-	streamReader, err := runnable.Stream(ctx, req.UserInput)
+	streamReader, err := runnable.Stream(ctx, req.UserInput, h.runOptions(&req)...)
 	if err != nil {
 		// 发送错误事件
 		errorEvent := &sse.Event{
@@ -110,8 +173,11 @@ func (h *ChatHandler) StreamChatWithModel(ctx context.Context, c *app.RequestCon
 		stream.Publish(errorEvent)
 		return
 	}
+	defer streamReader.Close()
 
 	// 从流中读取数据并发送给客户端
+	sourcesSent := false
+	var answer strings.Builder
 	for {
 		chunk, err := streamReader.Recv()
 		if err != nil {
@@ -128,6 +194,16 @@ func (h *ChatHandler) StreamChatWithModel(ctx context.Context, c *app.RequestCon
 			return
 		}
 
+		// 检索在生成之前完成，收到第一段内容时先发送引用来源
+		if !sourcesSent {
+			sourcesSent = true
+			if err := publishSources(stream, trace); err != nil {
+				break
+			}
+		}
+
+		answer.WriteString(chunk.Content)
+
 		// 发送消息事件
 		event := &sse.Event{
 			Event: "message",
@@ -140,6 +216,18 @@ func (h *ChatHandler) StreamChatWithModel(ctx context.Context, c *app.RequestCon
 		}
 	}
 
+	if !sourcesSent {
+		publishSources(stream, trace)
+	}
+	saveTurn(octx, req.UserInput, answer.String())
+
+	if data, err := json.Marshal(trace.Info()); err == nil {
+		stream.Publish(&sse.Event{
+			Event: "trace",
+			Data:  data,
+		})
+	}
+
 	// 发送完成事件
 	doneEvent := &sse.Event{
 		Event: "done",
@@ -147,3 +235,44 @@ func (h *ChatHandler) StreamChatWithModel(ctx context.Context, c *app.RequestCon
 	}
 	stream.Publish(doneEvent)
 }
+
+// publishSources 以 sources 事件发送引用来源
+func publishSources(stream *sse.Stream, trace *pipeline.Trace) error {
+	data, err := json.Marshal(trace.Sources())
+	if err != nil {
+		return err
+	}
+	return stream.Publish(&sse.Event{
+		Event: "sources",
+		Data:  data,
+	})
+}
+
+// withSession 加载会话的对话历史和会话中禁用的工具，未设置会话ID时返回nil
+func (h *ChatHandler) withSession(ctx context.Context, req *Req) (context.Context, *orchestration.OrchestrationContext) {
+	if req.SessionID == "" {
+		return ctx, nil
+	}
+	n := h.app.ServerConfig.SessionConfig.HistoryMessages
+	if n <= 0 {
+		n = defaultHistoryMessages
+	}
+
+	octx := h.app.Sessions.Get(req.SessionID)
+	messages := octx.GetConversationHistory(n)
+	history := make([]*schema.Message, 0, len(messages))
+	for i := range messages {
+		history = append(history, &messages[i])
+	}
+	ctx = toolpolicy.WithSession(ctx, req.SessionID, h.app.Sessions.DisabledTools(req.SessionID))
+	return pipeline.WithHistory(ctx, history), octx
+}
+
+// saveTurn 把本轮问答写入会话记忆
+func saveTurn(octx *orchestration.OrchestrationContext, question, answer string) {
+	if octx == nil || answer == "" {
+		return
+	}
+	octx.AddUserMessage(question)
+	octx.AddAssistantMessage(answer)
+}
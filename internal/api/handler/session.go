@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"MoonAgent/cmd/di"
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+type SessionHandler struct {
+	app *di.Application
+}
+
+func NewSessionHandler(app *di.Application) *SessionHandler {
+	return &SessionHandler{app: app}
+}
+
+type SessionToolsReq struct {
+	// 重新启用的工具
+	Enable []string `json:"enable,omitempty"`
+	// 禁用的工具
+	Disable []string `json:"disable,omitempty"`
+}
+
+// GetTools 返回会话中禁用的工具
+func (h *SessionHandler) GetTools(ctx context.Context, c *app.RequestContext) {
+	id := c.Param("id")
+	c.JSON(consts.StatusOK, map[string]any{
+		"sessionId": id,
+		"disabled":  h.app.Sessions.DisabledTools(id),
+	})
+}
+
+// UpdateTools 在会话中启用或禁用工具，只能在配置的权限策略之上进一步限制
+func (h *SessionHandler) UpdateTools(ctx context.Context, c *app.RequestContext) {
+	var req SessionToolsReq
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	id := c.Param("id")
+	c.JSON(consts.StatusOK, map[string]any{
+		"sessionId": id,
+		"disabled":  h.app.Sessions.UpdateTools(id, req.Enable, req.Disable),
+	})
+}
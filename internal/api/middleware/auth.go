@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// BearerToken 要求请求带 Authorization: Bearer <token>，以常量时间比较令牌，不匹配时返回 401
+func BearerToken(token string) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		got, ok := strings.CutPrefix(string(c.GetHeader("Authorization")), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(consts.StatusUnauthorized, map[string]string{
+				"error": "invalid or missing admin token",
+			})
+			return
+		}
+		c.Next(ctx)
+	}
+}
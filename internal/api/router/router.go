@@ -3,12 +3,14 @@ package router
 import (
 	"MoonAgent/cmd/di"
 	"MoonAgent/internal/api/handler"
+	"MoonAgent/internal/api/middleware"
+	"MoonAgent/internal/pipeline"
 
 	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/hertz-contrib/cors"
 )
 
-func InitRouter(h *server.Hertz, app *di.Application) {
+func InitRouter(h *server.Hertz, app *di.Application, pipelines *pipeline.Pipelines) {
 	// 添加CORS中间件
 	h.Use(cors.New(
 		cors.Config{
@@ -20,8 +22,37 @@ func InitRouter(h *server.Hertz, app *di.Application) {
 		},
 	))
 
-	ChatHandler := handler.NewChatHandler(app)
+	ChatHandler := handler.NewChatHandler(app, pipelines)
 	v1 := h.Group("/api")
 	v1.POST("/chat", ChatHandler.ChatWithModel)
 	v1.POST("/chat/stream", ChatHandler.StreamChatWithModel)
+
+	KnowledgeHandler := handler.NewKnowledgeHandler(app)
+	kb := v1.Group("/knowledge-bases")
+	kb.GET("", KnowledgeHandler.List)
+	kb.POST("", KnowledgeHandler.Create)
+	kb.GET("/:name", KnowledgeHandler.Get)
+	kb.PUT("/:name", KnowledgeHandler.Update)
+	kb.DELETE("/:name", KnowledgeHandler.Delete)
+	kb.POST("/:name/documents", KnowledgeHandler.Ingest)
+
+	SessionHandler := handler.NewSessionHandler(app)
+	sessions := v1.Group("/sessions")
+	sessions.GET("/:id/tools", SessionHandler.GetTools)
+	sessions.PUT("/:id/tools", SessionHandler.UpdateTools)
+
+	// 审批接口需要单独的管理员令牌，没有配置令牌时不注册
+	if token := app.ServerConfig.ToolsConfig.Approval.Token; token != "" {
+		ApprovalHandler := handler.NewApprovalHandler(app)
+		approvals := v1.Group("/approvals", middleware.BearerToken(token))
+		approvals.GET("", ApprovalHandler.List)
+		approvals.POST("/:id/approve", ApprovalHandler.Approve)
+		approvals.POST("/:id/reject", ApprovalHandler.Reject)
+	}
+
+	MetricsHandler := handler.NewMetricsHandler(app)
+	v1.GET("/metrics", MetricsHandler.Get)
+
+	PipelineHandler := handler.NewPipelineHandler(pipelines)
+	v1.GET("/pipelines", PipelineHandler.List)
 }
@@ -0,0 +1,62 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Case 评测集中的一条样本
+type Case struct {
+	ID       string `json:"id"`
+	Question string `json:"question"`
+	// ExpectedSources 期望检索到的片段ID或文档ID
+	ExpectedSources []string `json:"expectedSources"`
+	// ReferenceAnswer 参考答案，提供给评审模型参考
+	ReferenceAnswer string `json:"referenceAnswer,omitempty"`
+	// KnowledgeBases 检索的知识库，为空时使用默认知识库
+	KnowledgeBases []string `json:"knowledgeBases,omitempty"`
+}
+
+// LoadGoldenSet 读取 JSONL 格式的评测集，空行和以 # 开头的行会被忽略
+func LoadGoldenSet(path string) ([]*Case, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cases []*Case
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if c.Question == "" {
+			return nil, fmt.Errorf("%s:%d: question cannot be empty", path, line)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("case-%d", line)
+		}
+		if _, ok := seen[c.ID]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate case id %s", path, line, c.ID)
+		}
+		seen[c.ID] = struct{}{}
+		cases = append(cases, &c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
@@ -0,0 +1,116 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const judgePrompt = `你是一个RAG回答质量评审。请判断回答中的陈述是否都能由给出的资料支持，不能被资料支持或与资料矛盾的陈述会降低分数。
+参考答案仅用于理解问题，不作为事实依据。
+只输出一个 JSON 对象，格式为 {"score": 0到1之间的小数, "reason": "简短理由"}，不要输出其他内容。`
+
+// JudgeInput 评审的输入
+type JudgeInput struct {
+	CaseID          string
+	Question        string
+	Answer          string
+	ReferenceAnswer string
+	// Contexts 生成回答时使用的资料
+	Contexts []string
+}
+
+// Verdict 评审结果
+type Verdict struct {
+	// Score 忠实度分数，范围 0 到 1
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// Judge 回答忠实度评审，可替换为脚本化的实现以便在 CI 中稳定复现
+type Judge interface {
+	Judge(ctx context.Context, in *JudgeInput) (*Verdict, error)
+}
+
+// LLMJudge 使用大模型评审
+type LLMJudge struct {
+	chatModel model.BaseChatModel
+}
+
+func NewLLMJudge(chatModel model.BaseChatModel) *LLMJudge {
+	return &LLMJudge{chatModel: chatModel}
+}
+
+func (j *LLMJudge) Judge(ctx context.Context, in *JudgeInput) (*Verdict, error) {
+	var content strings.Builder
+	content.WriteString("问题: " + in.Question + "\n\n")
+	content.WriteString("资料:\n")
+	for i, c := range in.Contexts {
+		content.WriteString(fmt.Sprintf("[%d] %s\n\n", i+1, c))
+	}
+	if in.ReferenceAnswer != "" {
+		content.WriteString("参考答案: " + in.ReferenceAnswer + "\n\n")
+	}
+	content.WriteString("回答: " + in.Answer)
+
+	resp, err := j.chatModel.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(judgePrompt),
+		schema.UserMessage(content.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseVerdict(resp.Content)
+}
+
+// parseVerdict 从模型输出中提取第一个JSON对象
+func parseVerdict(content string) (*Verdict, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("judge output has no verdict object: %s", content)
+	}
+	var v Verdict
+	if err := json.Unmarshal([]byte(content[start:end+1]), &v); err != nil {
+		return nil, fmt.Errorf("failed to parse judge verdict: %w", err)
+	}
+	if v.Score < 0 || v.Score > 1 {
+		return nil, fmt.Errorf("judge score %v out of range [0, 1]", v.Score)
+	}
+	return &v, nil
+}
+
+// ScriptedJudge 按样本ID返回预先写好的评审结果，用于在没有大模型的环境中跑通评测
+type ScriptedJudge struct {
+	Verdicts map[string]*Verdict `json:"verdicts"`
+	// Default 未配置的样本使用的结果，为空时返回错误
+	Default *Verdict `json:"default,omitempty"`
+}
+
+// LoadScriptedJudge 从 JSON 文件加载脚本化评审
+func LoadScriptedJudge(path string) (*ScriptedJudge, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var j ScriptedJudge
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &j, nil
+}
+
+func (j *ScriptedJudge) Judge(ctx context.Context, in *JudgeInput) (*Verdict, error) {
+	if v, ok := j.Verdicts[in.CaseID]; ok {
+		return v, nil
+	}
+	if j.Default != nil {
+		return j.Default, nil
+	}
+	return nil, fmt.Errorf("no scripted verdict for case %s", in.CaseID)
+}
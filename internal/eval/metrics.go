@@ -0,0 +1,56 @@
+package eval
+
+import (
+	"MoonAgent/pkg/splitter"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// RetrievalResult 单条样本的检索指标
+type RetrievalResult struct {
+	// Retrieved 按排名排列的片段ID
+	Retrieved []string `json:"retrieved"`
+	// Recall 前 k 个结果覆盖的期望来源比例
+	Recall float64 `json:"recall"`
+	// ReciprocalRank 第一个命中结果排名的倒数，未命中为0
+	ReciprocalRank float64 `json:"reciprocalRank"`
+	// Hit 前 k 个结果中是否至少命中一个期望来源
+	Hit bool `json:"hit"`
+}
+
+// ScoreRetrieval 计算检索指标，片段ID或其所属文档ID与期望来源相同即视为命中
+func ScoreRetrieval(docs []*schema.Document, expected []string) *RetrievalResult {
+	result := &RetrievalResult{Retrieved: make([]string, 0, len(docs))}
+	want := make(map[string]struct{}, len(expected))
+	for _, e := range expected {
+		want[e] = struct{}{}
+	}
+
+	found := make(map[string]struct{})
+	for rank, doc := range docs {
+		result.Retrieved = append(result.Retrieved, doc.ID)
+		matched := false
+		for _, key := range sourceKeys(doc) {
+			if _, ok := want[key]; ok {
+				found[key] = struct{}{}
+				matched = true
+			}
+		}
+		if matched && result.ReciprocalRank == 0 {
+			result.ReciprocalRank = 1 / float64(rank+1)
+		}
+	}
+	result.Hit = len(found) > 0
+	if len(want) > 0 {
+		result.Recall = float64(len(found)) / float64(len(want))
+	}
+	return result
+}
+
+func sourceKeys(doc *schema.Document) []string {
+	keys := []string{doc.ID}
+	if id, ok := doc.MetaData[splitter.MetadataKeyDocumentID].(string); ok && id != "" && id != doc.ID {
+		keys = append(keys, id)
+	}
+	return keys
+}
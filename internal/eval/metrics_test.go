@@ -0,0 +1,115 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"MoonAgent/pkg/splitter"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+)
+
+func chunk(id, documentID string) *schema.Document {
+	doc := &schema.Document{ID: id}
+	if documentID != "" {
+		doc.MetaData = map[string]any{splitter.MetadataKeyDocumentID: documentID}
+	}
+	return doc
+}
+
+func TestScoreRetrieval(t *testing.T) {
+	tests := []struct {
+		name     string
+		docs     []*schema.Document
+		expected []string
+		recall   float64
+		rr       float64
+		hit      bool
+	}{
+		{"first rank", []*schema.Document{chunk("a", ""), chunk("b", "")}, []string{"a"}, 1, 1, true},
+		{"third rank", []*schema.Document{chunk("x", ""), chunk("y", ""), chunk("a", "")}, []string{"a", "b"}, 0.5, 1.0 / 3, true},
+		{"miss", []*schema.Document{chunk("x", "")}, []string{"a"}, 0, 0, false},
+		{"document id", []*schema.Document{chunk("x", ""), chunk("doc1_0", "doc1"), chunk("doc1_1", "doc1")}, []string{"doc1"}, 1, 0.5, true},
+		{"chunk and document", []*schema.Document{chunk("doc1_0", "doc1"), chunk("doc2_3", "doc2")}, []string{"doc2_3", "doc1", "doc3"}, 2.0 / 3, 1, true},
+		{"no expected sources", []*schema.Document{chunk("a", "")}, nil, 0, 0, false},
+		{"no results", nil, []string{"a"}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScoreRetrieval(tt.docs, tt.expected)
+			if got.Recall != tt.recall || got.ReciprocalRank != tt.rr || got.Hit != tt.hit {
+				t.Errorf("got recall %v, rr %v, hit %v, want %v, %v, %v", got.Recall, got.ReciprocalRank, got.Hit, tt.recall, tt.rr, tt.hit)
+			}
+			if len(got.Retrieved) != len(tt.docs) {
+				t.Errorf("retrieved = %v", got.Retrieved)
+			}
+		})
+	}
+}
+
+// fakeRetriever 按问题返回固定的结果
+type fakeRetriever map[string][]*schema.Document
+
+func (r fakeRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	docs, ok := r[query]
+	if !ok {
+		return nil, errors.New("retriever down")
+	}
+	return docs, nil
+}
+
+type echoAnswerer struct{}
+
+func (echoAnswerer) Answer(ctx context.Context, c *Case) (string, []string, error) {
+	return "answer to " + c.Question, nil, nil
+}
+
+func TestRunnerSummary(t *testing.T) {
+	r := &Runner{
+		Retriever: fakeRetriever{
+			"q1": {chunk("a", ""), chunk("b", "")},
+			// 第 3 条超出 k，不计入指标
+			"q2": {chunk("x", ""), chunk("y", ""), chunk("b", "")},
+			"q3": {chunk("c", ""), chunk("d", "")},
+		},
+		Answerer: echoAnswerer{},
+		Judge: &ScriptedJudge{
+			Verdicts: map[string]*Verdict{"1": {Score: 1}, "3": {Score: 0.33333}},
+		},
+		K: 2,
+	}
+	cases := []*Case{
+		{ID: "1", Question: "q1", ExpectedSources: []string{"a"}},
+		{ID: "2", Question: "q2", ExpectedSources: []string{"b"}},
+		{ID: "3", Question: "q3", ExpectedSources: []string{"d", "e", "f"}},
+		{ID: "4", Question: "unknown", ExpectedSources: []string{"a"}},
+	}
+	report := r.Run(context.Background(), "golden.jsonl", "scripted", cases)
+
+	s := report.Summary
+	// recall: 1 + 0 + 1/3 + 0，MRR: 1 + 0 + 1/2 + 0，命中: 2
+	if s.Cases != 4 || s.Errors != 2 || s.RecallAtK != 0.3333 || s.MRR != 0.375 || s.HitRate != 0.5 {
+		t.Errorf("summary = %+v", s)
+	}
+	if s.Judged != 2 || s.Faithfulness == nil || *s.Faithfulness != 0.6667 {
+		t.Errorf("judged = %d, faithfulness = %v", s.Judged, s.Faithfulness)
+	}
+	if report.Cases[1].Error == "" || report.Cases[3].Error == "" {
+		t.Errorf("errors = %q, %q", report.Cases[1].Error, report.Cases[3].Error)
+	}
+
+	// 报告中的样本保持评测集的顺序，重复运行的输出完全一致
+	for i, c := range report.Cases {
+		if c.ID != cases[i].ID {
+			t.Fatalf("case %d = %s, want %s", i, c.ID, cases[i].ID)
+		}
+	}
+	first, _ := json.Marshal(report)
+	second, _ := json.Marshal(r.Run(context.Background(), "golden.jsonl", "scripted", cases))
+	if string(first) != string(second) {
+		t.Errorf("reports differ:\n%s\n%s", first, second)
+	}
+}
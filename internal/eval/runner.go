@@ -0,0 +1,165 @@
+package eval
+
+import (
+	"context"
+	"math"
+
+	"MoonAgent/pkg/knowledge"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"go.uber.org/zap"
+)
+
+const DefaultK = 5
+
+// Answerer 为样本生成回答，并返回生成时使用的资料
+type Answerer interface {
+	Answer(ctx context.Context, c *Case) (answer string, contexts []string, err error)
+}
+
+// Runner 依次评测每条样本，单条样本出错不会中断评测
+type Runner struct {
+	Retriever retriever.Retriever
+	// Answerer 为 nil 时只评测检索
+	Answerer Answerer
+	// Judge 为 nil 时不评审回答
+	Judge Judge
+	K     int
+}
+
+// Report 评测报告，字段顺序和数值精度固定，便于在多次运行之间 diff
+type Report struct {
+	GoldenSet string        `json:"goldenSet"`
+	K         int           `json:"k"`
+	Judge     string        `json:"judge"`
+	Summary   Summary       `json:"summary"`
+	Cases     []*CaseResult `json:"cases"`
+}
+
+// Summary 汇总指标
+type Summary struct {
+	Cases     int     `json:"cases"`
+	Errors    int     `json:"errors"`
+	RecallAtK float64 `json:"recallAtK"`
+	MRR       float64 `json:"mrr"`
+	HitRate   float64 `json:"hitRate"`
+	// Judged 完成评审的样本数
+	Judged int `json:"judged"`
+	// Faithfulness 平均忠实度，没有评审时为空
+	Faithfulness *float64 `json:"faithfulness,omitempty"`
+}
+
+// CaseResult 单条样本的评测结果
+type CaseResult struct {
+	ID              string           `json:"id"`
+	Question        string           `json:"question"`
+	ExpectedSources []string         `json:"expectedSources"`
+	Retrieval       *RetrievalResult `json:"retrieval,omitempty"`
+	Answer          string           `json:"answer,omitempty"`
+	Faithfulness    *Verdict         `json:"faithfulness,omitempty"`
+	Error           string           `json:"error,omitempty"`
+}
+
+func (r *Runner) Run(ctx context.Context, goldenSet string, judgeName string, cases []*Case) *Report {
+	k := r.K
+	if k <= 0 {
+		k = DefaultK
+	}
+	report := &Report{
+		GoldenSet: goldenSet,
+		K:         k,
+		Judge:     judgeName,
+		Cases:     make([]*CaseResult, 0, len(cases)),
+	}
+
+	var recall, mrr, hits, faithfulness float64
+	for _, c := range cases {
+		result := r.runCase(ctx, c, k)
+		report.Cases = append(report.Cases, result)
+		if result.Error != "" {
+			report.Summary.Errors++
+			zap.S().Warn("Eval case failed", zap.String("id", c.ID), zap.String("error", result.Error))
+		}
+		if result.Retrieval != nil {
+			recall += result.Retrieval.Recall
+			mrr += result.Retrieval.ReciprocalRank
+			if result.Retrieval.Hit {
+				hits++
+			}
+		}
+		if result.Faithfulness != nil {
+			report.Summary.Judged++
+			faithfulness += result.Faithfulness.Score
+		}
+	}
+
+	n := float64(len(cases))
+	report.Summary.Cases = len(cases)
+	if n > 0 {
+		report.Summary.RecallAtK = round(recall / n)
+		report.Summary.MRR = round(mrr / n)
+		report.Summary.HitRate = round(hits / n)
+	}
+	if report.Summary.Judged > 0 {
+		avg := round(faithfulness / float64(report.Summary.Judged))
+		report.Summary.Faithfulness = &avg
+	}
+	return report
+}
+
+func (r *Runner) runCase(ctx context.Context, c *Case, k int) *CaseResult {
+	result := &CaseResult{
+		ID:              c.ID,
+		Question:        c.Question,
+		ExpectedSources: c.ExpectedSources,
+	}
+
+	opts := []retriever.Option{retriever.WithTopK(k)}
+	if len(c.KnowledgeBases) > 0 {
+		opts = append(opts, knowledge.WithKnowledgeBases(c.KnowledgeBases...))
+	}
+	docs, err := r.Retriever.Retrieve(ctx, c.Question, opts...)
+	if err != nil {
+		result.Error = "retrieve: " + err.Error()
+		return result
+	}
+	// 多路检索合并后可能多于 k 条，指标只按前 k 条计算
+	if len(docs) > k {
+		docs = docs[:k]
+	}
+	result.Retrieval = ScoreRetrieval(docs, c.ExpectedSources)
+	result.Retrieval.Recall = round(result.Retrieval.Recall)
+	result.Retrieval.ReciprocalRank = round(result.Retrieval.ReciprocalRank)
+
+	if r.Answerer == nil {
+		return result
+	}
+	answer, contexts, err := r.Answerer.Answer(ctx, c)
+	if err != nil {
+		result.Error = "answer: " + err.Error()
+		return result
+	}
+	result.Answer = answer
+
+	if r.Judge == nil {
+		return result
+	}
+	verdict, err := r.Judge.Judge(ctx, &JudgeInput{
+		CaseID:          c.ID,
+		Question:        c.Question,
+		Answer:          answer,
+		ReferenceAnswer: c.ReferenceAnswer,
+		Contexts:        contexts,
+	})
+	if err != nil {
+		result.Error = "judge: " + err.Error()
+		return result
+	}
+	result.Faithfulness = &Verdict{Score: round(verdict.Score), Reason: verdict.Reason}
+	return result
+}
+
+// round 保留4位小数，避免浮点误差造成无意义的 diff
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
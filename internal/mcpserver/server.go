@@ -0,0 +1,247 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"MoonAgent/cmd/di"
+	manus "MoonAgent/internal/agents/Manus"
+	"MoonAgent/internal/pipeline"
+	"MoonAgent/pkg/knowledge"
+	"MoonAgent/pkg/tools"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+const (
+	serverName    = "MoonAgent"
+	serverVersion = "1.0.0"
+	// maxManusSteps run_manus 允许设置的最大步数
+	maxManusSteps = 30
+)
+
+// 对外提供的工具名称
+const (
+	ToolListKnowledgeBases = "list_knowledge_bases"
+	ToolKnowledgeSearch    = "knowledge_search"
+	ToolIngestDocuments    = "ingest_documents"
+	ToolAsk                = "ask"
+	ToolRunManus           = "run_manus"
+)
+
+// Server 把知识库检索、文档写入、RAG 问答和 Manus 智能体作为 MCP 工具对外提供
+type Server struct {
+	app       *di.Application
+	pipelines *pipeline.Pipelines
+}
+
+// NewServer 创建 MCP 服务，工具调用出错时作为工具错误返回给调用方
+func NewServer(app *di.Application, pipelines *pipeline.Pipelines) *server.MCPServer {
+	s := &Server{app: app, pipelines: pipelines}
+	srv := server.NewMCPServer(serverName, serverVersion,
+		server.WithToolCapabilities(false),
+		server.WithRecovery(),
+		server.WithInstructions("MoonAgent 的知识库和智能体。回答依赖资料的问题前先用 knowledge_search 检索，或直接用 ask 获取带引用的回答。"),
+	)
+
+	srv.AddTool(mcp.NewTool(ToolListKnowledgeBases,
+		mcp.WithDescription("列出所有知识库及其说明"),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), s.listKnowledgeBases)
+
+	srv.AddTool(mcp.NewTool(ToolKnowledgeSearch,
+		mcp.WithDescription("在知识库中检索与查询相关的文档片段，返回片段ID、来源文档和相似度分数"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithString("query", mcp.Required(), mcp.Description("检索查询，应是独立完整的问题或关键词")),
+		mcp.WithNumber("top_k", mcp.Description("返回的片段数，默认使用检索配置的 top_k，最多10")),
+		mcp.WithArray("knowledge_bases", mcp.Description("检索的知识库名称，为空时使用默认知识库"), mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithObject("metadata", mcp.Description("按文档元数据等值过滤，例如 {\"product\": \"muelsyse\"}，值为数组时表示任一匹配")),
+	), s.knowledgeSearch)
+
+	srv.AddTool(mcp.NewTool(ToolIngestDocuments,
+		mcp.WithDescription("向知识库写入文档，文档按知识库的分块配置切分并向量化，返回写入的片段ID"),
+		mcp.WithString("knowledge_base", mcp.Description("写入的知识库名称，为空时使用默认知识库")),
+		mcp.WithArray("documents", mcp.Required(), mcp.Description("要写入的文档"), mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"id":       map[string]any{"type": "string", "description": "文档ID，相同ID的文档会被覆盖"},
+				"content":  map[string]any{"type": "string", "description": "文档内容"},
+				"metadata": map[string]any{"type": "object", "description": "文档元数据，可用于检索时过滤"},
+			},
+			"required": []string{"id", "content"},
+		})),
+	), s.ingestDocuments)
+
+	srv.AddTool(mcp.NewTool(ToolAsk,
+		mcp.WithDescription("基于知识库回答问题，先检索相关片段再由模型生成回答，回答中的 [n] 对应返回的引用来源"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithString("question", mcp.Required(), mcp.Description("用户的问题")),
+		mcp.WithString("pipeline", mcp.Description("使用的流水线，为空时使用默认流水线")),
+		mcp.WithArray("knowledge_bases", mcp.Description("检索的知识库名称，为空时使用默认知识库"), mcp.Items(map[string]any{"type": "string"})),
+	), s.ask)
+
+	srv.AddTool(mcp.NewTool(ToolRunManus,
+		mcp.WithDescription("让 Manus 智能体自主完成任务，智能体会多轮思考并调用知识库检索等工具，适合需要多步推理的任务"),
+		mcp.WithString("task", mcp.Required(), mcp.Description("任务描述")),
+		mcp.WithNumber("max_steps", mcp.Description(fmt.Sprintf("最大步数，默认10，最多%d", maxManusSteps))),
+	), s.runManus)
+
+	return srv
+}
+
+func (s *Server) listKnowledgeBases(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var sb strings.Builder
+	defaultName := s.app.KnowledgeBases.DefaultName()
+	for _, kb := range s.app.KnowledgeBases.List() {
+		sb.WriteString("- " + kb.Name)
+		if kb.Name == defaultName {
+			sb.WriteString("（默认）")
+		}
+		if kb.Description != "" {
+			sb.WriteString(": " + kb.Description)
+		}
+		sb.WriteString("\n")
+	}
+	if sb.Len() == 0 {
+		return mcp.NewToolResultText("还没有知识库"), nil
+	}
+	return mcp.NewToolResultText(strings.TrimSpace(sb.String())), nil
+}
+
+func (s *Server) knowledgeSearch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	p := &tools.KnowledgeSearchParam{}
+	if err := req.BindArguments(p); err != nil {
+		return mcp.NewToolResultErrorFromErr("invalid arguments", err), nil
+	}
+	result, err := tools.SearchKnowledge(ctx, s.app.Retriever, p)
+	if err != nil {
+		return toolError(ToolKnowledgeSearch, err), nil
+	}
+	return mcp.NewToolResultText(result), nil
+}
+
+type ingestParam struct {
+	KnowledgeBase string `json:"knowledge_base"`
+	Documents     []struct {
+		ID       string         `json:"id"`
+		Content  string         `json:"content"`
+		Metadata map[string]any `json:"metadata"`
+	} `json:"documents"`
+}
+
+func (s *Server) ingestDocuments(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	p := &ingestParam{}
+	if err := req.BindArguments(p); err != nil {
+		return mcp.NewToolResultErrorFromErr("invalid arguments", err), nil
+	}
+	if len(p.Documents) == 0 {
+		return mcp.NewToolResultError("documents cannot be empty"), nil
+	}
+	name := p.KnowledgeBase
+	if name == "" {
+		name = s.app.KnowledgeBases.DefaultName()
+	}
+	docs := make([]*schema.Document, 0, len(p.Documents))
+	for _, doc := range p.Documents {
+		if doc.ID == "" || doc.Content == "" {
+			return mcp.NewToolResultError("document id and content cannot be empty"), nil
+		}
+		docs = append(docs, &schema.Document{ID: doc.ID, Content: doc.Content, MetaData: doc.Metadata})
+	}
+	ids, err := s.app.KnowledgeBases.Ingest(ctx, name, docs)
+	if err != nil {
+		return toolError(ToolIngestDocuments, err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("已向知识库 %s 写入 %d 个文档，共 %d 个片段: %s",
+		name, len(docs), len(ids), strings.Join(ids, ", "))), nil
+}
+
+type askParam struct {
+	Question       string   `json:"question"`
+	Pipeline       string   `json:"pipeline"`
+	KnowledgeBases []string `json:"knowledge_bases"`
+}
+
+func (s *Server) ask(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	p := &askParam{}
+	if err := req.BindArguments(p); err != nil {
+		return mcp.NewToolResultErrorFromErr("invalid arguments", err), nil
+	}
+	if strings.TrimSpace(p.Question) == "" {
+		return mcp.NewToolResultError("question cannot be empty"), nil
+	}
+	runnable, err := s.pipelines.Get(p.Pipeline)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var retrieverOpts []retriever.Option
+	if len(p.KnowledgeBases) > 0 {
+		retrieverOpts = append(retrieverOpts, knowledge.WithKnowledgeBases(p.KnowledgeBases...))
+	}
+	opts := []compose.Option{compose.WithRetrieverOption(retrieverOpts...)}
+	if handler := s.app.Telemetry.Handler(); handler != nil {
+		opts = append(opts, compose.WithCallbacks(handler))
+	}
+	ctx, trace := pipeline.WithTrace(ctx)
+	out, err := runnable.Invoke(ctx, p.Question, opts...)
+	if err != nil {
+		return toolError(ToolAsk, err), nil
+	}
+
+	// 回答正文之后附上结构化的引用来源，便于调用方展示
+	result := mcp.NewToolResultText(out.Content)
+	if sources := trace.Sources(); len(sources) > 0 {
+		data, err := json.MarshalIndent(sources, "", "  ")
+		if err == nil {
+			result.Content = append(result.Content, mcp.NewTextContent("引用来源:\n"+string(data)))
+		}
+	}
+	return result, nil
+}
+
+type runManusParam struct {
+	Task     string `json:"task"`
+	MaxSteps int    `json:"max_steps"`
+}
+
+func (s *Server) runManus(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	p := &runManusParam{}
+	if err := req.BindArguments(p); err != nil {
+		return mcp.NewToolResultErrorFromErr("invalid arguments", err), nil
+	}
+	if strings.TrimSpace(p.Task) == "" {
+		return mcp.NewToolResultError("task cannot be empty"), nil
+	}
+	// 智能体有状态，每次调用创建新的实例
+	config := manus.DefaultManusConfig()
+	if p.MaxSteps > 0 {
+		config.MaxSteps = min(p.MaxSteps, maxManusSteps)
+	}
+	agent, err := pipeline.NewManus(ctx, s.app, config)
+	if err != nil {
+		return toolError(ToolRunManus, err), nil
+	}
+	out, err := agent.RunWithContext(ctx, p.Task)
+	if err != nil {
+		return toolError(ToolRunManus, err), nil
+	}
+	return mcp.NewToolResultText(out.Content), nil
+}
+
+// toolError 记录日志并把错误作为工具结果返回，调用方可以据此调整参数重试
+func toolError(tool string, err error) *mcp.CallToolResult {
+	if errors.Is(err, context.Canceled) {
+		return mcp.NewToolResultError("request canceled")
+	}
+	zap.L().Error("MCP tool call failed", zap.String("tool", tool), zap.Error(err))
+	return mcp.NewToolResultError(err.Error())
+}
@@ -0,0 +1,246 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/knowledge"
+
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// 流水线定义中表示图起点和终点的节点名
+const (
+	specStart = "START"
+	specEnd   = "END"
+)
+
+// retrieverParams retriever 节点的参数
+type retrieverParams struct {
+	// 未指定 TopK 时返回的文档数，默认使用配置文件中的值
+	TopK int `mapstructure:"top_k"`
+	// 请求未指定知识库时检索的知识库
+	KnowledgeBases []string `mapstructure:"knowledge_bases"`
+}
+
+// templateParams template 节点的参数
+type templateParams struct {
+	Messages []templateMessage `mapstructure:"messages"`
+}
+
+// templateMessage 提示词中的一条消息，role 为 placeholder 时 content 为变量名
+type templateMessage struct {
+	Role    string `mapstructure:"role"`
+	Content string `mapstructure:"content"`
+}
+
+// agentParams agent 节点的参数
+type agentParams struct {
+	// 挂载的工具，为空时使用默认工具
+	Tools   []string `mapstructure:"tools"`
+	MaxStep int      `mapstructure:"max_step"`
+}
+
+// CompileGraph 把流水线定义编译为可运行的图
+func CompileGraph(ctx context.Context, app *di.Application, spec *GraphSpec) (compose.Runnable[string, *schema.Message], error) {
+	g := compose.NewGraph[string, *schema.Message](compose.WithGenLocalState(func(ctx context.Context) *assistantState {
+		return &assistantState{}
+	}))
+
+	for i := range spec.Nodes {
+		node := &spec.Nodes[i]
+		if node.Name == "" || node.Name == specStart || node.Name == specEnd {
+			return nil, fmt.Errorf("pipeline %s: invalid node name %q", spec.Name, node.Name)
+		}
+		if err := addNode(ctx, app, g, node); err != nil {
+			return nil, fmt.Errorf("pipeline %s: node %s: %w", spec.Name, node.Name, err)
+		}
+	}
+	for _, edge := range spec.Edges {
+		if err := g.AddEdge(graphNodeName(edge.From), graphNodeName(edge.To)); err != nil {
+			return nil, fmt.Errorf("pipeline %s: edge %s -> %s: %w", spec.Name, edge.From, edge.To, err)
+		}
+	}
+	for i := range spec.Branches {
+		branch := &spec.Branches[i]
+		gb, err := newSpecBranch(ctx, app, branch)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %s: branch from %s: %w", spec.Name, branch.From, err)
+		}
+		if err := g.AddBranch(graphNodeName(branch.From), gb); err != nil {
+			return nil, fmt.Errorf("pipeline %s: branch from %s: %w", spec.Name, branch.From, err)
+		}
+	}
+
+	r, err := g.Compile(ctx, compose.WithGraphName(spec.Name), compose.WithNodeTriggerMode(compose.AnyPredecessor))
+	if err != nil {
+		return nil, fmt.Errorf("pipeline %s: %w", spec.Name, err)
+	}
+	return r, nil
+}
+
+// addNode 按节点类型创建组件并加入图中
+func addNode(ctx context.Context, app *di.Application, g *compose.Graph[string, *schema.Message], node *NodeSpec) error {
+	switch node.Type {
+	case NodeTypeRetriever:
+		var p retrieverParams
+		if err := decodeParams(node.Params, &p); err != nil {
+			return err
+		}
+		topK := p.TopK
+		if topK <= 0 {
+			topK = app.ServerConfig.RetrieverConfig.TopK
+		}
+		var r retriever.Retriever = newExpansionRetriever(app.Retriever, app.KnowledgeBases, topK)
+		if len(p.KnowledgeBases) > 0 {
+			r = &defaultOptionRetriever{Retriever: r, defaults: []retriever.Option{knowledge.WithKnowledgeBases(p.KnowledgeBases...)}}
+		}
+		return g.AddRetrieverNode(node.Name, r, compose.WithNodeName(node.Name))
+	case NodeTypeTemplate:
+		ctp, err := newSpecTemplate(node.Params)
+		if err != nil {
+			return err
+		}
+		return g.AddChatTemplateNode(node.Name, ctp, compose.WithNodeName(node.Name))
+	case NodeTypeModel:
+		cm, err := newChatModel(ctx, app)
+		if err != nil {
+			return err
+		}
+		return g.AddChatModelNode(node.Name, cm, compose.WithNodeName(node.Name))
+	case NodeTypeAgent:
+		var p agentParams
+		if err := decodeParams(node.Params, &p); err != nil {
+			return err
+		}
+		names := p.Tools
+		if len(names) == 0 {
+			names = defaultAgentTools
+		}
+		tools := func(ctx context.Context) ([]tool.BaseTool, error) {
+			return newTools(ctx, app, names)
+		}
+		lba, err := newAgentLambda(ctx, app, node.Name, tools, p.MaxStep)
+		if err != nil {
+			return err
+		}
+		return g.AddLambdaNode(node.Name, lba, compose.WithNodeName(node.Name))
+	case NodeTypeLambda:
+		factory, err := lookupLambda(node.Lambda)
+		if err != nil {
+			return err
+		}
+		lba, err := factory(ctx, app, node.Params)
+		if err != nil {
+			return err
+		}
+		return g.AddLambdaNode(node.Name, lba, compose.WithNodeName(node.Name))
+	default:
+		return fmt.Errorf("unknown node type: %s", node.Type)
+	}
+}
+
+// newSpecTemplate 按参数创建提示词模板，变量使用 {name} 格式
+func newSpecTemplate(params map[string]any) (prompt.ChatTemplate, error) {
+	var p templateParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Messages) == 0 {
+		return nil, fmt.Errorf("template has no messages")
+	}
+
+	templates := make([]schema.MessagesTemplate, 0, len(p.Messages))
+	for _, msg := range p.Messages {
+		switch schema.RoleType(msg.Role) {
+		case schema.System:
+			templates = append(templates, schema.SystemMessage(msg.Content))
+		case schema.User:
+			templates = append(templates, schema.UserMessage(msg.Content))
+		case schema.Assistant:
+			templates = append(templates, schema.AssistantMessage(msg.Content, nil))
+		case "placeholder":
+			templates = append(templates, schema.MessagesPlaceholder(msg.Content, true))
+		default:
+			return nil, fmt.Errorf("unknown message role: %s", msg.Role)
+		}
+	}
+	return prompt.FromMessages(schema.FString, templates...), nil
+}
+
+// newSpecBranch 创建分支，条件返回值通过路由表映射到下游节点
+func newSpecBranch(ctx context.Context, app *di.Application, spec *BranchSpec) (*compose.GraphBranch, error) {
+	factory, err := lookupCondition(spec.Condition)
+	if err != nil {
+		return nil, err
+	}
+	if len(spec.Routes) == 0 {
+		return nil, fmt.Errorf("branch has no routes")
+	}
+	condition, err := factory(ctx, app, spec.Routes)
+	if err != nil {
+		return nil, err
+	}
+
+	endNodes := make(map[string]bool, len(spec.Routes))
+	for _, node := range spec.Routes {
+		endNodes[graphNodeName(node)] = true
+	}
+	return compose.NewGraphBranch(func(ctx context.Context, input any) (string, error) {
+		key, err := condition(ctx, input)
+		if err != nil {
+			return "", err
+		}
+		node, ok := spec.Routes[key]
+		if !ok {
+			return "", fmt.Errorf("branch from %s has no route for %q", spec.From, key)
+		}
+		return graphNodeName(node), nil
+	}, endNodes), nil
+}
+
+// graphNodeName 把定义中的 START / END 转换为图的起点和终点
+func graphNodeName(name string) string {
+	switch name {
+	case specStart:
+		return compose.START
+	case specEnd:
+		return compose.END
+	default:
+		return name
+	}
+}
+
+func decodeParams(params map[string]any, out any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           out,
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(params); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	return nil
+}
+
+// defaultOptionRetriever 为检索附加默认选项，请求中的选项优先
+type defaultOptionRetriever struct {
+	retriever.Retriever
+	defaults []retriever.Option
+}
+
+func (r *defaultOptionRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	return r.Retriever.Retrieve(ctx, query, append(append([]retriever.Option{}, r.defaults...), opts...)...)
+}
@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"context"
+
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/queryexpand"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+type historyKey struct{}
+
+// WithHistory 在上下文中挂载会话的对话历史，用于压缩追问和生成回答
+func WithHistory(ctx context.Context, history []*schema.Message) context.Context {
+	return context.WithValue(ctx, historyKey{}, history)
+}
+
+func historyFromContext(ctx context.Context) []*schema.Message {
+	history, _ := ctx.Value(historyKey{}).([]*schema.Message)
+	return history
+}
+
+// newCondenseLambda component initialization function of node 'Condense8' in graph 'Assitant'
+// 有对话历史时把追问压缩为独立的检索查询，原始问题保存在状态中用于生成回答
+func newCondenseLambda(ctx context.Context, app *di.Application) (*compose.Lambda, error) {
+	chatModel, err := newChatModel(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	condenser := queryexpand.NewCondenser(chatModel)
+
+	return compose.InvokableLambda(func(ctx context.Context, input string) (output string, err error) {
+		err = compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+			state.Question = input
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+
+		output, err = condenser.Condense(ctx, historyFromContext(ctx), input)
+		if err != nil {
+			zap.S().Warn("Condense question failed", zap.String("error", err.Error()))
+			output = input
+		}
+		traceFromContext(ctx).setQuery(output)
+		return output, nil
+	}), nil
+}
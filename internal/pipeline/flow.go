@@ -2,38 +2,133 @@ package pipeline
 
 import (
 	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/toolcheck"
+	"MoonAgent/pkg/toolpolicy"
 	"context"
+	"sync"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent"
 	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
 )
 
+// builtinAgentName 内置流水线中智能体的名称，用于匹配权限策略
+const builtinAgentName = "assistant"
+
+// defaultAgentTools 智能体默认挂载的工具
+var defaultAgentTools = []string{ToolWebSearch, ToolHTTPFetch, ToolJumpWebPage, ToolKnowledgeSearch}
+
+// optionalAgentTools 在配置中开启后才挂载的工具，包括外部 MCP 服务当前提供的工具
+func optionalAgentTools(app *di.Application) []string {
+	cfg := app.ServerConfig.ToolsConfig
+	var names []string
+	if cfg.CodeExec.Enable {
+		names = append(names, ToolCodeExec)
+	}
+	if cfg.Workspace.Enable {
+		names = append(names, ToolReadFile, ToolListDir, ToolGrep)
+		if !cfg.Workspace.ReadOnly {
+			names = append(names, ToolWriteFile)
+		}
+	}
+	if cfg.Shell.Enable {
+		names = append(names, ToolShell)
+	}
+	// 外部 MCP 服务的工具
+	names = append(names, app.MCP.ToolNames()...)
+	return names
+}
+
 // newLambda component initialization function of node 'Lambda3' in graph 'Assitant'
 func newLambda(ctx context.Context, app *di.Application) (lba *compose.Lambda, err error) {
-	// TODO Modify component configuration here.
-	config := &react.AgentConfig{}
-	chatModelIns11, err := newChatModel(ctx, app)
-	if err != nil {
-		return nil, err
+	tools := func(ctx context.Context) ([]tool.BaseTool, error) {
+		names := append(append([]string{}, defaultAgentTools...), optionalAgentTools(app)...)
+		return newTools(ctx, app, names)
 	}
-	config.ToolCallingModel = chatModelIns11
-	toolIns21, err := newGoogleSearchTool(ctx, app)
-	if err != nil {
-		return nil, err
+	return newAgentLambda(ctx, app, builtinAgentName, tools, 0)
+}
+
+// toolsFunc 创建智能体挂载的工具，外部 MCP 服务的工具列表变化后会再次调用
+type toolsFunc func(ctx context.Context) ([]tool.BaseTool, error)
+
+// newAgentLambda 创建挂载指定工具的 react 智能体，maxStep 为 0 时使用默认值
+// 工具调用前按参数定义校验参数，每次运行单独计算参数错误的重试次数；name 用于匹配权限策略中的智能体规则
+func newAgentLambda(ctx context.Context, app *di.Application, name string, tools toolsFunc, maxStep int) (lba *compose.Lambda, err error) {
+	build := func(ctx context.Context) (*react.Agent, error) {
+		ts, err := tools(ctx)
+		if err != nil {
+			return nil, err
+		}
+		config := &react.AgentConfig{MaxStep: maxStep}
+		chatModelIns11, err := newChatModel(ctx, app)
+		if err != nil {
+			return nil, err
+		}
+		config.ToolCallingModel = chatModelIns11
+		config.ToolsConfig.Tools = app.ToolValidator.WrapAll(ts)
+		return react.NewAgent(ctx, config)
 	}
-	toolIns22, err := newJumpWebPage(ctx)
-	if err != nil {
+	ra := &reloadingAgent{app: app, name: name, build: build}
+	// 编译时创建一次，配置错误在启动阶段暴露
+	if _, err := ra.get(ctx); err != nil {
 		return nil, err
 	}
-	config.ToolsConfig.Tools = []tool.BaseTool{toolIns21, toolIns22}
-	ins, err := react.NewAgent(ctx, config)
-	if err != nil {
-		return nil, err
+	generate := func(ctx context.Context, input []*schema.Message, opts ...agent.AgentOption) (*schema.Message, error) {
+		ins, err := ra.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return ins.Generate(toolpolicy.WithAgent(toolcheck.WithBudget(ctx), name), input, opts...)
+	}
+	stream := func(ctx context.Context, input []*schema.Message, opts ...agent.AgentOption) (*schema.StreamReader[*schema.Message], error) {
+		ins, err := ra.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return ins.Stream(toolpolicy.WithAgent(toolcheck.WithBudget(ctx), name), input, opts...)
 	}
-	lba, err = compose.AnyLambda(ins.Generate, ins.Stream, nil, nil)
+	lba, err = compose.AnyLambda(generate, stream, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 	return lba, nil
 }
+
+// reloadingAgent 外部 MCP 服务的工具列表变化后（例如启动时未连接的服务恢复），在下一次运行前重新创建智能体，
+// 使新的工具挂载到已编译的流水线中
+type reloadingAgent struct {
+	app   *di.Application
+	name  string
+	build func(ctx context.Context) (*react.Agent, error)
+
+	mu      sync.Mutex
+	agent   *react.Agent
+	version uint64
+}
+
+func (a *reloadingAgent) get(ctx context.Context) (*react.Agent, error) {
+	version := a.app.MCP.Version()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.agent != nil && a.version == version {
+		return a.agent, nil
+	}
+	ins, err := a.build(ctx)
+	if err != nil {
+		if a.agent == nil {
+			return nil, err
+		}
+		// 重建失败时继续使用原来的工具，等工具列表再次变化时重试
+		zap.L().Warn("Failed to reload agent tools, keeping the previous tools", zap.String("agent", a.name), zap.Error(err))
+		a.version = version
+		return a.agent, nil
+	}
+	if a.agent != nil {
+		zap.L().Info("Agent tools reloaded after mcp tools changed", zap.String("agent", a.name))
+	}
+	a.agent, a.version = ins, version
+	return ins, nil
+}
@@ -2,17 +2,102 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 
+	"MoonAgent/pkg/knowledge"
+	"MoonAgent/pkg/reranker"
+	"MoonAgent/pkg/splitter"
+
+	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 )
 
+const snippetLength = 200
+
 // newLambda1 component initialization function of node 'Lambda5' in graph 'Assitant'
 func newLambda1(ctx context.Context, input []*schema.Document) (output map[string]any, err error) {
 	output = make(map[string]any)
-	contentString := ""
-	for _, doc := range input {
-		contentString += doc.Content
+	sources := make([]Source, 0, len(input))
+	var content strings.Builder
+	for i, doc := range input {
+		source := newSource(i+1, doc)
+		sources = append(sources, source)
+		// 每个片段带编号、文档ID和元数据，便于模型引用
+		content.WriteString(fmt.Sprintf("[%d] 文档: %s, 片段: %s", source.Index, source.DocumentID, source.ChunkID))
+		if meta := formatMetadata(doc.MetaData); meta != "" {
+			content.WriteString(", " + meta)
+		}
+		content.WriteString("\n" + doc.Content + "\n\n")
+	}
+	traceFromContext(ctx).setSources(sources)
+	var question string
+	err = compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+		question = state.Question
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	output["retrieve_result"] = contentString
+	output["user_input"] = question
+	output["retrieve_result"] = content.String()
+	output["history"] = historyFromContext(ctx)
 	return output, nil
 }
+
+func newSource(index int, doc *schema.Document) Source {
+	source := Source{
+		Index:      index,
+		DocumentID: documentID(doc),
+		ChunkID:    doc.ID,
+		Snippet:    snippet(doc.Content),
+		Content:    doc.Content,
+		Score:      doc.Score(),
+	}
+	if kb, ok := doc.MetaData[knowledge.MetadataKeyKnowledgeBase].(string); ok {
+		source.KnowledgeBase = kb
+	}
+	// 经过重排时使用重排分数
+	if score, ok := doc.MetaData[reranker.MetadataKeyScore].(float64); ok {
+		source.Score = score
+	}
+	return source
+}
+
+// documentID 片段所属的原始文档ID，旧数据没有记录时从片段ID推断
+func documentID(doc *schema.Document) string {
+	if id, ok := doc.MetaData[splitter.MetadataKeyDocumentID].(string); ok && id != "" {
+		return id
+	}
+	if idx := strings.LastIndex(doc.ID, "_"); idx > 0 {
+		return doc.ID[:idx]
+	}
+	return doc.ID
+}
+
+func snippet(content string) string {
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= snippetLength {
+		return string(runes)
+	}
+	return string(runes[:snippetLength]) + "..."
+}
+
+// formatMetadata 输出对模型有意义的元数据，内部字段以下划线开头的不输出
+func formatMetadata(metadata map[string]any) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		if strings.HasPrefix(k, "_") || k == splitter.MetadataKeyDocumentID || k == reranker.MetadataKeyScore {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %v", k, metadata[k]))
+	}
+	return strings.Join(parts, ", ")
+}
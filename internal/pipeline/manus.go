@@ -0,0 +1,39 @@
+package pipeline
+
+import (
+	"context"
+
+	"MoonAgent/cmd/di"
+	manus "MoonAgent/internal/agents/Manus"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// NewManus 创建挂载知识库检索工具的 Manus 智能体，配置中开启的代码执行、工作区文件、命令行工具和外部 MCP 服务的工具也会一并挂载
+func NewManus(ctx context.Context, app *di.Application, config *manus.ManusConfig) (*manus.Manus, error) {
+	cm, err := newChatModel(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	agent := manus.NewManus(config, cm, nil)
+	agent.SetValidator(app.ToolValidator)
+	if handler := app.Telemetry.Handler(); handler != nil {
+		agent.SetCallbacks(handler)
+	}
+
+	// 与流水线中的智能体一样经过权限检查和输出长度限制
+	tools, err := newTools(ctx, app, append([]string{ToolKnowledgeSearch}, optionalAgentTools(app)...))
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tools {
+		it, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		if err := agent.RegisterTool(ctx, it); err != nil {
+			return nil, err
+		}
+	}
+	return agent, nil
+}
@@ -21,3 +21,8 @@ func newChatModel(ctx context.Context, app *di.Application) (cm model.ToolCallin
 	}
 	return cm, nil
 }
+
+// NewChatModel 创建对话模型，供流水线之外的组件复用
+func NewChatModel(ctx context.Context, app *di.Application) (model.ToolCallingChatModel, error) {
+	return newChatModel(ctx, app)
+}
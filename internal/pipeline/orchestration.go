@@ -3,46 +3,143 @@ package pipeline
 import (
 	"context"
 
+	"github.com/cloudwego/eino/components/retriever"
 	"github.com/cloudwego/eino/compose"
 
 	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/intent"
 
 	"github.com/cloudwego/eino/schema"
 )
 
+// assistantState 图运行期间的共享状态
+type assistantState struct {
+	// 用户的原始问题
+	Question string
+	// 用于检索的查询
+	Query string
+	// 各知识库扩展后的检索查询，未启用扩展的知识库不在其中
+	Queries map[string][]string
+	// 意图路由选择的路线
+	Route intent.Route
+	// 请求指定的返回文档数，启用重排时在重排之后截断，为 0 时不截断
+	TopK int
+}
+
+// rememberQuestion 在状态中记录用户的原始问题，已记录时不覆盖
+func rememberQuestion(ctx context.Context, question string) error {
+	return compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+		if state.Question == "" {
+			state.Question = question
+		}
+		return nil
+	})
+}
+
 func BuildAssitant(ctx context.Context, app *di.Application) (r compose.Runnable[string, *schema.Message], err error) {
 	const (
-		Lambda3       = "Lambda3"
-		ChatTemplate2 = "ChatTemplate2"
-		Retriever4    = "Retriever4"
-		Lambda5       = "Lambda5"
+		Lambda3        = "Lambda3"
+		ChatTemplate2  = "ChatTemplate2"
+		Retriever4     = "Retriever4"
+		Lambda5        = "Lambda5"
+		Rerank6        = "Rerank6"
+		QueryExpand7   = "QueryExpand7"
+		Condense8      = "Condense8"
+		Classify9      = "Classify9"
+		SmallTalk10    = "SmallTalk10"
+		ChatTemplate11 = "ChatTemplate11"
+		ChatModel12    = "ChatModel12"
+		AgentInput13   = "AgentInput13"
+		ChatTemplate14 = "ChatTemplate14"
 	)
 	// 构建图
-	g := compose.NewGraph[string, *schema.Message]()
+	g := compose.NewGraph[string, *schema.Message](compose.WithGenLocalState(func(ctx context.Context) *assistantState {
+		return &assistantState{}
+	}))
 	// 构建Lambda3
 	lambda3KeyOfLambda, err := newLambda(ctx, app)
 	if err != nil {
 		return nil, err
 	}
-	_ = g.AddLambdaNode(Lambda3, lambda3KeyOfLambda)
+	_ = g.AddLambdaNode(Lambda3, lambda3KeyOfLambda, compose.WithNodeName(Lambda3))
 	// 构建ChatTemplate2
 	chatTemplate2KeyOfChatTemplate, err := newChatTemplate(ctx)
 	if err != nil {
 		return nil, err
 	}
-	_ = g.AddChatTemplateNode(ChatTemplate2, chatTemplate2KeyOfChatTemplate)
+	_ = g.AddChatTemplateNode(ChatTemplate2, chatTemplate2KeyOfChatTemplate, compose.WithNodeName(ChatTemplate2))
+	// 构建Classify9
+	classifier, err := newClassifier(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	_ = g.AddLambdaNode(Classify9, newClassifyLambda(classifier), compose.WithNodeName(Classify9))
+	// 构建闲聊路线
+	chatTemplate11, err := newSmallTalkTemplate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatModel12, err := newChatModel(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	_ = g.AddLambdaNode(SmallTalk10, newPromptInputLambda(), compose.WithNodeName(SmallTalk10))
+	_ = g.AddChatTemplateNode(ChatTemplate11, chatTemplate11, compose.WithNodeName(ChatTemplate11))
+	_ = g.AddChatModelNode(ChatModel12, chatModel12, compose.WithNodeName(ChatModel12))
+	// 构建智能体路线
+	chatTemplate14, err := newAgentTemplate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_ = g.AddLambdaNode(AgentInput13, newPromptInputLambda(), compose.WithNodeName(AgentInput13))
+	_ = g.AddChatTemplateNode(ChatTemplate14, chatTemplate14, compose.WithNodeName(ChatTemplate14))
+	// 构建Condense8
+	condense8, err := newCondenseLambda(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	_ = g.AddLambdaNode(Condense8, condense8, compose.WithNodeName(Condense8))
+	// 构建QueryExpand7
+	queryExpand7, err := newQueryExpandLambda(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	_ = g.AddLambdaNode(QueryExpand7, queryExpand7, compose.WithNodeName(QueryExpand7))
 	// 构建Retriever4
-	retriever4KeyOfRetriever := app.Retriever
+	var retriever4KeyOfRetriever retriever.Retriever = newExpansionRetriever(app.Retriever, app.KnowledgeBases, app.ServerConfig.RetrieverConfig.TopK)
+	// 构建Rerank6，未启用时为nil
+	rerank6, err := newReranker(ctx, app)
 	if err != nil {
 		return nil, err
 	}
-	_ = g.AddRetrieverNode(Retriever4, retriever4KeyOfRetriever)
-	_ = g.AddLambdaNode(Lambda5, compose.InvokableLambda(newLambda1))
-	_ = g.AddEdge(compose.START, Retriever4)
+	if rerank6 != nil {
+		retriever4KeyOfRetriever = newCandidateRetriever(retriever4KeyOfRetriever, app.ServerConfig.RerankConfig.Candidates)
+	}
+	_ = g.AddRetrieverNode(Retriever4, retriever4KeyOfRetriever, compose.WithNodeName(Retriever4))
+	_ = g.AddLambdaNode(Lambda5, compose.InvokableLambda(newLambda1), compose.WithNodeName(Lambda5))
+	_ = g.AddEdge(compose.START, Classify9)
+	_ = g.AddBranch(Classify9, newRouteBranch(map[intent.Route]string{
+		intent.RouteChat:      SmallTalk10,
+		intent.RouteKnowledge: Condense8,
+		intent.RouteAgent:     AgentInput13,
+	}))
+	_ = g.AddEdge(SmallTalk10, ChatTemplate11)
+	_ = g.AddEdge(ChatTemplate11, ChatModel12)
+	_ = g.AddEdge(ChatModel12, compose.END)
+	_ = g.AddEdge(AgentInput13, ChatTemplate14)
+	_ = g.AddEdge(ChatTemplate14, Lambda3)
+	_ = g.AddEdge(Condense8, QueryExpand7)
+	_ = g.AddEdge(QueryExpand7, Retriever4)
 	_ = g.AddEdge(Lambda3, compose.END)
 	_ = g.AddEdge(ChatTemplate2, Lambda3)
 	_ = g.AddEdge(Lambda5, ChatTemplate2)
-	_ = g.AddEdge(Retriever4, Lambda5)
+	if rerank6 != nil {
+		_ = g.AddLambdaNode(Rerank6, newRerankLambda(rerank6), compose.WithNodeName(Rerank6))
+		_ = g.AddEdge(Retriever4, Rerank6)
+		_ = g.AddEdge(Rerank6, Lambda5)
+	} else {
+		_ = g.AddEdge(Retriever4, Lambda5)
+	}
 	r, err = g.Compile(ctx, compose.WithGraphName("Assitant"), compose.WithNodeTriggerMode(compose.AnyPredecessor))
 	if err != nil {
 		return nil, err
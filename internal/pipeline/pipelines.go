@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"MoonAgent/cmd/di"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+// Pipelines 启动时编译好的流水线，编译结果在所有请求间共享
+type Pipelines struct {
+	// builtin 内置的 Assitant
+	builtin   compose.Runnable[string, *schema.Message]
+	specs     map[string]*GraphSpec
+	runnables map[string]compose.Runnable[string, *schema.Message]
+	// 请求未指定流水线时使用的流水线，为空时使用内置的 Assitant
+	defaultName string
+}
+
+// LoadPipelines 编译内置的 Assitant，并读取配置目录中的流水线定义全部编译，任一定义有误时返回错误
+func LoadPipelines(ctx context.Context, app *di.Application) (*Pipelines, error) {
+	builtin, err := BuildAssitant(ctx, app)
+	if err != nil {
+		return nil, fmt.Errorf("compile builtin pipeline failed: %w", err)
+	}
+	cfg := app.ServerConfig.PipelineConfig
+	p := &Pipelines{
+		builtin:     builtin,
+		specs:       make(map[string]*GraphSpec),
+		runnables:   make(map[string]compose.Runnable[string, *schema.Message]),
+		defaultName: cfg.Default,
+	}
+	if cfg.Dir == "" {
+		return p, p.checkDefault()
+	}
+
+	specs, err := LoadGraphSpecs(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range specs {
+		r, err := CompileGraph(ctx, app, spec)
+		if err != nil {
+			return nil, err
+		}
+		p.specs[spec.Name] = spec
+		p.runnables[spec.Name] = r
+		zap.S().Info("Pipeline compiled", zap.String("name", spec.Name), zap.Int("nodes", len(spec.Nodes)))
+	}
+	return p, p.checkDefault()
+}
+
+func (p *Pipelines) checkDefault() error {
+	if p.defaultName == "" {
+		return nil
+	}
+	if _, ok := p.runnables[p.defaultName]; !ok {
+		return fmt.Errorf("default pipeline %s is not defined", p.defaultName)
+	}
+	return nil
+}
+
+// Get 按名称获取流水线，名称为空时返回默认流水线，未配置默认流水线时返回内置的 Assitant
+func (p *Pipelines) Get(name string) (compose.Runnable[string, *schema.Message], error) {
+	if name == "" {
+		name = p.defaultName
+	}
+	if name == "" {
+		return p.builtin, nil
+	}
+	r, ok := p.runnables[name]
+	if !ok {
+		return nil, fmt.Errorf("pipeline %s not found", name)
+	}
+	return r, nil
+}
+
+// PipelineInfo 流水线的概要信息
+type PipelineInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// List 返回所有配置化流水线，按名称排序
+func (p *Pipelines) List() []PipelineInfo {
+	infos := make([]PipelineInfo, 0, len(p.specs))
+	for name, spec := range p.specs {
+		infos = append(infos, PipelineInfo{
+			Name:        name,
+			Description: spec.Description,
+			Default:     name == p.defaultName,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
@@ -12,21 +12,61 @@ type ChatTemplateConfig struct {
 	Templates  []schema.MessagesTemplate
 }
 
-// newChatTemplate component initialization function of node 'ChatTemplate2' in graph 'Assitant'
-func newChatTemplate(ctx context.Context) (ctp prompt.ChatTemplate, err error) {
-	config := &ChatTemplateConfig{
-		FormatType: schema.FString,
-		Templates: []schema.MessagesTemplate{
-			schema.SystemMessage("你是一个活泼的小助手，会用活泼的方式回答问题"),
-			schema.SystemMessage(`你是一个专业的规划代理，负责通过结构化计划高效解决问题。
+// 助手人设，各条路线共用
+var (
+	personaMessage = schema.SystemMessage("你是一个活泼的小助手，会用活泼的方式回答问题")
+	plannerMessage = schema.SystemMessage(`你是一个专业的规划代理，负责通过结构化计划高效解决问题。
 									你的职责是：
 									1. 分析请求以理解任务范围。
 									2. 创建清晰、可操作的计划。
 									3. 根据需要使用可用工具执行步骤。
 									4. 跟踪进度并在必要时调整计划。
-									5. 回答的时候会详细介绍每一步及使用的工具`),
-			schema.SystemMessage("根据用户回答检索到的内容为{retrieve_result}"),
-			schema.UserMessage(ctx.Value("user_input").(string)),
+									5. 回答的时候会详细介绍每一步及使用的工具`)
+)
+
+// newChatTemplate component initialization function of node 'ChatTemplate2' in graph 'Assitant'
+func newChatTemplate(ctx context.Context) (ctp prompt.ChatTemplate, err error) {
+	config := &ChatTemplateConfig{
+		FormatType: schema.FString,
+		Templates: []schema.MessagesTemplate{
+			personaMessage,
+			plannerMessage,
+			schema.SystemMessage(`根据用户问题检索到的资料如下，每个片段以 [编号] 开头：
+{retrieve_result}
+使用资料回答时，请在相关句子末尾用 [编号] 标注引用的片段，例如 [1] 或 [1][3]；不要编造不存在的编号，资料与问题无关时无需引用。
+以上资料不足以回答时，可以调用 knowledge_search 工具换用更具体的查询继续检索知识库。`),
+			schema.MessagesPlaceholder("history", true),
+			schema.UserMessage("{user_input}"),
+		},
+	}
+	ctp = prompt.FromMessages(config.FormatType, config.Templates...)
+	return ctp, nil
+}
+
+// newSmallTalkTemplate 闲聊路线的提示词，不带检索资料
+func newSmallTalkTemplate(ctx context.Context) (ctp prompt.ChatTemplate, err error) {
+	config := &ChatTemplateConfig{
+		FormatType: schema.FString,
+		Templates: []schema.MessagesTemplate{
+			personaMessage,
+			schema.MessagesPlaceholder("history", true),
+			schema.UserMessage("{user_input}"),
+		},
+	}
+	ctp = prompt.FromMessages(config.FormatType, config.Templates...)
+	return ctp, nil
+}
+
+// newAgentTemplate 智能体路线的提示词，资料由智能体按需通过工具获取
+func newAgentTemplate(ctx context.Context) (ctp prompt.ChatTemplate, err error) {
+	config := &ChatTemplateConfig{
+		FormatType: schema.FString,
+		Templates: []schema.MessagesTemplate{
+			personaMessage,
+			plannerMessage,
+			schema.SystemMessage("需要内部资料时调用 knowledge_search 工具检索知识库，需要最新信息时使用搜索和网页工具。"),
+			schema.MessagesPlaceholder("history", true),
+			schema.UserMessage("{user_input}"),
 		},
 	}
 	ctp = prompt.FromMessages(config.FormatType, config.Templates...)
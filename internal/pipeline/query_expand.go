@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"context"
+
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/knowledge"
+	"MoonAgent/pkg/queryexpand"
+	moonretriever "MoonAgent/pkg/retriever"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+// newQueryExpandLambda component initialization function of node 'QueryExpand7' in graph 'Assitant'
+// 按所选知识库的配置扩展查询并写入状态，输出原始问题；扩展失败时退化为只用原始问题检索
+func newQueryExpandLambda(ctx context.Context, app *di.Application) (*compose.Lambda, error) {
+	chatModel, err := newChatModel(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	expander := queryexpand.NewExpander(chatModel)
+	kbs := app.KnowledgeBases
+
+	return compose.InvokableLambdaWithOption(func(ctx context.Context, input string, opts ...retriever.Option) (output string, err error) {
+		if err := rememberQuestion(ctx, input); err != nil {
+			return "", err
+		}
+		queries := make(map[string][]string)
+		// 配置相同的知识库共用一次扩展结果
+		expanded := make(map[queryexpand.Config][]string)
+		for _, name := range selectedKnowledgeBases(kbs, opts) {
+			kb, err := kbs.Get(name)
+			if err != nil {
+				// 知识库不存在的错误由检索节点返回
+				continue
+			}
+			cfg := kb.QueryExpansion.Config()
+			if !cfg.Enabled() {
+				continue
+			}
+			if _, ok := expanded[cfg]; !ok {
+				result, err := expander.Expand(ctx, input, cfg)
+				if err != nil {
+					zap.S().Warn("Query expansion failed", zap.String("knowledge_base", name), zap.String("error", err.Error()))
+				}
+				expanded[cfg] = result
+			}
+			queries[name] = expanded[cfg]
+		}
+
+		err = compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+			state.Query = input
+			state.Queries = queries
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return input, nil
+	}), nil
+}
+
+// expansionRetriever 对每个知识库使用扩展后的全部查询检索，并融合去重
+type expansionRetriever struct {
+	retriever.Retriever
+	kbs *knowledge.Manager
+	// 未指定 TopK 时融合后保留的文档数
+	topK int
+}
+
+func newExpansionRetriever(r retriever.Retriever, kbs *knowledge.Manager, topK int) retriever.Retriever {
+	if topK <= 0 {
+		topK = moonretriever.DefaultTopK
+	}
+	return &expansionRetriever{Retriever: r, kbs: kbs, topK: topK}
+}
+
+func (r *expansionRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	var queries map[string][]string
+	// 在图外调用时没有状态，直接检索
+	_ = compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+		queries = state.Queries
+		return nil
+	})
+	if len(queries) == 0 {
+		return r.Retriever.Retrieve(ctx, query, opts...)
+	}
+
+	var lists [][]*schema.Document
+	for _, name := range selectedKnowledgeBases(r.kbs, opts) {
+		kbQueries, ok := queries[name]
+		if !ok || len(kbQueries) == 0 {
+			kbQueries = []string{query}
+		}
+		kbOpts := append(append([]retriever.Option{}, opts...), knowledge.WithKnowledgeBases(name))
+		for _, q := range kbQueries {
+			docs, err := r.Retriever.Retrieve(ctx, q, kbOpts...)
+			if err != nil {
+				return nil, err
+			}
+			lists = append(lists, docs)
+		}
+	}
+
+	topK := r.topK
+	co := retriever.GetCommonOptions(&retriever.Options{TopK: &topK}, opts...)
+	return queryexpand.Fuse(lists, *co.TopK), nil
+}
+
+// selectedKnowledgeBases 请求选择的知识库，未指定时为默认知识库
+func selectedKnowledgeBases(kbs *knowledge.Manager, opts []retriever.Option) []string {
+	io := retriever.GetImplSpecificOptions(&knowledge.ImplOptions{}, opts...)
+	if len(io.KnowledgeBases) > 0 {
+		return io.KnowledgeBases
+	}
+	return []string{kbs.DefaultName()}
+}
@@ -0,0 +1,210 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/tooloutput"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"go.uber.org/zap"
+)
+
+// 内置工具名称
+const (
+	ToolGoogleSearch    = "google_search"
+	ToolWebSearch       = "web_search"
+	ToolJumpWebPage     = "jump_web_page"
+	ToolHTTPFetch       = "http_fetch"
+	ToolKnowledgeSearch = "knowledge_search"
+	ToolCodeExec        = "code_exec"
+	ToolReadFile        = "read_file"
+	ToolWriteFile       = "write_file"
+	ToolListDir         = "list_dir"
+	ToolGrep            = "grep"
+	ToolShell           = "shell"
+	ToolReadToolOutput  = tooloutput.ReadToolName
+)
+
+// LambdaFactory 根据节点参数创建 lambda 组件
+type LambdaFactory func(ctx context.Context, app *di.Application, params map[string]any) (*compose.Lambda, error)
+
+// ToolFactory 创建智能体可以使用的工具
+type ToolFactory func(ctx context.Context, app *di.Application) (tool.BaseTool, error)
+
+// ConditionFactory 根据分支的路由表创建分支条件，条件返回 routes 中的键
+type ConditionFactory func(ctx context.Context, app *di.Application, routes map[string]string) (func(ctx context.Context, input any) (string, error), error)
+
+var (
+	registryMu sync.RWMutex
+	lambdas    = map[string]LambdaFactory{
+		"condense": func(ctx context.Context, app *di.Application, params map[string]any) (*compose.Lambda, error) {
+			return newCondenseLambda(ctx, app)
+		},
+		"query_expand": func(ctx context.Context, app *di.Application, params map[string]any) (*compose.Lambda, error) {
+			return newQueryExpandLambda(ctx, app)
+		},
+		"format_documents": func(ctx context.Context, app *di.Application, params map[string]any) (*compose.Lambda, error) {
+			return compose.InvokableLambda(newLambda1), nil
+		},
+		"prompt_input": func(ctx context.Context, app *di.Application, params map[string]any) (*compose.Lambda, error) {
+			return newPromptInputLambda(), nil
+		},
+		"classify": func(ctx context.Context, app *di.Application, params map[string]any) (*compose.Lambda, error) {
+			classifier, err := newClassifier(ctx, app)
+			if err != nil {
+				return nil, err
+			}
+			return newClassifyLambda(classifier), nil
+		},
+		"rerank": newRerankLambdaFromParams,
+	}
+	toolFactories = map[string]ToolFactory{
+		ToolGoogleSearch: newGoogleSearchTool,
+		ToolWebSearch: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newWebSearch(ctx, app)
+		},
+		ToolJumpWebPage: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newJumpWebPage(ctx, app)
+		},
+		ToolHTTPFetch: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newHTTPFetch(ctx, app)
+		},
+		ToolKnowledgeSearch: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newKnowledgeSearch(ctx, app)
+		},
+		ToolCodeExec: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newCodeExec(ctx, app)
+		},
+		ToolReadFile: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newReadFile(ctx, app)
+		},
+		ToolWriteFile: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newWriteFile(ctx, app)
+		},
+		ToolListDir: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newListDir(ctx, app)
+		},
+		ToolGrep: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newGrep(ctx, app)
+		},
+		ToolShell: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newShell(ctx, app)
+		},
+		ToolReadToolOutput: func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+			return newReadToolOutput(ctx, app)
+		},
+	}
+	conditions = map[string]ConditionFactory{
+		"route": newRouteCondition,
+	}
+)
+
+// RegisterLambda 注册可在流水线定义中引用的 lambda 组件，同名时覆盖
+func RegisterLambda(name string, factory LambdaFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	lambdas[name] = factory
+}
+
+// RegisterTool 注册可在流水线定义中引用的工具，同名时覆盖
+func RegisterTool(name string, factory ToolFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	toolFactories[name] = factory
+}
+
+// RegisterCondition 注册可在流水线定义中引用的分支条件，同名时覆盖
+func RegisterCondition(name string, factory ConditionFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	conditions[name] = factory
+}
+
+func lookupLambda(name string) (LambdaFactory, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := lambdas[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown lambda: %s, available: %v", name, sortedKeys(lambdas))
+	}
+	return factory, nil
+}
+
+func lookupCondition(name string) (ConditionFactory, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := conditions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown condition: %s, available: %v", name, sortedKeys(conditions))
+	}
+	return factory, nil
+}
+
+// newTools 按名称创建工具，未注册的名称从外部 MCP 服务的工具中查找，所属服务尚未连接的工具暂时跳过
+// 工具调用按权限策略检查，输出按配置限制长度，限制开启时自动挂载分页读取完整输出的工具
+func newTools(ctx context.Context, app *di.Application, names []string) ([]tool.BaseTool, error) {
+	registryMu.RLock()
+	factories := make([]ToolFactory, 0, len(names))
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		factory, ok := toolFactories[name]
+		if !ok {
+			t := app.MCP.Tool(name)
+			if t == nil && app.MCP.Owns(name) {
+				zap.L().Warn("MCP tool is not available, skipped until its server provides it", zap.String("tool", name))
+				continue
+			}
+			if t == nil {
+				available := append(sortedKeys(toolFactories), app.MCP.ToolNames()...)
+				registryMu.RUnlock()
+				return nil, fmt.Errorf("unknown tool: %s, available: %v", name, available)
+			}
+			factory = func(ctx context.Context, app *di.Application) (tool.BaseTool, error) {
+				return t, nil
+			}
+		}
+		factories = append(factories, factory)
+		resolved = append(resolved, name)
+	}
+	registryMu.RUnlock()
+	names = resolved
+
+	limiter, err := newOutputLimiter(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	tools := make([]tool.BaseTool, 0, len(factories)+1)
+	for i, factory := range factories {
+		t, err := factory(ctx, app)
+		if err != nil {
+			return nil, err
+		}
+		if names[i] != ToolReadToolOutput {
+			t = limiter.Wrap(names[i], t)
+		}
+		tools = append(tools, app.ToolPolicy.Wrap(names[i], t))
+	}
+	if limiter.Enabled() && !slices.Contains(names, ToolReadToolOutput) {
+		t, err := newReadToolOutput(ctx, app)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, app.ToolPolicy.Wrap(ToolReadToolOutput, t))
+	}
+	return tools, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
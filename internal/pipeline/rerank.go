@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/config"
+	"MoonAgent/pkg/reranker"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+const defaultRerankCandidates = 20
+
+// newReranker 根据配置创建重排器，未启用时返回nil
+func newReranker(ctx context.Context, app *di.Application) (*reranker.Reranker, error) {
+	cfg := app.ServerConfig.RerankConfig
+	if !cfg.Enable {
+		return nil, nil
+	}
+	return newRerankerFromConfig(ctx, app, cfg)
+}
+
+// rerankParams 流水线定义中 rerank 节点的参数，未设置时使用配置文件中的值
+type rerankParams struct {
+	Type string `mapstructure:"type"`
+	TopN int    `mapstructure:"top_n"`
+}
+
+// newRerankLambdaFromParams 流水线定义中的 rerank 节点，不受配置文件中 enable 的影响
+func newRerankLambdaFromParams(ctx context.Context, app *di.Application, params map[string]any) (*compose.Lambda, error) {
+	var p rerankParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	cfg := app.ServerConfig.RerankConfig
+	if p.Type != "" {
+		cfg.Type = p.Type
+	}
+	if p.TopN > 0 {
+		cfg.TopN = p.TopN
+	}
+	rr, err := newRerankerFromConfig(ctx, app, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newRerankLambda(rr), nil
+}
+
+func newRerankerFromConfig(ctx context.Context, app *di.Application, cfg config.RerankConfig) (*reranker.Reranker, error) {
+	var scorer reranker.Scorer
+	switch cfg.Type {
+	case reranker.TypeCrossEncoder:
+		scorer = reranker.NewCrossEncoderScorer(&reranker.CrossEncoderConfig{
+			Endpoint: cfg.Endpoint,
+			APIKey:   cfg.API_KEY,
+			Model:    cfg.Model,
+		})
+	case reranker.TypeLLM:
+		chatModel, err := newChatModel(ctx, app)
+		if err != nil {
+			return nil, err
+		}
+		scorer = reranker.NewLLMScorer(chatModel)
+	case reranker.TypeLexical, "":
+		scorer = reranker.NewLexicalScorer()
+	default:
+		return nil, fmt.Errorf("unknown rerank type: %s", cfg.Type)
+	}
+	return reranker.NewReranker(scorer, cfg.TopN), nil
+}
+
+// newRerankLambda component initialization function of node 'Rerank6' in graph 'Assitant'
+func newRerankLambda(rr *reranker.Reranker) *compose.Lambda {
+	return compose.InvokableLambda(func(ctx context.Context, input []*schema.Document) (output []*schema.Document, err error) {
+		var query string
+		var topK int
+		err = compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+			query = state.Query
+			topK = state.TopK
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		output, err = rr.Rerank(ctx, query, input)
+		if err != nil {
+			return nil, err
+		}
+		if topK > 0 && len(output) > topK {
+			output = output[:topK]
+		}
+		return output, nil
+	})
+}
+
+// candidateRetriever 在重排前扩大召回数量。召回数量固定为候选数，调用方传入的TopK记录在状态中，
+// 重排之后再截断，否则重排只能在最终的TopK个文档中排序
+type candidateRetriever struct {
+	retriever.Retriever
+	topK int
+}
+
+func newCandidateRetriever(r retriever.Retriever, topK int) retriever.Retriever {
+	if topK <= 0 {
+		topK = defaultRerankCandidates
+	}
+	return &candidateRetriever{Retriever: r, topK: topK}
+}
+
+func (r *candidateRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	if topK := retriever.GetCommonOptions(&retriever.Options{}, opts...).TopK; topK != nil && *topK > 0 {
+		// 不在流水线中运行时没有状态，只扩大召回数量
+		_ = compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+			state.TopK = *topK
+			return nil
+		})
+	}
+	opts = append(opts, retriever.WithTopK(r.topK))
+	return r.Retriever.Retrieve(ctx, query, opts...)
+}
@@ -0,0 +1,76 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"MoonAgent/pkg/reranker"
+
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// countingRetriever 返回请求数量的文档，第 i 个文档与查询的相关性随 i 增大
+type countingRetriever struct {
+	topK int
+}
+
+func (r *countingRetriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	r.topK = *retriever.GetCommonOptions(&retriever.Options{TopK: new(int)}, opts...).TopK
+	docs := make([]*schema.Document, 0, r.topK)
+	for i := 0; i < r.topK; i++ {
+		content := "无关内容"
+		for j := 0; j < i; j++ {
+			content += " 重排"
+		}
+		docs = append(docs, &schema.Document{ID: fmt.Sprint(i), Content: content})
+	}
+	return docs, nil
+}
+
+func TestCandidateRetrieverRerankThenTruncate(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRetriever{}
+	g := compose.NewGraph[string, []*schema.Document](compose.WithGenLocalState(func(ctx context.Context) *assistantState {
+		return &assistantState{Query: "重排"}
+	}))
+	_ = g.AddRetrieverNode("retriever", newCandidateRetriever(inner, 10))
+	_ = g.AddLambdaNode("rerank", newRerankLambda(reranker.NewReranker(reranker.NewLexicalScorer(), 5)))
+	_ = g.AddEdge(compose.START, "retriever")
+	_ = g.AddEdge("retriever", "rerank")
+	_ = g.AddEdge("rerank", compose.END)
+	r, err := g.Compile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := r.Invoke(ctx, "重排", compose.WithRetrieverOption(retriever.WithTopK(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.topK != 10 {
+		t.Errorf("candidates = %d, want 10", inner.topK)
+	}
+	// 请求的 topK 在重排之后生效，保留候选中最相关的文档
+	if got := fmt.Sprint(docIDs(docs)); got != "[9 8 7]" {
+		t.Errorf("docs = %s, want [9 8 7]", got)
+	}
+
+	docs, err = r.Invoke(ctx, "重排")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 5 {
+		t.Errorf("docs without request topK = %d, want top_n 5", len(docs))
+	}
+}
+
+func docIDs(docs []*schema.Document) []string {
+	ids := make([]string, 0, len(docs))
+	for _, d := range docs {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/intent"
+
+	"github.com/cloudwego/eino/compose"
+	"go.uber.org/zap"
+)
+
+// newClassifier 根据配置创建意图分类器
+func newClassifier(ctx context.Context, app *di.Application) (intent.Classifier, error) {
+	cfg := app.ServerConfig.RouterConfig
+	defaultRoute := intent.RouteKnowledge
+	if cfg.Default != "" {
+		route, err := intent.ParseRoute(cfg.Default)
+		if err != nil {
+			return nil, err
+		}
+		defaultRoute = route
+	}
+
+	rules := intent.NewRuleClassifier(&intent.RuleConfig{
+		ChatKeywords:  cfg.ChatKeywords,
+		AgentKeywords: cfg.AgentKeywords,
+		ChatMaxLength: cfg.ChatMaxLength,
+		Default:       defaultRoute,
+	})
+	switch cfg.Type {
+	case intent.TypeRules, "":
+		return rules, nil
+	case intent.TypeLLM:
+		chatModel, err := newChatModel(ctx, app)
+		if err != nil {
+			return nil, err
+		}
+		return intent.NewLLMClassifier(chatModel, describeKnowledgeBases(app), rules), nil
+	case intent.TypeOff:
+		return &intent.FixedClassifier{Route: defaultRoute}, nil
+	default:
+		return nil, fmt.Errorf("unknown router type: %s", cfg.Type)
+	}
+}
+
+// describeKnowledgeBases 知识库名称和说明，供大模型判断问题是否属于知识库范围
+func describeKnowledgeBases(app *di.Application) string {
+	var desc strings.Builder
+	for _, kb := range app.KnowledgeBases.List() {
+		desc.WriteString("- " + kb.Name)
+		if kb.Description != "" {
+			desc.WriteString(": " + kb.Description)
+		}
+		desc.WriteString("\n")
+	}
+	return strings.TrimSpace(desc.String())
+}
+
+// newClassifyLambda component initialization function of node 'Classify9' in graph 'Assitant'
+// 判断消息的路线并记录在状态中，输入原样传给下游
+func newClassifyLambda(classifier intent.Classifier) *compose.Lambda {
+	return compose.InvokableLambda(func(ctx context.Context, input string) (output string, err error) {
+		if err := rememberQuestion(ctx, input); err != nil {
+			return "", err
+		}
+		route, err := classifier.Classify(ctx, historyFromContext(ctx), input)
+		if err != nil {
+			zap.S().Warn("Classify intent failed", zap.String("error", err.Error()))
+			route = intent.RouteKnowledge
+		}
+		err = compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+			state.Route = route
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		traceFromContext(ctx).setRoute(string(route))
+		return input, nil
+	})
+}
+
+// newRouteBranch 按状态中的路线选择下游节点
+func newRouteBranch(nodes map[intent.Route]string) *compose.GraphBranch {
+	endNodes := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		endNodes[node] = true
+	}
+	return compose.NewGraphBranch(func(ctx context.Context, input string) (string, error) {
+		var route intent.Route
+		err := compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+			route = state.Route
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		node, ok := nodes[route]
+		if !ok {
+			return "", fmt.Errorf("no node for route: %s", route)
+		}
+		return node, nil
+	}, endNodes)
+}
+
+// newRouteCondition 流水线定义中的 route 分支条件，路由表的键为路线名称
+func newRouteCondition(ctx context.Context, app *di.Application, routes map[string]string) (func(ctx context.Context, input any) (string, error), error) {
+	for name := range routes {
+		if _, err := intent.ParseRoute(name); err != nil {
+			return nil, err
+		}
+	}
+	return func(ctx context.Context, input any) (string, error) {
+		var route intent.Route
+		err := compose.ProcessState[*assistantState](ctx, func(_ context.Context, state *assistantState) error {
+			route = state.Route
+			return nil
+		})
+		return string(route), err
+	}, nil
+}
+
+// newPromptInputLambda 把用户输入转换为不带检索资料的提示词变量
+func newPromptInputLambda() *compose.Lambda {
+	return compose.InvokableLambda(func(ctx context.Context, input string) (output map[string]any, err error) {
+		if err := rememberQuestion(ctx, input); err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"user_input": input,
+			"history":    historyFromContext(ctx),
+		}, nil
+	})
+}
@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// 节点类型
+const (
+	NodeTypeRetriever = "retriever"
+	NodeTypeTemplate  = "template"
+	NodeTypeModel     = "model"
+	NodeTypeAgent     = "agent"
+	NodeTypeLambda    = "lambda"
+)
+
+// GraphSpec 配置文件中描述的流水线，输入为用户问题，输出为回答消息
+type GraphSpec struct {
+	Name        string       `mapstructure:"name" yaml:"name"`
+	Description string       `mapstructure:"description" yaml:"description"`
+	Nodes       []NodeSpec   `mapstructure:"nodes" yaml:"nodes"`
+	Edges       []EdgeSpec   `mapstructure:"edges" yaml:"edges"`
+	Branches    []BranchSpec `mapstructure:"branches" yaml:"branches"`
+}
+
+type NodeSpec struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	// 组件类型: retriever / template / model / agent / lambda
+	Type string `mapstructure:"type" yaml:"type"`
+	// lambda 节点在注册表中的名称
+	Lambda string `mapstructure:"lambda" yaml:"lambda"`
+	// 组件参数，不同类型的节点含义不同
+	Params map[string]any `mapstructure:"params" yaml:"params"`
+}
+
+// EdgeSpec 节点之间的边，START 和 END 表示图的起点和终点
+type EdgeSpec struct {
+	From string `mapstructure:"from" yaml:"from"`
+	To   string `mapstructure:"to" yaml:"to"`
+}
+
+// BranchSpec 从 From 节点出发的分支，按条件的返回值选择 Routes 中的下游节点
+type BranchSpec struct {
+	From string `mapstructure:"from" yaml:"from"`
+	// 分支条件在注册表中的名称
+	Condition string            `mapstructure:"condition" yaml:"condition"`
+	Routes    map[string]string `mapstructure:"routes" yaml:"routes"`
+}
+
+// LoadGraphSpec 读取单个流水线定义文件
+func LoadGraphSpec(path string) (*GraphSpec, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	spec := new(GraphSpec)
+	if err := v.Unmarshal(spec); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline %s: %w", path, err)
+	}
+	if spec.Name == "" {
+		return nil, fmt.Errorf("pipeline %s has no name", path)
+	}
+	return spec, nil
+}
+
+// LoadGraphSpecs 读取目录下所有 yaml 流水线定义，目录不存在时返回空
+func LoadGraphSpecs(dir string) ([]*GraphSpec, error) {
+	var paths []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	sort.Strings(paths)
+
+	specs := make([]*GraphSpec, 0, len(paths))
+	names := make(map[string]string)
+	for _, path := range paths {
+		spec, err := LoadGraphSpec(path)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := names[spec.Name]; ok {
+			return nil, fmt.Errorf("pipeline %s is defined in both %s and %s", spec.Name, prev, path)
+		}
+		names[spec.Name] = path
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
@@ -3,11 +3,19 @@ package pipeline
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 
 	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/sandbox"
 	"MoonAgent/pkg/tools"
+	"MoonAgent/pkg/webfetch"
+	"MoonAgent/pkg/websearch"
 
 	"github.com/cloudwego/eino-ext/components/tool/googlesearch"
+	"github.com/cloudwego/eino/components/retriever"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
 )
@@ -24,15 +32,64 @@ func newGoogleSearchTool(ctx context.Context, app *di.Application) (bt tool.Base
 	return bt, nil
 }
 
+type WebSearchImpl struct {
+	config *WebSearchConfig
+}
+
+type WebSearchConfig struct {
+	Searcher *websearch.Searcher
+}
+
+func newWebSearch(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	searcher, err := websearch.NewSearcherFromConfig(&app.ServerConfig.ToolsConfig.Search, &app.ServerConfig.BrowserConfig)
+	if err != nil {
+		return nil, err
+	}
+	config := &WebSearchConfig{
+		Searcher: searcher,
+	}
+	bt = &WebSearchImpl{config: config}
+	return bt, nil
+}
+
+func (impl *WebSearchImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "web_search",
+		Desc: "在互联网上搜索，返回带编号的标题、网址和摘要。需要阅读详细内容时用 http_fetch 打开结果中的网址",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"query": {
+				Type:     schema.String,
+				Desc:     "搜索关键词",
+				Required: true,
+			},
+			"num": {
+				Type: schema.Integer,
+				Desc: fmt.Sprintf("返回的结果数，最多 %d", websearch.MaxResults),
+			},
+		}),
+	}, nil
+}
+
+func (impl *WebSearchImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.WebSearchParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	return tools.SearchWeb(ctx, impl.config.Searcher, p)
+}
+
 type JumpWebPageImpl struct {
 	config *JumpWebPageConfig
 }
 
 type JumpWebPageConfig struct {
+	// CheckURL 检查浏览器最终打开的地址
+	CheckURL webfetch.URLCheck
 }
 
-func newJumpWebPage(ctx context.Context) (bt tool.BaseTool, err error) {
-	config := &JumpWebPageConfig{}
+func newJumpWebPage(ctx context.Context, app *di.Application) (bt tool.BaseTool, err error) {
+	config := &JumpWebPageConfig{CheckURL: policyURLCheck(app, ToolJumpWebPage)}
 	bt = &JumpWebPageImpl{config: config}
 	return bt, nil
 }
@@ -40,11 +97,12 @@ func newJumpWebPage(ctx context.Context) (bt tool.BaseTool, err error) {
 func (impl *JumpWebPageImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
 	return &schema.ToolInfo{
 		Name: "网页跳转",
-		Desc: "跳转到指定网页",
+		Desc: "用浏览器打开指定网页，速度较慢，适合依赖 JS 渲染、http_fetch 无法获取正文的页面",
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
 			"url": {
-				Type: "string",
-				Desc: "网页地址",
+				Type:     "string",
+				Desc:     "网页地址，以 http:// 或 https:// 开头",
+				Required: true,
 			},
 		}),
 	}, nil
@@ -56,12 +114,204 @@ func (impl *JumpWebPageImpl) InvokableRun(ctx context.Context, argumentsInJSON s
 	if err != nil {
 		return "", err
 	}
-	if p.URL != "" {
-		return tools.GoToWebPage(ctx, p.URL)
+	u, err := url.Parse(strings.TrimSpace(p.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid url %q: only http and https urls are supported", p.URL)
+	}
+	output, err := tools.GoToWebPage(ctx, u.String(), impl.config.CheckURL)
+	var denied *webfetch.DeniedError
+	if errors.As(err, &denied) {
+		return denied.Reason, nil
 	}
-	return "", nil
+	return output, err
 }
 
 type GoToWebPageParam struct {
 	URL string `json:"url"`
 }
+
+type HTTPFetchImpl struct {
+	config *HTTPFetchConfig
+}
+
+type HTTPFetchConfig struct {
+	Fetcher *webfetch.Fetcher
+	Options tools.HTTPFetchOptions
+}
+
+func newHTTPFetch(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	cfg := &app.ServerConfig.ToolsConfig.HTTPFetch
+	checkURL := policyURLCheck(app, ToolHTTPFetch)
+	config := &HTTPFetchConfig{
+		Fetcher: webfetch.NewFetcher(cfg).WithURLCheck(checkURL),
+		Options: tools.HTTPFetchOptions{
+			MaxContentLength: cfg.MaxContentLength,
+			MaxLinks:         cfg.MaxLinks,
+			CheckURL:         checkURL,
+		},
+	}
+	// 浏览器自己解析域名、跟随重定向和加载子资源，无法套用内网地址检查，只在允许访问内网时回退
+	if cfg.AllowPrivate {
+		config.Options.BrowserFallbackMinLength = cfg.BrowserFallbackMinLength
+	}
+	bt = &HTTPFetchImpl{config: config}
+	return bt, nil
+}
+
+func (impl *HTTPFetchImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "http_fetch",
+		Desc: "抓取网页并提取正文，返回 markdown 格式的标题和正文，速度快于网页跳转。适合阅读文章、文档等静态页面",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"url": {
+				Type:     schema.String,
+				Desc:     "网页地址，只支持 http 和 https",
+				Required: true,
+			},
+			"include_links": {
+				Type: schema.Boolean,
+				Desc: "为 true 时附带页面中的链接列表，便于继续浏览",
+			},
+		}),
+	}, nil
+}
+
+func (impl *HTTPFetchImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.HTTPFetchParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	output, err := tools.FetchWebPage(ctx, impl.config.Fetcher, p, impl.config.Options)
+	var denied *webfetch.DeniedError
+	if errors.As(err, &denied) {
+		return denied.Reason, nil
+	}
+	return output, err
+}
+
+// policyURLCheck 按工具权限策略检查工具实际访问的地址，参数中的地址在调用前已经检查，
+// 这里覆盖重定向后的地址和浏览器最终打开的地址，拒绝时返回交给模型的结构化错误
+func policyURLCheck(app *di.Application, name string) webfetch.URLCheck {
+	return func(ctx context.Context, u *url.URL) string {
+		return app.ToolPolicy.AuthorizeURL(ctx, name, u.String())
+	}
+}
+
+type KnowledgeSearchImpl struct {
+	config *KnowledgeSearchConfig
+}
+
+type KnowledgeSearchConfig struct {
+	Retriever retriever.Retriever
+}
+
+func newKnowledgeSearch(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	config := &KnowledgeSearchConfig{
+		Retriever: app.Retriever,
+	}
+	bt = &KnowledgeSearchImpl{config: config}
+	return bt, nil
+}
+
+func (impl *KnowledgeSearchImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "knowledge_search",
+		Desc: "在内部知识库中检索资料，返回带片段ID的相关片段。资料不足时可以换用更具体的查询多次检索",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"query": {
+				Type:     schema.String,
+				Desc:     "检索查询，应是独立完整的问题或关键词",
+				Required: true,
+			},
+			"top_k": {
+				Type: schema.Integer,
+				Desc: "返回的片段数，默认使用检索配置的 top_k，最多10",
+			},
+			"knowledge_bases": {
+				Type:     schema.Array,
+				Desc:     "检索的知识库名称，为空时使用默认知识库",
+				ElemInfo: &schema.ParameterInfo{Type: schema.String},
+			},
+			"metadata": {
+				Type: schema.Object,
+				Desc: "按文档元数据等值过滤，例如 {\"product\": \"muelsyse\"}，值为数组时表示任一匹配",
+			},
+		}),
+	}, nil
+}
+
+func (impl *KnowledgeSearchImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.KnowledgeSearchParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	return tools.SearchKnowledge(ctx, impl.config.Retriever, p)
+}
+
+type CodeExecImpl struct {
+	config *CodeExecConfig
+}
+
+type CodeExecConfig struct {
+	Runner *sandbox.Runner
+}
+
+func newCodeExec(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	if !app.ServerConfig.ToolsConfig.CodeExec.Enable {
+		return nil, errors.New("code_exec tool is disabled, enable it in tools.code_exec")
+	}
+	runner, err := sandbox.NewRunner(&app.ServerConfig.ToolsConfig.CodeExec)
+	if err != nil {
+		return nil, err
+	}
+	config := &CodeExecConfig{
+		Runner: runner,
+	}
+	bt = &CodeExecImpl{config: config}
+	return bt, nil
+}
+
+func (impl *CodeExecImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	runner := impl.config.Runner
+	languages := runner.Languages()
+	desc := "在临时工作区中执行代码，用于计算和处理数据，返回 stdout、stderr、退出码和代码写入工作区的文件。"
+	if runner.Isolated() {
+		desc = "在隔离的临时工作区中执行代码，用于计算和处理数据，返回 stdout、stderr、退出码和代码写入工作区的文件。只能访问工作区和系统目录，"
+	}
+	if !runner.Network() {
+		desc += "无法访问网络，"
+	}
+	desc += fmt.Sprintf("有 CPU 时间、内存、磁盘和输出长度限制，每次执行的工作区相互独立。可用语言: %s", strings.Join(languages, ", "))
+	return &schema.ToolInfo{
+		Name: "code_exec",
+		Desc: desc,
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"language": {
+				Type:     schema.String,
+				Desc:     "代码语言",
+				Enum:     languages,
+				Required: true,
+			},
+			"code": {
+				Type:     schema.String,
+				Desc:     "完整的程序源码，Go 代码需要包含 package main 和 main 函数；用 print 输出需要的结果",
+				Required: true,
+			},
+			"files": {
+				Type: schema.Object,
+				Desc: "执行前写入工作区的输入文件，键为相对路径，值为文件内容",
+			},
+		}),
+	}, nil
+}
+
+func (impl *CodeExecImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.CodeExecParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	return tools.ExecuteCode(ctx, impl.config.Runner, p)
+}
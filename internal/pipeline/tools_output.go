@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/tooloutput"
+	"MoonAgent/pkg/tools"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// newOutputLimiter 按配置创建工具输出长度限制，开启摘要时创建对话模型
+func newOutputLimiter(ctx context.Context, app *di.Application) (*tooloutput.Limiter, error) {
+	cfg := &app.ServerConfig.ToolsConfig.Output
+	var cm model.BaseChatModel
+	if cfg.Summarize {
+		var err error
+		if cm, err = newChatModel(ctx, app); err != nil {
+			return nil, err
+		}
+	}
+	return tooloutput.NewLimiter(cfg, app.ToolOutputs, cm), nil
+}
+
+type ReadToolOutputImpl struct {
+	config *ReadToolOutputConfig
+}
+
+type ReadToolOutputConfig struct {
+	Store *tooloutput.Store
+	// MaxChars 单页的最大字符数
+	MaxChars int
+}
+
+func newReadToolOutput(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	config := &ReadToolOutputConfig{Store: app.ToolOutputs, MaxChars: app.ServerConfig.ToolsConfig.Output.MaxChars}
+	if config.MaxChars <= 0 {
+		config.MaxChars = tooloutput.DefaultMaxChars
+	}
+	bt = &ReadToolOutputImpl{config: config}
+	return bt, nil
+}
+
+func (impl *ReadToolOutputImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: ToolReadToolOutput,
+		Desc: "分页读取因过长被截断或摘要的工具输出原文，artifact_id 见被截断输出中的提示",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"artifact_id": {
+				Type:     schema.String,
+				Desc:     "完整输出的ID，形如 out_xxx",
+				Required: true,
+			},
+			"offset": {
+				Type: schema.Integer,
+				Desc: "起始字符位置，从0开始，默认为0",
+			},
+			"limit": {
+				Type: schema.Integer,
+				Desc: "最多读取的字符数，默认读取一页",
+			},
+		}),
+	}, nil
+}
+
+func (impl *ReadToolOutputImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.ReadToolOutputParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	return tools.ReadToolOutput(impl.config.Store, p, impl.config.MaxChars)
+}
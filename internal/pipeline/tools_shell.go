@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/approval"
+	"MoonAgent/pkg/sandbox"
+	"MoonAgent/pkg/tools"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+type ShellImpl struct {
+	config *ShellToolConfig
+}
+
+type ShellToolConfig struct {
+	Runner    *sandbox.ShellRunner
+	Approvals *approval.Manager
+}
+
+func newShell(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	if !app.ServerConfig.ToolsConfig.Shell.Enable {
+		return nil, errors.New("shell tool is disabled, enable it in tools.shell")
+	}
+	runner, err := sandbox.NewShellRunner(&app.ServerConfig.ToolsConfig.Shell)
+	if err != nil {
+		return nil, err
+	}
+	config := &ShellToolConfig{
+		Runner:    runner,
+		Approvals: app.Approvals,
+	}
+	bt = &ShellImpl{config: config}
+	return bt, nil
+}
+
+func (impl *ShellImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	commands := impl.config.Runner.Commands()
+	names := make([]string, 0, len(commands))
+	var desc strings.Builder
+	for _, c := range commands {
+		names = append(names, c.Name)
+		desc.WriteString(fmt.Sprintf("\n- %s", c.Name))
+		if c.Description != "" {
+			desc.WriteString(": " + c.Description)
+		}
+		if len(c.Args) > 0 {
+			desc.WriteString(fmt.Sprintf("；参数规则: %s", strings.Join(c.Args, " | ")))
+		}
+		if c.RequireApproval {
+			desc.WriteString("；需要人工审批")
+		}
+	}
+	return &schema.ToolInfo{
+		Name: "shell",
+		Desc: fmt.Sprintf("在服务器上执行白名单中的命令，用于收集磁盘占用、日志等诊断信息，返回 stdout、stderr 和退出码。"+
+			"命令不经过 shell，通配符、管道、重定向和环境变量不会生效；每个参数必须匹配命令的参数规则，{path} 表示工作目录 %s 中的路径。可用命令:%s",
+			impl.config.Runner.WorkDir(), desc.String()),
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"command": {
+				Type:     schema.String,
+				Desc:     "命令名",
+				Enum:     names,
+				Required: true,
+			},
+			"args": {
+				Type:     schema.Array,
+				Desc:     "命令参数，每个元素是一个独立的参数，不要把多个参数拼在一起",
+				ElemInfo: &schema.ParameterInfo{Type: schema.String},
+			},
+		}),
+	}, nil
+}
+
+func (impl *ShellImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.ShellParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	return tools.RunShell(ctx, impl.config.Runner, impl.config.Approvals, p)
+}
@@ -0,0 +1,206 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"MoonAgent/cmd/di"
+	"MoonAgent/pkg/tools"
+	"MoonAgent/pkg/workspace"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// WorkspaceToolConfig 工作区文件工具共用的配置
+type WorkspaceToolConfig struct {
+	Workspace *workspace.Workspace
+}
+
+func newWorkspaceToolConfig(app *di.Application) (*WorkspaceToolConfig, error) {
+	cfg := &app.ServerConfig.ToolsConfig.Workspace
+	if !cfg.Enable {
+		return nil, errors.New("workspace tools are disabled, enable them in tools.workspace")
+	}
+	ws, err := workspace.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &WorkspaceToolConfig{Workspace: ws}, nil
+}
+
+type ReadFileImpl struct {
+	config *WorkspaceToolConfig
+}
+
+func newReadFile(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	config, err := newWorkspaceToolConfig(app)
+	if err != nil {
+		return nil, err
+	}
+	bt = &ReadFileImpl{config: config}
+	return bt, nil
+}
+
+func (impl *ReadFileImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "read_file",
+		Desc: "读取工作区中的文本文件，路径相对于工作区根目录。大文件可以用 offset 和 limit 分段读取",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"path": {
+				Type:     schema.String,
+				Desc:     "文件路径，相对于工作区根目录",
+				Required: true,
+			},
+			"offset": {
+				Type: schema.Integer,
+				Desc: "起始行号，从1开始，默认为1",
+			},
+			"limit": {
+				Type: schema.Integer,
+				Desc: "最多读取的行数，默认读取到文件末尾",
+			},
+		}),
+	}, nil
+}
+
+func (impl *ReadFileImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.ReadFileParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	return tools.ReadFile(impl.config.Workspace, p)
+}
+
+type WriteFileImpl struct {
+	config *WorkspaceToolConfig
+}
+
+func newWriteFile(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	config, err := newWorkspaceToolConfig(app)
+	if err != nil {
+		return nil, err
+	}
+	if config.Workspace.ReadOnly() {
+		return nil, workspace.ErrReadOnly
+	}
+	bt = &WriteFileImpl{config: config}
+	return bt, nil
+}
+
+func (impl *WriteFileImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "write_file",
+		Desc: "把内容写入工作区中的文件，用于生成报告等产出，路径相对于工作区根目录，目录不存在时自动创建。默认覆盖已有文件",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"path": {
+				Type:     schema.String,
+				Desc:     "文件路径，相对于工作区根目录",
+				Required: true,
+			},
+			"content": {
+				Type:     schema.String,
+				Desc:     "写入的完整内容",
+				Required: true,
+			},
+			"append": {
+				Type: schema.Boolean,
+				Desc: "为 true 时追加到文件末尾而不是覆盖",
+			},
+		}),
+	}, nil
+}
+
+func (impl *WriteFileImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.WriteFileParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	return tools.WriteFile(impl.config.Workspace, p)
+}
+
+type ListDirImpl struct {
+	config *WorkspaceToolConfig
+}
+
+func newListDir(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	config, err := newWorkspaceToolConfig(app)
+	if err != nil {
+		return nil, err
+	}
+	bt = &ListDirImpl{config: config}
+	return bt, nil
+}
+
+func (impl *ListDirImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "list_dir",
+		Desc: "列出工作区中目录的内容，目录以 / 结尾，文件附带大小",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"path": {
+				Type: schema.String,
+				Desc: "目录路径，相对于工作区根目录，默认为根目录",
+			},
+			"recursive": {
+				Type: schema.Boolean,
+				Desc: "为 true 时递归列出子目录",
+			},
+		}),
+	}, nil
+}
+
+func (impl *ListDirImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.ListDirParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	return tools.ListDir(impl.config.Workspace, p)
+}
+
+type GrepImpl struct {
+	config *WorkspaceToolConfig
+}
+
+func newGrep(ctx context.Context, app *di.Application) (bt tool.InvokableTool, err error) {
+	config, err := newWorkspaceToolConfig(app)
+	if err != nil {
+		return nil, err
+	}
+	bt = &GrepImpl{config: config}
+	return bt, nil
+}
+
+func (impl *GrepImpl) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "grep",
+		Desc: "在工作区的文本文件中按正则表达式搜索，返回 路径:行号: 内容",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"pattern": {
+				Type:     schema.String,
+				Desc:     "正则表达式，使用 Go 正则语法",
+				Required: true,
+			},
+			"path": {
+				Type: schema.String,
+				Desc: "搜索的目录或文件，相对于工作区根目录，默认为根目录",
+			},
+			"glob": {
+				Type: schema.String,
+				Desc: "按文件名过滤，例如 *.md",
+			},
+		}),
+	}, nil
+}
+
+func (impl *GrepImpl) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	p := &tools.GrepParam{}
+	err := json.Unmarshal([]byte(argumentsInJSON), p)
+	if err != nil {
+		return "", err
+	}
+	return tools.Grep(impl.config.Workspace, p)
+}
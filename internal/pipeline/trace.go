@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+type traceKey struct{}
+
+// Source 回答引用的检索片段
+type Source struct {
+	// 片段在提示词中的编号，对应回答中的 [n]
+	Index         int     `json:"index"`
+	DocumentID    string  `json:"documentId"`
+	ChunkID       string  `json:"chunkId"`
+	KnowledgeBase string  `json:"knowledgeBase,omitempty"`
+	Snippet       string  `json:"snippet"`
+	Score         float64 `json:"score"`
+	// 片段全文，仅在进程内使用，例如评测时提供给评审模型
+	Content string `json:"-"`
+}
+
+// TraceInfo 返回给调用方的调试信息
+type TraceInfo struct {
+	// 实际用于检索的查询
+	Query string `json:"query"`
+	// 意图路由选择的路线
+	Route string `json:"route"`
+}
+
+// Trace 记录单次请求在流水线中产生的中间结果，供接口层返回给调用方
+type Trace struct {
+	mu      sync.Mutex
+	sources []Source
+	// 结合对话历史压缩后用于检索的查询
+	query string
+	route string
+}
+
+// WithTrace 在上下文中挂载一个新的Trace
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	t := &Trace{}
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+// traceFromContext 获取上下文中的Trace，不存在时返回nil
+func traceFromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+func (t *Trace) setSources(sources []Source) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sources = sources
+}
+
+// Sources 返回检索到的引用来源
+func (t *Trace) Sources() []Source {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sources == nil {
+		return []Source{}
+	}
+	return t.sources
+}
+
+func (t *Trace) setQuery(query string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.query = query
+}
+
+func (t *Trace) setRoute(route string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.route = route
+}
+
+// Info 返回调试信息
+func (t *Trace) Info() TraceInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return TraceInfo{
+		Query: t.query,
+		Route: t.route,
+	}
+}
@@ -0,0 +1,161 @@
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"MoonAgent/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout 默认等待审批的时间
+const DefaultTimeout = 5 * time.Minute
+
+var ErrNotFound = errors.New("approval request not found")
+
+// Request 等待人工审批的操作
+type Request struct {
+	ID   string `json:"id"`
+	Tool string `json:"tool"`
+	// Action 待执行的操作，例如完整的命令行
+	Action    string    `json:"action"`
+	Agent     string    `json:"agent,omitempty"`
+	SessionID string    `json:"sessionId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Decision 审批结果
+type Decision struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type pending struct {
+	Request
+	decision chan Decision
+}
+
+// Manager 保存等待审批的操作，工具调用阻塞到有人批准、拒绝或超时
+type Manager struct {
+	mu      sync.Mutex
+	pending map[string]*pending
+	timeout time.Duration
+	// closed 审批接口关闭，没有人能处理审批
+	closed bool
+}
+
+// ProvideManager 没有配置管理员令牌时审批接口关闭，需要审批的操作直接拒绝，不必等到超时
+func ProvideManager(cfg *config.ServerConfig) *Manager {
+	m := NewManager(cfg.ToolsConfig.Approval.Timeout)
+	m.closed = cfg.ToolsConfig.Approval.Token == ""
+	return m
+}
+
+// NewManager timeout 不大于 0 时使用默认值
+func NewManager(timeout time.Duration) *Manager {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Manager{pending: make(map[string]*pending), timeout: timeout}
+}
+
+// closedDecision 审批接口关闭时的结果
+var closedDecision = Decision{Approved: false, Reason: "审批接口未开启，需要审批的操作不能执行"}
+
+// Close 关闭审批，用于不提供审批接口的运行方式，例如 MCP 标准输入输出服务；
+// 等待中的操作立即被拒绝，之后提交的审批直接拒绝
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for id, p := range m.pending {
+		delete(m.pending, id)
+		p.decision <- closedDecision
+	}
+}
+
+// Request 提交审批并等待结果，超时视为拒绝；ctx 取消时撤回审批并返回 ctx 的错误
+func (m *Manager) Request(ctx context.Context, req Request) (Decision, error) {
+	now := time.Now()
+	req.ID = newID()
+	req.CreatedAt = now
+	req.ExpiresAt = now.Add(m.timeout)
+	p := &pending{Request: req, decision: make(chan Decision, 1)}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		zap.L().Warn("Approval endpoints are disabled, rejecting", zap.String("tool", req.Tool), zap.String("action", req.Action))
+		return closedDecision, nil
+	}
+	m.pending[req.ID] = p
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.pending, req.ID)
+		m.mu.Unlock()
+	}()
+
+	zap.L().Info("Waiting for approval",
+		zap.String("id", req.ID),
+		zap.String("tool", req.Tool),
+		zap.String("action", req.Action),
+		zap.String("agent", req.Agent),
+		zap.String("session", req.SessionID))
+
+	timer := time.NewTimer(m.timeout)
+	defer timer.Stop()
+	select {
+	case d := <-p.decision:
+		zap.L().Info("Approval decided",
+			zap.String("id", req.ID),
+			zap.Bool("approved", d.Approved),
+			zap.String("reason", d.Reason))
+		return d, nil
+	case <-timer.C:
+		zap.L().Warn("Approval timed out", zap.String("id", req.ID), zap.String("action", req.Action))
+		return Decision{Approved: false, Reason: "审批超时"}, nil
+	case <-ctx.Done():
+		return Decision{}, ctx.Err()
+	}
+}
+
+// Pending 等待审批的操作，按提交时间排序
+func (m *Manager) Pending() []Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	requests := make([]Request, 0, len(m.pending))
+	for _, p := range m.pending {
+		requests = append(requests, p.Request)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+	})
+	return requests
+}
+
+// Decide 批准或拒绝操作，操作不存在或已经有结果时返回 ErrNotFound
+func (m *Manager) Decide(id string, d Decision) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pending[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.pending, id)
+	p.decision <- d
+	return nil
+}
+
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return "apr_" + hex.EncodeToString(b[:])
+}
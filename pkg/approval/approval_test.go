@@ -0,0 +1,135 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"MoonAgent/pkg/config"
+)
+
+// waitPending 等待审批出现在列表中，返回它的ID
+func waitPending(t *testing.T, m *Manager) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if pending := m.Pending(); len(pending) > 0 {
+			return pending[0].ID
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("approval request not submitted")
+	return ""
+}
+
+type outcome struct {
+	decision Decision
+	err      error
+}
+
+// submit 在后台提交审批，结果写入返回的通道
+func submit(ctx context.Context, m *Manager) <-chan outcome {
+	ch := make(chan outcome, 1)
+	go func() {
+		d, err := m.Request(ctx, Request{Tool: "shell", Action: "rm a.txt"})
+		ch <- outcome{d, err}
+	}()
+	return ch
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision Decision
+	}{
+		{"approve", Decision{Approved: true}},
+		{"reject", Decision{Approved: false, Reason: "不允许删除"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(time.Minute)
+			ch := submit(context.Background(), m)
+			id := waitPending(t, m)
+			req := m.Pending()[0]
+			if req.Tool != "shell" || req.Action != "rm a.txt" || !req.ExpiresAt.After(req.CreatedAt) {
+				t.Errorf("pending request = %+v", req)
+			}
+			if err := m.Decide(id, tt.decision); err != nil {
+				t.Fatalf("Decide: %v", err)
+			}
+			got := <-ch
+			if got.err != nil || got.decision != tt.decision {
+				t.Errorf("Request = %+v, %v, want %+v", got.decision, got.err, tt.decision)
+			}
+			if len(m.Pending()) != 0 {
+				t.Error("decided request still pending")
+			}
+			// 已经有结果的审批不能再次处理
+			if err := m.Decide(id, tt.decision); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Decide err = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestDecideUnknown(t *testing.T) {
+	if err := NewManager(0).Decide("apr_missing", Decision{Approved: true}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	m := NewManager(20 * time.Millisecond)
+	d, err := m.Request(context.Background(), Request{Tool: "shell", Action: "rm a.txt"})
+	if err != nil || d.Approved || d.Reason != "审批超时" {
+		t.Errorf("Request = %+v, %v, want rejected by timeout", d, err)
+	}
+	if len(m.Pending()) != 0 {
+		t.Error("timed out request still pending")
+	}
+}
+
+func TestRequestContextCanceled(t *testing.T) {
+	m := NewManager(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := submit(ctx, m)
+	waitPending(t, m)
+	cancel()
+	got := <-ch
+	if !errors.Is(got.err, context.Canceled) || got.decision.Approved {
+		t.Errorf("Request = %+v, %v, want context.Canceled", got.decision, got.err)
+	}
+	if len(m.Pending()) != 0 {
+		t.Error("canceled request still pending")
+	}
+}
+
+func TestClosedManager(t *testing.T) {
+	// 没有配置管理员令牌时不等待，直接拒绝
+	cfg := &config.ServerConfig{}
+	cfg.ToolsConfig.Approval.Timeout = time.Minute
+	m := ProvideManager(cfg)
+	start := time.Now()
+	d, err := m.Request(context.Background(), Request{Tool: "shell", Action: "rm a.txt"})
+	if err != nil || d.Approved || d.Reason == "" {
+		t.Errorf("Request = %+v, %v, want rejected", d, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("closed manager waited for a decision")
+	}
+
+	// Close 拒绝正在等待的审批，之后提交的审批也直接拒绝
+	m = NewManager(time.Minute)
+	ch := submit(context.Background(), m)
+	waitPending(t, m)
+	m.Close()
+	got := <-ch
+	if got.err != nil || got.decision.Approved {
+		t.Errorf("pending Request = %+v, %v, want rejected on close", got.decision, got.err)
+	}
+	d, err = m.Request(context.Background(), Request{Tool: "shell", Action: "rm b.txt"})
+	if err != nil || d.Approved || len(m.Pending()) != 0 {
+		t.Errorf("Request after Close = %+v, %v, pending %d", d, err, len(m.Pending()))
+	}
+}
@@ -1,11 +1,28 @@
 package config
 
+import "time"
+
+// 向量库后端
+const (
+	BackendMilvus = "milvus"
+	BackendLocal  = "local"
+)
+
 type ServerConfig struct {
-	Port           string         `mapstructure:"port" yaml:"port"`
-	Host           string         `mapstructure:"host" yaml:"host"`
-	LLMConfig      LLMConfig      `mapstructure:"llm" yaml:"llm"`
-	DocumentConfig DocumentConfig `mapstructure:"document" yaml:"document"`
-	BrowserConfig  BrowserConfig  `mapstructure:"browser" yaml:"browser"`
+	Port            string          `mapstructure:"port" yaml:"port"`
+	Host            string          `mapstructure:"host" yaml:"host"`
+	LLMConfig       LLMConfig       `mapstructure:"llm" yaml:"llm"`
+	DocumentConfig  DocumentConfig  `mapstructure:"document" yaml:"document"`
+	BrowserConfig   BrowserConfig   `mapstructure:"browser" yaml:"browser"`
+	RerankConfig    RerankConfig    `mapstructure:"rerank" yaml:"rerank"`
+	RetrieverConfig RetrieverConfig `mapstructure:"retriever" yaml:"retriever"`
+	KnowledgeConfig KnowledgeConfig `mapstructure:"knowledge" yaml:"knowledge"`
+	SessionConfig   SessionConfig   `mapstructure:"session" yaml:"session"`
+	RouterConfig    RouterConfig    `mapstructure:"router" yaml:"router"`
+	PipelineConfig  PipelineConfig  `mapstructure:"pipeline" yaml:"pipeline"`
+	TelemetryConfig TelemetryConfig `mapstructure:"telemetry" yaml:"telemetry"`
+	ToolsConfig     ToolsConfig     `mapstructure:"tools" yaml:"tools"`
+	MCPConfig       MCPConfig       `mapstructure:"mcp" yaml:"mcp"`
 }
 
 type LLMConfig struct {
@@ -15,12 +32,390 @@ type LLMConfig struct {
 }
 
 type DocumentConfig struct {
+	// 向量库后端: milvus / local，默认 milvus
+	Backend string `mapstructure:"backend" yaml:"backend"`
 	Addr    string `mapstructure:"addr" yaml:"addr"`
-	API_KEY string `mapstructure:"api_key" yaml:"api_key"`
-	Model   string `mapstructure:"model" yaml:"model"`
+	// local 后端的数据目录，每个集合保存为一个文件
+	LocalPath string `mapstructure:"local_path" yaml:"local_path"`
+	// 相似度度量: cosine / ip / l2，local 后端不支持 l2
+	Metric string `mapstructure:"metric" yaml:"metric"`
+	// milvus 向量索引
+	Index IndexConfig `mapstructure:"index" yaml:"index"`
+	// milvus 中 content 字段的最大长度
+	ContentMaxLength int `mapstructure:"content_max_length" yaml:"content_max_length"`
+	// 向量模型提供方: ark / hash，hash 不依赖外部服务，仅用于开发和测试
+	Embedder string `mapstructure:"embedder" yaml:"embedder"`
+	// hash 向量模型的维度
+	Dimension int    `mapstructure:"dimension" yaml:"dimension"`
+	API_KEY   string `mapstructure:"api_key" yaml:"api_key"`
+	Model     string `mapstructure:"model" yaml:"model"`
+	// 向量缓存
+	Cache EmbeddingCacheConfig `mapstructure:"cache" yaml:"cache"`
+}
+
+type IndexConfig struct {
+	// 索引类型: HNSW / IVF_FLAT / AUTOINDEX
+	Type string `mapstructure:"type" yaml:"type"`
+	// HNSW 建索引参数
+	M              int `mapstructure:"m" yaml:"m"`
+	EfConstruction int `mapstructure:"ef_construction" yaml:"ef_construction"`
+	// HNSW 检索参数
+	Ef int `mapstructure:"ef" yaml:"ef"`
+	// IVF_FLAT 建索引参数
+	Nlist int `mapstructure:"nlist" yaml:"nlist"`
+	// IVF_FLAT 检索参数
+	Nprobe int `mapstructure:"nprobe" yaml:"nprobe"`
+}
+
+type EmbeddingCacheConfig struct {
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// 缓存文件路径
+	Path string `mapstructure:"path" yaml:"path"`
 }
 
 type BrowserConfig struct {
 	API_KEY        string `mapstructure:"api_key" yaml:"api_key"`
 	SearchEngineID string `mapstructure:"search_engine_id" yaml:"search_engine_id"`
 }
+
+type RetrieverConfig struct {
+	TopK int `mapstructure:"top_k" yaml:"top_k"`
+	// 分数阈值，0 表示不过滤
+	ScoreThreshold float64  `mapstructure:"score_threshold" yaml:"score_threshold"`
+	Partitions     []string `mapstructure:"partitions" yaml:"partitions"`
+	// 默认的 milvus 过滤表达式，会与请求中的过滤条件取交集
+	Filter string `mapstructure:"filter" yaml:"filter"`
+}
+
+type KnowledgeConfig struct {
+	// 请求未指定知识库时使用的默认知识库
+	Default string `mapstructure:"default" yaml:"default"`
+	// 知识库注册表的持久化文件
+	StorePath string `mapstructure:"store_path" yaml:"store_path"`
+	// 启动时预置的知识库，已存在的不会被覆盖
+	Bases []KnowledgeBaseConfig `mapstructure:"bases" yaml:"bases"`
+}
+
+type KnowledgeBaseConfig struct {
+	Name           string `mapstructure:"name" yaml:"name"`
+	Description    string `mapstructure:"description" yaml:"description"`
+	Collection     string `mapstructure:"collection" yaml:"collection"`
+	EmbeddingModel string `mapstructure:"embedding_model" yaml:"embedding_model"`
+	ChunkSize      int    `mapstructure:"chunk_size" yaml:"chunk_size"`
+	ChunkOverlap   int    `mapstructure:"chunk_overlap" yaml:"chunk_overlap"`
+	// 检索前的查询扩展
+	QueryExpansion QueryExpansionConfig `mapstructure:"query_expansion" yaml:"query_expansion"`
+}
+
+type QueryExpansionConfig struct {
+	// 把问题改写为适合检索的独立查询
+	Rewrite bool `mapstructure:"rewrite" yaml:"rewrite"`
+	// 额外生成的改写问题数，0 表示不生成
+	MultiQuery int `mapstructure:"multi_query" yaml:"multi_query"`
+	// 生成假设性回答并用其检索
+	HyDE bool `mapstructure:"hyde" yaml:"hyde"`
+}
+
+type RerankConfig struct {
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// 重排后端: cross_encoder / llm / lexical
+	Type string `mapstructure:"type" yaml:"type"`
+	// 重排后保留的文档数
+	TopN int `mapstructure:"top_n" yaml:"top_n"`
+	// 重排前召回的候选文档数
+	Candidates int `mapstructure:"candidates" yaml:"candidates"`
+	// cross_encoder 服务地址及鉴权
+	Endpoint string `mapstructure:"endpoint" yaml:"endpoint"`
+	API_KEY  string `mapstructure:"api_key" yaml:"api_key"`
+	Model    string `mapstructure:"model" yaml:"model"`
+}
+
+type SessionConfig struct {
+	// 会话空闲多久后过期
+	TTL time.Duration `mapstructure:"ttl" yaml:"ttl"`
+	// 每个会话保留的最大消息数
+	MaxMessages int `mapstructure:"max_messages" yaml:"max_messages"`
+	// 压缩问题和生成回答时使用的最近消息数
+	HistoryMessages int `mapstructure:"history_messages" yaml:"history_messages"`
+}
+
+type RouterConfig struct {
+	// 意图分类器: rules / llm / off，off 时所有消息走默认路线
+	Type string `mapstructure:"type" yaml:"type"`
+	// 未命中规则或关闭分类时的路线: chat / knowledge / agent
+	Default string `mapstructure:"default" yaml:"default"`
+	// rules 分类器的闲聊关键词，为空时使用内置列表
+	ChatKeywords []string `mapstructure:"chat_keywords" yaml:"chat_keywords"`
+	// rules 分类器需要联网或工具的关键词，为空时使用内置列表
+	AgentKeywords []string `mapstructure:"agent_keywords" yaml:"agent_keywords"`
+	// 只有不超过该长度的消息才会按闲聊处理
+	ChatMaxLength int `mapstructure:"chat_max_length" yaml:"chat_max_length"`
+}
+
+type PipelineConfig struct {
+	// 流水线定义目录，目录中的每个 yaml 文件描述一条流水线，启动时全部编译
+	Dir string `mapstructure:"dir" yaml:"dir"`
+	// 请求未指定流水线时使用的流水线，为空时使用内置流水线
+	Default string `mapstructure:"default" yaml:"default"`
+}
+
+type TelemetryConfig struct {
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// 导出方式: otlp / stdout
+	Exporter string `mapstructure:"exporter" yaml:"exporter"`
+	// OTLP 协议: grpc / http
+	Protocol string `mapstructure:"protocol" yaml:"protocol"`
+	// OTLP 接收端地址，例如 127.0.0.1:4317，为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint string `mapstructure:"endpoint" yaml:"endpoint"`
+	// 不使用 TLS 连接接收端
+	Insecure    bool   `mapstructure:"insecure" yaml:"insecure"`
+	ServiceName string `mapstructure:"service_name" yaml:"service_name"`
+	// 采样比例，0 或大于 1 时全部采样
+	SampleRatio float64 `mapstructure:"sample_ratio" yaml:"sample_ratio"`
+}
+
+type ToolsConfig struct {
+	// 代码执行工具
+	CodeExec CodeExecConfig `mapstructure:"code_exec" yaml:"code_exec"`
+	// 工作区文件工具
+	Workspace WorkspaceConfig `mapstructure:"workspace" yaml:"workspace"`
+	// 网页抓取工具
+	HTTPFetch HTTPFetchConfig `mapstructure:"http_fetch" yaml:"http_fetch"`
+	// 网页搜索工具
+	Search SearchConfig `mapstructure:"search" yaml:"search"`
+	// 工具参数校验
+	Validation ToolValidationConfig `mapstructure:"validation" yaml:"validation"`
+	// 工具输出长度限制
+	Output ToolOutputConfig `mapstructure:"output" yaml:"output"`
+	// 工具权限策略
+	Policy ToolPolicyConfig `mapstructure:"policy" yaml:"policy"`
+	// 命令行工具
+	Shell ShellConfig `mapstructure:"shell" yaml:"shell"`
+	// 危险操作的人工审批
+	Approval ApprovalConfig `mapstructure:"approval" yaml:"approval"`
+}
+
+type ToolValidationConfig struct {
+	// 单次运行中同一工具允许的参数错误次数，超过后中止运行，为 0 时使用默认值
+	MaxRetries int `mapstructure:"max_retries" yaml:"max_retries"`
+}
+
+type ToolOutputConfig struct {
+	// 单次工具输出的最大字符数，超过时截断，为 0 时使用默认值，小于 0 时不限制
+	MaxChars int `mapstructure:"max_chars" yaml:"max_chars"`
+	// 按工具名设置的上限，覆盖 max_chars，小于 0 时该工具不限制
+	Limits map[string]int `mapstructure:"limits" yaml:"limits"`
+	// 超过上限时用对话模型生成摘要代替截断
+	Summarize bool `mapstructure:"summarize" yaml:"summarize"`
+	// 生成摘要时输入模型的最大字符数
+	SummaryInputChars int `mapstructure:"summary_input_chars" yaml:"summary_input_chars"`
+	// 保存的完整输出个数和保留时间，超过后淘汰最早的
+	MaxArtifacts int           `mapstructure:"max_artifacts" yaml:"max_artifacts"`
+	ArtifactTTL  time.Duration `mapstructure:"artifact_ttl" yaml:"artifact_ttl"`
+}
+
+type ToolPolicyConfig struct {
+	// 按智能体设置的工具白名单和黑名单
+	Agents []AgentToolPolicy `mapstructure:"agents" yaml:"agents"`
+	// 网页工具允许访问的域名
+	Domains DomainPolicy `mapstructure:"domains" yaml:"domains"`
+	// 工具参数约束
+	Arguments []ArgumentRule `mapstructure:"arguments" yaml:"arguments"`
+	// 审计日志文件，记录被拒绝的调用，为空时只写入应用日志
+	AuditLog string `mapstructure:"audit_log" yaml:"audit_log"`
+}
+
+type AgentToolPolicy struct {
+	// 智能体名称，"*" 对所有智能体生效
+	Agent string `mapstructure:"agent" yaml:"agent"`
+	// 允许的工具，为空时不限制
+	Allow []string `mapstructure:"allow" yaml:"allow"`
+	// 禁止的工具，优先于 allow
+	Deny []string `mapstructure:"deny" yaml:"deny"`
+}
+
+type DomainPolicy struct {
+	// 允许的域名，包括其子域名，为空时不限制
+	Allow []string `mapstructure:"allow" yaml:"allow"`
+	// 禁止的域名，优先于 allow
+	Deny []string `mapstructure:"deny" yaml:"deny"`
+	// 需要检查域名的工具及其地址参数，为空时检查 http_fetch 和 jump_web_page 的 url
+	Tools map[string]string `mapstructure:"tools" yaml:"tools"`
+}
+
+type ArgumentRule struct {
+	Tool     string `mapstructure:"tool" yaml:"tool"`
+	Argument string `mapstructure:"argument" yaml:"argument"`
+	// 参数值必须匹配的正则
+	Pattern string `mapstructure:"pattern" yaml:"pattern"`
+	// 参数值必须是其中之一
+	Enum []string `mapstructure:"enum" yaml:"enum"`
+	// 参数值的最大字符数
+	MaxLength int `mapstructure:"max_length" yaml:"max_length"`
+}
+
+type CodeExecConfig struct {
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// 允许的语言: python / go，为空时启用本机可用的全部语言
+	Languages []string `mapstructure:"languages" yaml:"languages"`
+	// 解释器和编译器路径，为空时从 PATH 中查找
+	Python string `mapstructure:"python" yaml:"python"`
+	Go     string `mapstructure:"go" yaml:"go"`
+	// 临时工作区的父目录，为空时使用系统临时目录
+	WorkDir string `mapstructure:"work_dir" yaml:"work_dir"`
+	// 墙钟超时
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	// CPU 时间上限
+	CPUTime time.Duration `mapstructure:"cpu_time" yaml:"cpu_time"`
+	// 数据段内存上限，单位 MB
+	MemoryMB int `mapstructure:"memory_mb" yaml:"memory_mb"`
+	// stdout 和 stderr 各自保留的最大字节数
+	MaxOutputBytes int `mapstructure:"max_output_bytes" yaml:"max_output_bytes"`
+	// 返回给智能体的产出文件内容总字节数
+	MaxFileBytes int `mapstructure:"max_file_bytes" yaml:"max_file_bytes"`
+	// 工作区、/tmp 和 Go 编译缓存合计的最大写入量，单位 MB
+	MaxDiskMB int `mapstructure:"max_disk_mb" yaml:"max_disk_mb"`
+	// 同时存在的最大进程和线程数，防止 fork 炸弹
+	MaxProcesses int `mapstructure:"max_processes" yaml:"max_processes"`
+	// 子进程在宿主上使用的 uid 和 gid，使用服务之外的用户需要以 root 运行；
+	// 为 0 时，以 root 运行的服务使用 65534 (nobody)，否则使用服务自身的用户
+	UID int `mapstructure:"uid" yaml:"uid"`
+	GID int `mapstructure:"gid" yaml:"gid"`
+	// 允许访问网络，默认禁止
+	Network bool `mapstructure:"network" yaml:"network"`
+	// 不做文件系统、进程和网络隔离，只用于无法使用用户命名空间的本地开发环境
+	Insecure bool `mapstructure:"insecure" yaml:"insecure"`
+}
+
+type ShellConfig struct {
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// 命令的工作目录，路径参数不能指向该目录之外
+	WorkDir string `mapstructure:"work_dir" yaml:"work_dir"`
+	// 墙钟超时，命令可以单独设置
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	// stdout 和 stderr 各自保留的最大字节数
+	MaxOutputBytes int `mapstructure:"max_output_bytes" yaml:"max_output_bytes"`
+	// 允许访问网络，默认禁止，仅 Linux 支持禁止网络
+	Network bool `mapstructure:"network" yaml:"network"`
+	// 额外的环境变量，格式为 KEY=VALUE，不继承服务进程的环境变量
+	Env []string `mapstructure:"env" yaml:"env"`
+	// 允许执行的命令，不在列表中的命令一律拒绝
+	Commands []ShellCommandConfig `mapstructure:"commands" yaml:"commands"`
+}
+
+type ShellCommandConfig struct {
+	// 命令名，智能体调用时使用
+	Name string `mapstructure:"name" yaml:"name"`
+	// 可执行文件路径，为空时按命令名从 PATH 中查找
+	Path        string `mapstructure:"path" yaml:"path"`
+	Description string `mapstructure:"description" yaml:"description"`
+	// 允许的参数，每个参数需要完整匹配其中一项正则；"{path}" 匹配工作目录中的路径
+	Args []string `mapstructure:"args" yaml:"args"`
+	// 最多的参数个数，为 0 时使用默认值
+	MaxArgs int `mapstructure:"max_args" yaml:"max_args"`
+	// 墙钟超时，为 0 时使用 shell.timeout
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	// 执行前需要人工审批
+	RequireApproval bool `mapstructure:"require_approval" yaml:"require_approval"`
+}
+
+type ApprovalConfig struct {
+	// 等待审批的时间，超时视为拒绝，为 0 时使用默认值
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	// 审批接口的管理员令牌，请求需带 Authorization: Bearer <token>；为空时关闭审批接口，需要审批的操作直接拒绝
+	Token string `mapstructure:"token" yaml:"token"`
+}
+
+type WorkspaceConfig struct {
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// 工作区根目录，文件工具只能访问该目录中的文件
+	Root string `mapstructure:"root" yaml:"root"`
+	// 只读模式下不提供 write_file 工具
+	ReadOnly bool `mapstructure:"read_only" yaml:"read_only"`
+	// 单个文件读写的最大字节数，grep 跳过超过该大小的文件
+	MaxFileBytes int64 `mapstructure:"max_file_bytes" yaml:"max_file_bytes"`
+	// list_dir 最多返回的条目数
+	MaxListEntries int `mapstructure:"max_list_entries" yaml:"max_list_entries"`
+	// grep 最多返回的匹配行数
+	MaxGrepMatches int `mapstructure:"max_grep_matches" yaml:"max_grep_matches"`
+}
+
+type HTTPFetchConfig struct {
+	// 单次请求的超时，包括重定向和读取响应体
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	// 响应体最多读取的字节数
+	MaxBytes int64 `mapstructure:"max_bytes" yaml:"max_bytes"`
+	// 最多跟随的重定向次数
+	MaxRedirects int    `mapstructure:"max_redirects" yaml:"max_redirects"`
+	UserAgent    string `mapstructure:"user_agent" yaml:"user_agent"`
+	// 允许访问内网和回环地址，默认禁止
+	AllowPrivate bool `mapstructure:"allow_private" yaml:"allow_private"`
+	// 返回给智能体的正文最大字符数
+	MaxContentLength int `mapstructure:"max_content_length" yaml:"max_content_length"`
+	// 返回的链接数上限
+	MaxLinks int `mapstructure:"max_links" yaml:"max_links"`
+	// 提取的正文少于该字符数时认为页面依赖 JS 渲染，改用浏览器打开，0 表示不回退；
+	// 浏览器无法套用内网地址检查，只在 AllowPrivate 为 true 时回退
+	BrowserFallbackMinLength int `mapstructure:"browser_fallback_min_length" yaml:"browser_fallback_min_length"`
+}
+
+type SearchConfig struct {
+	// 搜索服务: google / bing / searxng / duckduckgo，按顺序尝试；为空时有 Google 密钥则使用 google，否则使用 duckduckgo；
+	// 缺少密钥或地址的服务会被跳过
+	Providers []string `mapstructure:"providers" yaml:"providers"`
+	// 组合方式: failover 使用第一个返回结果的服务 / merge 合并所有服务的结果
+	Mode string `mapstructure:"mode" yaml:"mode"`
+	// 默认返回的结果数
+	MaxResults int `mapstructure:"max_results" yaml:"max_results"`
+	// 单个服务的请求超时
+	Timeout    time.Duration        `mapstructure:"timeout" yaml:"timeout"`
+	Google     SearchProviderConfig `mapstructure:"google" yaml:"google"`
+	Bing       SearchProviderConfig `mapstructure:"bing" yaml:"bing"`
+	SearXNG    SearchProviderConfig `mapstructure:"searxng" yaml:"searxng"`
+	DuckDuckGo SearchProviderConfig `mapstructure:"duckduckgo" yaml:"duckduckgo"`
+}
+
+type SearchProviderConfig struct {
+	// 服务地址，为空时使用官方地址，searxng 必填
+	Endpoint string `mapstructure:"endpoint" yaml:"endpoint"`
+	// google 和 bing 的密钥，google 为空时使用 browser.api_key
+	API_KEY string `mapstructure:"api_key" yaml:"api_key"`
+	// google 可编程搜索引擎ID，为空时使用 browser.search_engine_id
+	SearchEngineID string `mapstructure:"search_engine_id" yaml:"search_engine_id"`
+}
+
+// MCP 传输方式
+const (
+	MCPTransportStdio = "stdio"
+	MCPTransportHTTP  = "http"
+)
+
+type MCPConfig struct {
+	// 外部 MCP 服务，其工具会挂载到智能体
+	Servers []MCPServerConfig `mapstructure:"servers" yaml:"servers"`
+	// 建立连接和初始化的超时
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout" yaml:"connect_timeout"`
+	// 心跳检测间隔，检测失败时断开并重连
+	HealthInterval time.Duration `mapstructure:"health_interval" yaml:"health_interval"`
+	// 重连的最大退避间隔
+	MaxBackoff time.Duration `mapstructure:"max_backoff" yaml:"max_backoff"`
+}
+
+type MCPServerConfig struct {
+	// 服务名称，同时作为工具名前缀，例如 fs 服务的 read 工具名为 fs_read
+	Name   string `mapstructure:"name" yaml:"name"`
+	Enable bool   `mapstructure:"enable" yaml:"enable"`
+	// 传输方式: stdio / http，http 为 streamable HTTP
+	Transport string `mapstructure:"transport" yaml:"transport"`
+	// stdio 启动的命令、参数和额外的环境变量，环境变量格式为 KEY=VALUE
+	Command string   `mapstructure:"command" yaml:"command"`
+	Args    []string `mapstructure:"args" yaml:"args"`
+	Env     []string `mapstructure:"env" yaml:"env"`
+	// http 服务地址和请求头
+	URL     string            `mapstructure:"url" yaml:"url"`
+	Headers map[string]string `mapstructure:"headers" yaml:"headers"`
+	// 只挂载列出的工具，为空时挂载全部工具
+	Tools []string `mapstructure:"tools" yaml:"tools"`
+	// 单次工具调用的超时
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
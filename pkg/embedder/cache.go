@@ -0,0 +1,245 @@
+package embedder
+
+import (
+	"MoonAgent/pkg/config"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"go.uber.org/zap"
+)
+
+// cacheEntry 缓存文件中的一行
+type cacheEntry struct {
+	Key    string    `json:"k"`
+	Vector []float64 `json:"v"`
+}
+
+// CacheStats 向量缓存的命中统计
+type CacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+// Cache 持久化的向量缓存，以模型名和文本哈希为键，追加写入本地文件
+// 多个向量模型共用同一个缓存
+type Cache struct {
+	path string
+
+	mu      sync.RWMutex
+	vectors map[string][]float64
+	file    *os.File
+	writer  *bufio.Writer
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// ProvideCache 提供向量缓存，未启用时返回 nil，所有方法对 nil 安全
+func ProvideCache(cfg *config.ServerConfig) (*Cache, func(), error) {
+	cc := cfg.DocumentConfig.Cache
+	if !cc.Enable {
+		return nil, func() {}, nil
+	}
+	cache, err := NewCache(cc.Path)
+	if err != nil {
+		zap.S().Error("Failed to create embedding cache: %v", zap.String("error", err.Error()))
+		return nil, nil, err
+	}
+	return cache, func() {
+		if err := cache.Close(); err != nil {
+			zap.S().Warn("Failed to close embedding cache", zap.String("error", err.Error()))
+		}
+	}, nil
+}
+
+// NewCache 打开缓存文件并加载已有的向量
+func NewCache(path string) (*Cache, error) {
+	if path == "" {
+		return nil, errors.New("embedding cache path is required")
+	}
+	c := &Cache{
+		path:    path,
+		vectors: make(map[string][]float64),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	c.file = file
+	c.writer = bufio.NewWriter(file)
+	zap.S().Info("Embedding cache loaded", zap.String("path", path), zap.Int("entries", len(c.vectors)))
+	return c, nil
+}
+
+// Wrap 为向量模型加上缓存，缓存未启用时原样返回
+func (c *Cache) Wrap(emb embedding.Embedder, model string) embedding.Embedder {
+	if c == nil {
+		return emb
+	}
+	return &CachedEmbedder{embedder: emb, model: model, cache: c}
+}
+
+// Stats 返回命中统计
+func (c *Cache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return CacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: len(c.vectors),
+	}
+}
+
+// Close 刷新并关闭缓存文件
+func (c *Cache) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.writer.Flush(); err != nil {
+		return err
+	}
+	return c.file.Close()
+}
+
+func (c *Cache) get(key string) ([]float64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	vector, ok := c.vectors[key]
+	return vector, ok
+}
+
+// put 写入内存并追加到缓存文件，每批写入后刷新，避免进程退出时丢失
+func (c *Cache) put(keys []string, vectors [][]float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, key := range keys {
+		c.vectors[key] = vectors[i]
+		line, err := json.Marshal(cacheEntry{Key: key, Vector: vectors[i]})
+		if err != nil {
+			return err
+		}
+		if _, err := c.writer.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return c.writer.Flush()
+}
+
+// load 读取缓存文件，损坏的行会被跳过
+func (c *Cache) load() error {
+	file, err := os.Open(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	skipped := 0
+	for scanner.Scan() {
+		var entry cacheEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Key == "" {
+			skipped++
+			continue
+		}
+		c.vectors[entry.Key] = entry.Vector
+	}
+	if skipped > 0 {
+		zap.S().Warn("Skipped broken embedding cache entries", zap.Int("count", skipped))
+	}
+	return scanner.Err()
+}
+
+// CachedEmbedder 带缓存的向量模型，只把未命中的文本批量发给底层模型
+type CachedEmbedder struct {
+	embedder embedding.Embedder
+	model    string
+	cache    *Cache
+}
+
+var _ embedding.Embedder = (*CachedEmbedder)(nil)
+
+func (e *CachedEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	model := e.model
+	if o := embedding.GetCommonOptions(&embedding.Options{Model: &model}, opts...); o.Model != nil {
+		model = *o.Model
+	}
+
+	vectors := make([][]float64, len(texts))
+	keys := make([]string, len(texts))
+	// 同一批次中重复的文本只请求一次
+	missIndex := make(map[string][]int)
+	var missKeys, missTexts []string
+	for i, text := range texts {
+		keys[i] = cacheKey(model, text)
+		if vector, ok := e.cache.get(keys[i]); ok {
+			vectors[i] = vector
+			continue
+		}
+		if _, ok := missIndex[keys[i]]; !ok {
+			missKeys = append(missKeys, keys[i])
+			missTexts = append(missTexts, text)
+		}
+		missIndex[keys[i]] = append(missIndex[keys[i]], i)
+	}
+	e.cache.hits.Add(int64(len(texts) - len(missKeys)))
+	e.cache.misses.Add(int64(len(missKeys)))
+	if len(missTexts) == 0 {
+		return vectors, nil
+	}
+
+	missVectors, err := e.embedder.EmbedStrings(ctx, missTexts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(missVectors) != len(missTexts) {
+		return nil, fmt.Errorf("embedding returned %d vectors for %d texts", len(missVectors), len(missTexts))
+	}
+	for i, key := range missKeys {
+		for _, idx := range missIndex[key] {
+			vectors[idx] = missVectors[i]
+		}
+	}
+	// 写缓存失败不影响本次结果
+	if err := e.cache.put(missKeys, missVectors); err != nil {
+		zap.S().Warn("Failed to write embedding cache", zap.String("error", err.Error()))
+	}
+	return vectors, nil
+}
+
+func (e *CachedEmbedder) GetType() string {
+	return "Cached"
+}
+
+func cacheKey(model, text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return model + ":" + hex.EncodeToString(sum[:])
+}
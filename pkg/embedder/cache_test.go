@@ -0,0 +1,150 @@
+package embedder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+// countingEmbedder 以文本长度生成向量，记录每次请求的文本
+type countingEmbedder struct {
+	calls [][]string
+}
+
+func (e *countingEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	e.calls = append(e.calls, append([]string(nil), texts...))
+	vectors := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		vectors = append(vectors, []float64{float64(len(text)), 1})
+	}
+	return vectors, nil
+}
+
+func newTestCache(t *testing.T, path string) *Cache {
+	t.Helper()
+	c, err := NewCache(path)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestCachedEmbedderOnlySendsMisses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "embeddings.jsonl")
+	cache := newTestCache(t, path)
+	inner := &countingEmbedder{}
+	emb := cache.Wrap(inner, "model-a")
+	ctx := context.Background()
+
+	vectors, err := emb.EmbedStrings(ctx, []string{"a", "bb", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 3 || vectors[0][0] != 1 || vectors[1][0] != 2 || vectors[2][0] != 1 {
+		t.Fatalf("vectors = %v", vectors)
+	}
+	// 同一批次中重复的文本只请求一次
+	if len(inner.calls) != 1 || strings.Join(inner.calls[0], ",") != "a,bb" {
+		t.Fatalf("calls = %v", inner.calls)
+	}
+
+	vectors, err = emb.EmbedStrings(ctx, []string{"bb", "ccc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vectors[0][0] != 2 || vectors[1][0] != 3 {
+		t.Errorf("vectors = %v", vectors)
+	}
+	if len(inner.calls) != 2 || strings.Join(inner.calls[1], ",") != "ccc" {
+		t.Errorf("second call = %v, want only the miss", inner.calls)
+	}
+
+	// 全部命中时不请求底层模型
+	if _, err := emb.EmbedStrings(ctx, []string{"a", "ccc"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.calls) != 2 {
+		t.Errorf("calls = %d, want 2", len(inner.calls))
+	}
+	stats := cache.Stats()
+	if stats.Hits != 4 || stats.Misses != 3 || stats.Entries != 3 {
+		t.Errorf("stats = %+v, want 4 hits, 3 misses, 3 entries", stats)
+	}
+}
+
+func TestCacheKeyIncludesModel(t *testing.T) {
+	if cacheKey("model-a", "text") == cacheKey("model-b", "text") {
+		t.Error("different models share a cache key")
+	}
+	if cacheKey("model-a", "text") != cacheKey("model-a", "text") {
+		t.Error("cache key is not deterministic")
+	}
+
+	cache := newTestCache(t, filepath.Join(t.TempDir(), "embeddings.jsonl"))
+	inner := &countingEmbedder{}
+	ctx := context.Background()
+	if _, err := cache.Wrap(inner, "model-a").EmbedStrings(ctx, []string{"text"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Wrap(inner, "model-b").EmbedStrings(ctx, []string{"text"}); err != nil {
+		t.Fatal(err)
+	}
+	// 请求选项中的模型优先于包装时的模型
+	if _, err := cache.Wrap(inner, "model-b").EmbedStrings(ctx, []string{"text"}, embedding.WithModel("model-a")); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.calls) != 2 {
+		t.Errorf("calls = %d, want 2", len(inner.calls))
+	}
+}
+
+func TestCacheReloadsAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.jsonl")
+	cache, err := NewCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Wrap(&countingEmbedder{}, "m").EmbedStrings(context.Background(), []string{"hello", "world!"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 损坏的行被跳过，不影响其他条目
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{broken\n")
+	f.Close()
+
+	reopened := newTestCache(t, path)
+	if got := reopened.Stats().Entries; got != 2 {
+		t.Fatalf("entries after reload = %d, want 2", got)
+	}
+	inner := &countingEmbedder{}
+	vectors, err := reopened.Wrap(inner, "m").EmbedStrings(context.Background(), []string{"world!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.calls) != 0 || vectors[0][0] != 6 {
+		t.Errorf("calls = %v, vectors = %v, want a cache hit", inner.calls, vectors)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var cache *Cache
+	inner := &countingEmbedder{}
+	if cache.Wrap(inner, "m") != embedding.Embedder(inner) {
+		t.Error("nil cache wrapped the embedder")
+	}
+	if cache.Stats() != (CacheStats{}) || cache.Close() != nil {
+		t.Error("nil cache is not a no-op")
+	}
+}
@@ -3,20 +3,49 @@ package embedder
 import (
 	"MoonAgent/pkg/config"
 	"context"
+	"fmt"
 
 	"github.com/cloudwego/eino-ext/components/embedding/ark"
+	"github.com/cloudwego/eino/components/embedding"
 	"go.uber.org/zap"
 )
 
-func ProvideEmbedder(cfg *config.ServerConfig) (*ark.Embedder, error) {
-	embedder, err := ark.NewEmbedder(context.Background(), &ark.EmbeddingConfig{
-		APIKey: cfg.DocumentConfig.API_KEY,
-		Model:  cfg.DocumentConfig.Model,
+const (
+	ProviderArk  = "ark"
+	ProviderHash = "hash"
+)
+
+func ProvideEmbedder(cfg *config.ServerConfig, cache *Cache) (embedding.Embedder, error) {
+	return NewFromConfig(context.Background(), &cfg.DocumentConfig, cfg.DocumentConfig.Model, cache)
+}
+
+// NewFromConfig 按配置的向量模型提供方创建指定模型的向量模型，远程模型会加上缓存
+func NewFromConfig(ctx context.Context, cfg *config.DocumentConfig, model string, cache *Cache) (embedding.Embedder, error) {
+	switch cfg.Embedder {
+	case "", ProviderArk:
+		emb, err := NewEmbedder(ctx, cfg.API_KEY, model)
+		if err != nil {
+			return nil, err
+		}
+		return cache.Wrap(emb, model), nil
+	case ProviderHash:
+		zap.S().Info("Hash embedder created", zap.Int("dimension", cfg.Dimension))
+		return NewHashEmbedder(cfg.Dimension), nil
+	default:
+		return nil, fmt.Errorf("unsupported embedder: %s", cfg.Embedder)
+	}
+}
+
+// NewEmbedder 创建指定模型的向量模型
+func NewEmbedder(ctx context.Context, apiKey string, model string) (*ark.Embedder, error) {
+	embedder, err := ark.NewEmbedder(ctx, &ark.EmbeddingConfig{
+		APIKey: apiKey,
+		Model:  model,
 	})
 	if err != nil {
 		zap.S().Error("Failed to create embedder: %v", zap.String("error", err.Error()))
 		return nil, err
 	}
-	zap.S().Info("Embedder created: %v", zap.String("model", cfg.DocumentConfig.Model))
+	zap.S().Info("Embedder created: %v", zap.String("model", model))
 	return embedder, nil
 }
@@ -0,0 +1,67 @@
+package embedder
+
+import (
+	"MoonAgent/pkg/reranker"
+	"context"
+	"hash/fnv"
+	"math"
+
+	"github.com/cloudwego/eino/components/embedding"
+)
+
+const defaultHashDimension = 512
+
+// HashEmbedder 基于词哈希的向量模型，不依赖外部服务，语义效果有限，仅用于本地开发和测试
+type HashEmbedder struct {
+	dimension int
+}
+
+var _ embedding.Embedder = (*HashEmbedder)(nil)
+
+// NewHashEmbedder 创建哈希向量模型，dimension 不大于0时使用默认维度
+func NewHashEmbedder(dimension int) *HashEmbedder {
+	if dimension <= 0 {
+		dimension = defaultHashDimension
+	}
+	return &HashEmbedder{dimension: dimension}
+}
+
+func (e *HashEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		vectors = append(vectors, e.embed(text))
+	}
+	return vectors, nil
+}
+
+// embed 把每个词哈希到一个维度上，符号位同样由哈希决定以减少冲突带来的偏差，结果做 L2 归一化
+func (e *HashEmbedder) embed(text string) []float64 {
+	vector := make([]float64, e.dimension)
+	for _, token := range reranker.Tokenize(text) {
+		h := fnv.New64a()
+		h.Write([]byte(token))
+		sum := h.Sum64()
+		sign := 1.0
+		if sum&(1<<63) != 0 {
+			sign = -1.0
+		}
+		vector[sum%uint64(e.dimension)] += sign
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+func (e *HashEmbedder) GetType() string {
+	return "Hash"
+}
@@ -12,7 +12,8 @@ import (
 func NewIndexer(ctx context.Context, milvusConfig *milvus.IndexerConfig) (*milvus.Indexer, error) {
 	indexer, err := milvus.NewIndexer(ctx, milvusConfig)
 	if err != nil {
-		zap.S().Panic("Failed to create indexer: %v", zap.String("error", err.Error()))
+		zap.S().Error("Failed to create indexer: %v", zap.String("error", err.Error()))
+		return nil, err
 	}
 	zap.S().Info("Indexer created successfully")
 	return indexer, nil
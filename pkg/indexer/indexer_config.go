@@ -1,48 +1,54 @@
 package indexer
 
 import (
-	"github.com/cloudwego/eino-ext/components/embedding/ark"
+	"context"
+	"encoding/json"
+	"fmt"
+
 	"github.com/cloudwego/eino-ext/components/indexer/milvus"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/schema"
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 )
 
-var collection = "muelsyse"
-
-var fields = []*entity.Field{
-	{
-		Name:     "id",
-		DataType: entity.FieldTypeVarChar,
-		TypeParams: map[string]string{
-			"max_length": "255",
-		},
-		PrimaryKey: true,
-	},
-	{
-		Name:     "vector", // 确保字段名匹配
-		DataType: entity.FieldTypeBinaryVector,
-		TypeParams: map[string]string{
-			"dim": "65536",
-		},
-	},
-	{
-		Name:     "content",
-		DataType: entity.FieldTypeVarChar,
-		TypeParams: map[string]string{
-			"max_length": "8192",
-		},
-	},
-	{
-		Name:     "metadata",
-		DataType: entity.FieldTypeJSON,
-	},
+// row 写入 milvus 的行数据，向量为浮点向量
+type row struct {
+	ID       string    `json:"id" milvus:"name:id"`
+	Content  string    `json:"content" milvus:"name:content"`
+	Vector   []float32 `json:"vector" milvus:"name:vector"`
+	Metadata []byte    `json:"metadata" milvus:"name:metadata"`
 }
 
-func NewIndexerConfig(cli *client.Client, emb *ark.Embedder) *milvus.IndexerConfig {
+// NewIndexerConfig 创建索引器配置，fields 需与已创建的集合一致
+func NewIndexerConfig(cli client.Client, emb embedding.Embedder, collection string, fields []*entity.Field) *milvus.IndexerConfig {
 	return &milvus.IndexerConfig{
-		Client:     *cli,
-		Embedding:  emb,
-		Collection: collection,
-		Fields:     fields,
+		Client:            cli,
+		Embedding:         emb,
+		Collection:        collection,
+		Fields:            fields,
+		DocumentConverter: documentConverter,
+	}
+}
+
+// documentConverter 把文档和向量转换为行数据
+func documentConverter(ctx context.Context, docs []*schema.Document, vectors [][]float64) ([]interface{}, error) {
+	rows := make([]interface{}, 0, len(docs))
+	for i, doc := range docs {
+		metadata, err := json.Marshal(doc.MetaData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		vector := make([]float32, len(vectors[i]))
+		for j, v := range vectors[i] {
+			vector[j] = float32(v)
+		}
+		rows = append(rows, &row{
+			ID:       doc.ID,
+			Content:  doc.Content,
+			Vector:   vector,
+			Metadata: metadata,
+		})
 	}
+	return rows, nil
 }
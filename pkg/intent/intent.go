@@ -0,0 +1,51 @@
+package intent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Route 用户消息的处理路线
+type Route string
+
+const (
+	// RouteChat 闲聊，直接由大模型回答，不检索也不调用工具
+	RouteChat Route = "chat"
+	// RouteKnowledge 知识库问答，先检索知识库再回答
+	RouteKnowledge Route = "knowledge"
+	// RouteAgent 需要联网或多步操作，交给带工具的智能体
+	RouteAgent Route = "agent"
+)
+
+// 分类器类型
+const (
+	TypeRules = "rules"
+	TypeLLM   = "llm"
+	TypeOff   = "off"
+)
+
+// Classifier 判断用户消息应走的路线
+type Classifier interface {
+	Classify(ctx context.Context, history []*schema.Message, question string) (Route, error)
+}
+
+// ParseRoute 解析路线名称
+func ParseRoute(name string) (Route, error) {
+	switch Route(name) {
+	case RouteChat, RouteKnowledge, RouteAgent:
+		return Route(name), nil
+	default:
+		return "", fmt.Errorf("unknown route: %s", name)
+	}
+}
+
+// FixedClassifier 总是返回同一路线，用于关闭意图识别
+type FixedClassifier struct {
+	Route Route
+}
+
+func (c *FixedClassifier) Classify(ctx context.Context, history []*schema.Message, question string) (Route, error) {
+	return c.Route, nil
+}
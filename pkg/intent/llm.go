@@ -0,0 +1,76 @@
+package intent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+const classifyPrompt = `你是一个对话意图分类器。请判断用户最新消息应该走哪条处理路线，只输出路线名称，不要输出其他内容：
+- chat: 问候、寒暄、感谢等闲聊，不需要查资料
+- knowledge: 需要查阅内部知识库才能回答的问题
+- agent: 需要联网搜索、访问网页或多步操作才能完成的任务`
+
+// LLMClassifier 使用大模型判断路线，失败时交给兜底分类器
+type LLMClassifier struct {
+	chatModel model.BaseChatModel
+	// 知识库说明，帮助模型判断问题是否属于知识库范围
+	knowledge string
+	fallback  Classifier
+}
+
+func NewLLMClassifier(chatModel model.BaseChatModel, knowledge string, fallback Classifier) *LLMClassifier {
+	return &LLMClassifier{chatModel: chatModel, knowledge: knowledge, fallback: fallback}
+}
+
+func (c *LLMClassifier) Classify(ctx context.Context, history []*schema.Message, question string) (Route, error) {
+	var content strings.Builder
+	if c.knowledge != "" {
+		content.WriteString("内部知识库:\n" + c.knowledge + "\n\n")
+	}
+	if len(history) > 0 {
+		content.WriteString("对话历史:\n")
+		for _, msg := range history {
+			switch msg.Role {
+			case schema.User:
+				content.WriteString("用户: ")
+			case schema.Assistant:
+				content.WriteString("助手: ")
+			default:
+				continue
+			}
+			content.WriteString(msg.Content + "\n")
+		}
+		content.WriteString("\n")
+	}
+	content.WriteString("最新消息: " + question)
+
+	resp, err := c.chatModel.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(classifyPrompt),
+		schema.UserMessage(content.String()),
+	})
+	if err != nil {
+		return c.fallbackRoute(ctx, history, question, err)
+	}
+	route, err := ParseRoute(strings.ToLower(strings.Trim(strings.TrimSpace(resp.Content), "`\"'。.")))
+	if err != nil {
+		return c.fallbackRoute(ctx, history, question, err)
+	}
+	return route, nil
+}
+
+func (c *LLMClassifier) fallbackRoute(ctx context.Context, history []*schema.Message, question string, cause error) (Route, error) {
+	if c.fallback == nil {
+		return "", cause
+	}
+	zap.S().Warn("LLM classify failed, using fallback classifier", zap.String("error", cause.Error()))
+	route, err := c.fallback.Classify(ctx, history, question)
+	if err != nil {
+		return "", fmt.Errorf("llm classify failed: %v, fallback failed: %w", cause, err)
+	}
+	return route, nil
+}
@@ -0,0 +1,93 @@
+package intent
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// DefaultChatKeywords 默认的闲聊关键词
+var DefaultChatKeywords = []string{"你好", "您好", "嗨", "在吗", "谢谢", "感谢", "再见", "拜拜", "早上好", "晚上好", "晚安", "hi", "hello", "thanks", "bye"}
+
+// DefaultAgentKeywords 默认需要联网或工具的关键词
+var DefaultAgentKeywords = []string{"搜索", "搜一下", "上网", "网上", "联网", "网页", "最新", "新闻", "天气", "今天", "打开", "http://", "https://"}
+
+// defaultChatMaxLength 闲聊消息的最大长度，超过时不按闲聊处理
+const defaultChatMaxLength = 12
+
+// RuleConfig 规则分类器配置
+type RuleConfig struct {
+	ChatKeywords  []string
+	AgentKeywords []string
+	// 只有不超过该长度的消息才会按闲聊处理
+	ChatMaxLength int
+	// 未命中任何规则时的路线
+	Default Route
+}
+
+// RuleClassifier 基于关键词的分类器，不调用大模型
+type RuleClassifier struct {
+	config *RuleConfig
+}
+
+func NewRuleClassifier(config *RuleConfig) *RuleClassifier {
+	if len(config.ChatKeywords) == 0 {
+		config.ChatKeywords = DefaultChatKeywords
+	}
+	if len(config.AgentKeywords) == 0 {
+		config.AgentKeywords = DefaultAgentKeywords
+	}
+	if config.ChatMaxLength <= 0 {
+		config.ChatMaxLength = defaultChatMaxLength
+	}
+	if config.Default == "" {
+		config.Default = RouteKnowledge
+	}
+	return &RuleClassifier{config: config}
+}
+
+func (c *RuleClassifier) Classify(ctx context.Context, history []*schema.Message, question string) (Route, error) {
+	text := strings.ToLower(strings.TrimSpace(question))
+	if text == "" {
+		return RouteChat, nil
+	}
+	if containsAny(text, c.config.AgentKeywords) {
+		return RouteAgent, nil
+	}
+	if utf8.RuneCountInString(text) <= c.config.ChatMaxLength && containsAny(text, c.config.ChatKeywords) {
+		return RouteChat, nil
+	}
+	return c.config.Default, nil
+}
+
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if keyword != "" && containsKeyword(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsKeyword 英文字母和数字开头或结尾的关键词按整词匹配，避免 "hi" 命中 "this"、"which"
+func containsKeyword(text, keyword string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], keyword)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(keyword)
+		if (!isWordByte(keyword[0]) || start == 0 || !isWordByte(text[start-1])) &&
+			(!isWordByte(keyword[len(keyword)-1]) || end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_'
+}
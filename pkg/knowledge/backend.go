@@ -0,0 +1,166 @@
+package knowledge
+
+import (
+	"MoonAgent/pkg/config"
+	moonindexer "MoonAgent/pkg/indexer"
+	"MoonAgent/pkg/localstore"
+	moonmilvus "MoonAgent/pkg/milvus"
+	moonretriever "MoonAgent/pkg/retriever"
+	"MoonAgent/pkg/splitter"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
+)
+
+const defaultLocalPath = "vector_store"
+
+// searcher 知识库的检索组件，合并多个知识库的结果时需要知道分数方向
+type searcher interface {
+	retriever.Retriever
+	HigherIsBetter() bool
+}
+
+// runtime 知识库运行时依赖的组件
+type runtime struct {
+	indexer   indexer.Indexer
+	retriever searcher
+	// drop 删除知识库的全部向量数据
+	drop func(ctx context.Context) error
+	// deleteDocuments 删除这些原始文档切分出的全部片段，重新写入同一文档前调用
+	deleteDocuments func(ctx context.Context, ids []string) error
+	// created 集合是创建运行时组件时新建的
+	created bool
+}
+
+// newRuntime 按配置的向量库后端创建知识库的运行时组件
+func (m *Manager) newRuntime(ctx context.Context, kb *KnowledgeBase, emb embedding.Embedder) (*runtime, error) {
+	switch m.cfg.DocumentConfig.Backend {
+	case "", config.BackendMilvus:
+		return m.newMilvusRuntime(ctx, kb, emb)
+	case config.BackendLocal:
+		return m.newLocalRuntime(ctx, kb, emb)
+	default:
+		return nil, fmt.Errorf("unsupported vector store backend: %s", m.cfg.DocumentConfig.Backend)
+	}
+}
+
+func (m *Manager) newMilvusRuntime(ctx context.Context, kb *KnowledgeBase, emb embedding.Embedder) (*runtime, error) {
+	if m.cli == nil {
+		return nil, errors.New("milvus client is not initialized")
+	}
+	dc := &m.cfg.DocumentConfig
+	spec, err := moonmilvus.NewIndexSpec(dc)
+	if err != nil {
+		return nil, err
+	}
+	// 向量维度以向量模型的实际输出为准
+	dim, err := moonmilvus.ProbeDimension(ctx, emb)
+	if err != nil {
+		return nil, fmt.Errorf("knowledge base %s: %w", kb.Name, err)
+	}
+	fields := moonmilvus.Fields(dim, dc.ContentMaxLength)
+	existed, err := m.cli.HasCollection(ctx, kb.Collection)
+	if err != nil {
+		return nil, fmt.Errorf("knowledge base %s: %w", kb.Name, err)
+	}
+	if err := moonmilvus.EnsureCollection(ctx, m.cli, kb.Collection, fields, spec); err != nil {
+		return nil, fmt.Errorf("knowledge base %s: %w", kb.Name, err)
+	}
+	searchParam, err := spec.SearchParam()
+	if err != nil {
+		return nil, fmt.Errorf("invalid search params: %w", err)
+	}
+
+	idx, err := moonindexer.NewIndexer(ctx, moonindexer.NewIndexerConfig(m.cli, emb, kb.Collection, fields))
+	if err != nil {
+		return nil, fmt.Errorf("failed to init indexer for knowledge base %s: %w", kb.Name, err)
+	}
+	rc := m.cfg.RetrieverConfig
+	ret, err := moonretriever.NewRetriever(ctx, &moonretriever.Config{
+		Client:         m.cli,
+		Embedding:      emb,
+		Collection:     kb.Collection,
+		TopK:           rc.TopK,
+		ScoreThreshold: rc.ScoreThreshold,
+		Partitions:     rc.Partitions,
+		Filter:         rc.Filter,
+		MetricType:     spec.Metric,
+		SearchParam:    searchParam,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to init retriever for knowledge base %s: %w", kb.Name, err)
+	}
+
+	collection := kb.Collection
+	return &runtime{
+		indexer:   idx,
+		retriever: ret,
+		drop: func(ctx context.Context) error {
+			return m.cli.DropCollection(ctx, collection)
+		},
+		deleteDocuments: func(ctx context.Context, ids []string) error {
+			// milvus 按主键写入不会覆盖已有数据，只能按片段记录的原始文档ID删除
+			expr, err := moonretriever.MetadataExpr(map[string]any{splitter.MetadataKeyDocumentID: toAnySlice(ids)})
+			if err != nil {
+				return err
+			}
+			return m.cli.Delete(ctx, collection, "", expr)
+		},
+		created: !existed,
+	}, nil
+}
+
+func (m *Manager) newLocalRuntime(ctx context.Context, kb *KnowledgeBase, emb embedding.Embedder) (*runtime, error) {
+	dir := m.cfg.DocumentConfig.LocalPath
+	if dir == "" {
+		dir = defaultLocalPath
+	}
+	rc := m.cfg.RetrieverConfig
+	// 本地向量库没有分区，也无法执行 milvus 表达式，配置了这些限制时直接报错，不能静默地检索全部数据
+	if len(rc.Partitions) > 0 {
+		return nil, errors.New("retriever.partitions is not supported by the local vector store")
+	}
+	if rc.Filter != "" {
+		return nil, errors.New("retriever.filter is not supported by the local vector store, use metadata filter in requests instead")
+	}
+	path := filepath.Join(dir, kb.Collection+".json")
+	_, statErr := os.Stat(path)
+	store, err := localstore.NewStore(ctx, &localstore.Config{
+		Path:           path,
+		Embedding:      emb,
+		Metric:         strings.ToLower(m.cfg.DocumentConfig.Metric),
+		TopK:           rc.TopK,
+		ScoreThreshold: rc.ScoreThreshold,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to init local store for knowledge base %s: %w", kb.Name, err)
+	}
+	return &runtime{
+		indexer:   store,
+		retriever: store,
+		drop: func(ctx context.Context) error {
+			return store.Drop()
+		},
+		deleteDocuments: func(ctx context.Context, ids []string) error {
+			_, err := store.DeleteByMetadata(splitter.MetadataKeyDocumentID, ids)
+			return err
+		},
+		created: errors.Is(statErr, fs.ErrNotExist),
+	}, nil
+}
+
+func toAnySlice(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
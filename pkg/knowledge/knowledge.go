@@ -0,0 +1,99 @@
+package knowledge
+
+import (
+	"MoonAgent/pkg/config"
+	"MoonAgent/pkg/queryexpand"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("knowledge base not found")
+	ErrAlreadyExists = errors.New("knowledge base already exists")
+	ErrInvalid       = errors.New("invalid knowledge base")
+)
+
+// milvus 集合名只允许字母、数字和下划线，知识库名沿用同样的约束
+var namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,254}$`)
+
+// KnowledgeBase 知识库，每个知识库对应独立的向量集合、向量模型和切分配置
+type KnowledgeBase struct {
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Collection     string `json:"collection"`
+	EmbeddingModel string `json:"embeddingModel"`
+	ChunkSize      int    `json:"chunkSize"`
+	ChunkOverlap   int    `json:"chunkOverlap"`
+	// QueryExpansion 检索该知识库前的查询扩展方式
+	QueryExpansion QueryExpansion `json:"queryExpansion"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+}
+
+// QueryExpansion 查询扩展配置
+type QueryExpansion struct {
+	Rewrite    bool `json:"rewrite"`
+	MultiQuery int  `json:"multiQuery"`
+	HyDE       bool `json:"hyde"`
+}
+
+// Config 转换为查询扩展器配置
+func (q QueryExpansion) Config() queryexpand.Config {
+	return queryexpand.Config{
+		Rewrite:    q.Rewrite,
+		MultiQuery: q.MultiQuery,
+		HyDE:       q.HyDE,
+	}
+}
+
+// UpdateRequest 可修改的知识库字段，集合和向量模型创建后不可修改
+type UpdateRequest struct {
+	Description    *string         `json:"description,omitempty"`
+	ChunkSize      *int            `json:"chunkSize,omitempty"`
+	ChunkOverlap   *int            `json:"chunkOverlap,omitempty"`
+	QueryExpansion *QueryExpansion `json:"queryExpansion,omitempty"`
+}
+
+func fromConfig(cfg config.KnowledgeBaseConfig) *KnowledgeBase {
+	return &KnowledgeBase{
+		Name:           cfg.Name,
+		Description:    cfg.Description,
+		Collection:     cfg.Collection,
+		EmbeddingModel: cfg.EmbeddingModel,
+		ChunkSize:      cfg.ChunkSize,
+		ChunkOverlap:   cfg.ChunkOverlap,
+		QueryExpansion: QueryExpansion{
+			Rewrite:    cfg.QueryExpansion.Rewrite,
+			MultiQuery: cfg.QueryExpansion.MultiQuery,
+			HyDE:       cfg.QueryExpansion.HyDE,
+		},
+	}
+}
+
+// normalize 校验并填充默认值
+func (kb *KnowledgeBase) normalize(defaultModel string) error {
+	if !namePattern.MatchString(kb.Name) {
+		return fmt.Errorf("%w: name %q must start with a letter and contain only letters, digits and underscores", ErrInvalid, kb.Name)
+	}
+	if kb.Collection == "" {
+		kb.Collection = kb.Name
+	}
+	if !namePattern.MatchString(kb.Collection) {
+		return fmt.Errorf("%w: collection name %q", ErrInvalid, kb.Collection)
+	}
+	if kb.EmbeddingModel == "" {
+		kb.EmbeddingModel = defaultModel
+	}
+	if kb.ChunkSize <= 0 {
+		kb.ChunkSize = 400
+	}
+	if kb.ChunkOverlap < 0 || kb.ChunkOverlap >= kb.ChunkSize {
+		return fmt.Errorf("%w: chunk overlap %d for chunk size %d", ErrInvalid, kb.ChunkOverlap, kb.ChunkSize)
+	}
+	if kb.QueryExpansion.MultiQuery < 0 || kb.QueryExpansion.MultiQuery > queryexpand.MaxMultiQuery {
+		return fmt.Errorf("%w: multi query must be between 0 and %d", ErrInvalid, queryexpand.MaxMultiQuery)
+	}
+	return nil
+}
@@ -0,0 +1,497 @@
+package knowledge
+
+import (
+	"MoonAgent/pkg/config"
+	"MoonAgent/pkg/embedder"
+	"MoonAgent/pkg/queryexpand"
+	moonretriever "MoonAgent/pkg/retriever"
+	"MoonAgent/pkg/splitter"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultKnowledgeBase = "muelsyse"
+	defaultStorePath     = "knowledge_bases.json"
+	storeBatchSize       = 10
+)
+
+// MetadataKeyKnowledgeBase 检索结果中记录来源知识库的元数据键
+const MetadataKeyKnowledgeBase = queryexpand.MetadataKeyKnowledgeBase
+
+// Manager 管理多个知识库的注册信息及其索引、检索组件
+// Manager 本身实现了 eino 的 retriever.Retriever，通过 WithKnowledgeBases 选择检索的知识库
+type Manager struct {
+	cfg         *config.ServerConfig
+	cli         client.Client
+	cache       *embedder.Cache
+	storePath   string
+	defaultName string
+
+	mu        sync.RWMutex
+	bases     map[string]*KnowledgeBase
+	runtimes  map[string]*runtime
+	embedders map[string]embedding.Embedder
+
+	// 串行化运行时组件的创建，避免重复建集合
+	initMu sync.Mutex
+	// 串行化注册表的保存，避免较早的快照最后写入
+	saveMu sync.Mutex
+}
+
+// ProvideManager 提供知识库管理器
+func ProvideManager(cfg *config.ServerConfig, cli *client.Client, cache *embedder.Cache) (*Manager, error) {
+	m := &Manager{
+		cfg:         cfg,
+		cache:       cache,
+		storePath:   cfg.KnowledgeConfig.StorePath,
+		defaultName: cfg.KnowledgeConfig.Default,
+		bases:       make(map[string]*KnowledgeBase),
+		runtimes:    make(map[string]*runtime),
+		embedders:   make(map[string]embedding.Embedder),
+	}
+	// 使用本地向量库时没有 milvus 客户端
+	if cli != nil {
+		m.cli = *cli
+	}
+	if m.storePath == "" {
+		m.storePath = defaultStorePath
+	}
+	if m.defaultName == "" {
+		m.defaultName = defaultKnowledgeBase
+	}
+
+	if err := m.load(); err != nil {
+		zap.S().Error("Failed to load knowledge bases: %v", zap.String("error", err.Error()))
+		return nil, err
+	}
+
+	// 预置配置中的知识库，已存在的以注册表为准
+	seeds := cfg.KnowledgeConfig.Bases
+	if _, ok := m.bases[m.defaultName]; !ok && !containsBase(seeds, m.defaultName) {
+		seeds = append(seeds, config.KnowledgeBaseConfig{Name: m.defaultName})
+	}
+	now := time.Now()
+	for _, seed := range seeds {
+		if _, ok := m.bases[seed.Name]; ok {
+			continue
+		}
+		kb := fromConfig(seed)
+		if err := kb.normalize(cfg.DocumentConfig.Model); err != nil {
+			return nil, err
+		}
+		kb.CreatedAt, kb.UpdatedAt = now, now
+		m.bases[kb.Name] = kb
+	}
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+
+	// 启动时创建或校验全部知识库的集合和索引，尽早暴露向量库不可用、集合不兼容等问题
+	for _, kb := range m.List() {
+		if _, err := m.runtime(context.Background(), kb.Name); err != nil {
+			zap.S().Error("Failed to init knowledge base", zap.String("name", kb.Name), zap.String("error", err.Error()))
+			return nil, err
+		}
+	}
+	zap.S().Info("Knowledge bases loaded", zap.Int("count", len(m.bases)))
+	return m, nil
+}
+
+// DefaultName 默认知识库名称
+func (m *Manager) DefaultName() string {
+	return m.defaultName
+}
+
+// List 列出全部知识库
+func (m *Manager) List() []*KnowledgeBase {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*KnowledgeBase, 0, len(m.bases))
+	for _, kb := range m.bases {
+		copied := *kb
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Get 获取知识库
+func (m *Manager) Get(name string) (*KnowledgeBase, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	kb, ok := m.bases[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	copied := *kb
+	return &copied, nil
+}
+
+// Create 创建知识库并初始化对应的向量集合
+func (m *Manager) Create(ctx context.Context, kb *KnowledgeBase) (*KnowledgeBase, error) {
+	if err := kb.normalize(m.cfg.DocumentConfig.Model); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	if _, ok := m.bases[kb.Name]; ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyExists, kb.Name)
+	}
+	for _, existing := range m.bases {
+		if existing.Collection == kb.Collection {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("%w: collection %s is already used by %s", ErrAlreadyExists, kb.Collection, existing.Name)
+		}
+	}
+	now := time.Now()
+	kb.CreatedAt, kb.UpdatedAt = now, now
+	m.bases[kb.Name] = kb
+	m.mu.Unlock()
+
+	rt, err := m.runtime(ctx, kb.Name)
+	if err != nil {
+		m.mu.Lock()
+		delete(m.bases, kb.Name)
+		m.mu.Unlock()
+		return nil, err
+	}
+	if err := m.save(); err != nil {
+		// 注册表写入失败时撤销创建，集合是这次新建的才删除，避免误删已有数据
+		m.mu.Lock()
+		delete(m.bases, kb.Name)
+		delete(m.runtimes, kb.Name)
+		m.mu.Unlock()
+		if !rt.created {
+			return nil, err
+		}
+		if dropErr := rt.drop(ctx); dropErr != nil {
+			zap.S().Error("Failed to drop collection after create failed",
+				zap.String("collection", kb.Collection), zap.String("error", dropErr.Error()))
+		}
+		return nil, err
+	}
+	zap.S().Info("Knowledge base created", zap.String("name", kb.Name), zap.String("collection", kb.Collection))
+	return m.Get(kb.Name)
+}
+
+// Update 修改知识库的描述和切分配置
+func (m *Manager) Update(name string, req *UpdateRequest) (*KnowledgeBase, error) {
+	m.mu.Lock()
+	kb, ok := m.bases[name]
+	if !ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	updated := *kb
+	if req.Description != nil {
+		updated.Description = *req.Description
+	}
+	if req.ChunkSize != nil {
+		updated.ChunkSize = *req.ChunkSize
+	}
+	if req.ChunkOverlap != nil {
+		updated.ChunkOverlap = *req.ChunkOverlap
+	}
+	if req.QueryExpansion != nil {
+		updated.QueryExpansion = *req.QueryExpansion
+	}
+	if err := updated.normalize(m.cfg.DocumentConfig.Model); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	updated.UpdatedAt = time.Now()
+	m.bases[name] = &updated
+	m.mu.Unlock()
+
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+	return m.Get(name)
+}
+
+// Delete 删除知识库，purge 为 true 时同时删除向量集合
+func (m *Manager) Delete(ctx context.Context, name string, purge bool) error {
+	if name == m.defaultName {
+		return fmt.Errorf("%w: cannot delete default knowledge base %s", ErrInvalid, name)
+	}
+
+	kb, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+	// 先删除向量数据，失败时保留知识库以便重试
+	if purge {
+		rt, err := m.runtime(ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := rt.drop(ctx); err != nil {
+			return fmt.Errorf("failed to drop collection %s: %w", kb.Collection, err)
+		}
+	}
+
+	m.mu.Lock()
+	delete(m.bases, name)
+	delete(m.runtimes, name)
+	m.mu.Unlock()
+
+	if err := m.save(); err != nil {
+		return err
+	}
+	zap.S().Info("Knowledge base deleted", zap.String("name", name), zap.Bool("purge", purge))
+	return nil
+}
+
+// Ingest 按知识库的切分配置切分文档并写入向量集合，返回写入的片段ID。
+// 写入前删除同一文档ID已有的片段，重新写入的文档切分出的片段变少时不会残留旧片段
+func (m *Manager) Ingest(ctx context.Context, name string, docs []*schema.Document) ([]string, error) {
+	kb, err := m.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := m.runtime(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := splitter.SplitDocs(ctx, docs, &splitter.Config{
+		ChunkSize:   kb.ChunkSize,
+		OverlapSize: kb.ChunkOverlap,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	docIDs := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		if doc.ID != "" {
+			docIDs = append(docIDs, doc.ID)
+		}
+	}
+	if err := rt.deleteDocuments(ctx, docIDs); err != nil {
+		return nil, fmt.Errorf("failed to delete existing chunks: %w", err)
+	}
+
+	ids := make([]string, 0, len(chunks))
+	for i := 0; i < len(chunks); i += storeBatchSize {
+		end := i + storeBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		batchIDs, err := rt.indexer.Store(ctx, chunks[i:end])
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, batchIDs...)
+	}
+	return ids, nil
+}
+
+// Retrieve 在选定的知识库中检索，多个知识库的结果按分数合并
+func (m *Manager) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	io := retriever.GetImplSpecificOptions(&ImplOptions{}, opts...)
+	names := io.KnowledgeBases
+	if len(names) == 0 {
+		names = []string{m.defaultName}
+	}
+
+	var (
+		results        []*schema.Document
+		higherIsBetter bool
+	)
+	for _, name := range names {
+		rt, err := m.runtime(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		higherIsBetter = rt.retriever.HigherIsBetter()
+
+		// 每个知识库的检索作为单独的回调节点，便于链路追踪区分
+		typ, _ := components.GetType(rt.retriever)
+		kbCtx := callbacks.ReuseHandlers(ctx, &callbacks.RunInfo{
+			Name:      name,
+			Type:      typ,
+			Component: components.ComponentOfRetriever,
+		})
+		docs, err := rt.retriever.Retrieve(kbCtx, query, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("retrieve from knowledge base %s failed: %w", name, err)
+		}
+		for _, doc := range docs {
+			if doc.MetaData == nil {
+				doc.MetaData = make(map[string]any)
+			}
+			doc.MetaData[MetadataKeyKnowledgeBase] = name
+		}
+		results = append(results, docs...)
+	}
+	if len(names) == 1 {
+		return results, nil
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if higherIsBetter {
+			return results[i].Score() > results[j].Score()
+		}
+		return results[i].Score() < results[j].Score()
+	})
+	topK := m.cfg.RetrieverConfig.TopK
+	if topK <= 0 {
+		topK = moonretriever.DefaultTopK
+	}
+	co := retriever.GetCommonOptions(&retriever.Options{TopK: &topK}, opts...)
+	if len(results) > *co.TopK {
+		results = results[:*co.TopK]
+	}
+	return results, nil
+}
+
+func (m *Manager) GetType() string {
+	return "KnowledgeBases"
+}
+
+// runtime 获取知识库的运行时组件，不存在时创建
+func (m *Manager) runtime(ctx context.Context, name string) (*runtime, error) {
+	m.mu.RLock()
+	rt, ok := m.runtimes[name]
+	kb, exists := m.bases[name]
+	m.mu.RUnlock()
+	if ok {
+		return rt, nil
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	m.initMu.Lock()
+	defer m.initMu.Unlock()
+	m.mu.RLock()
+	rt, ok = m.runtimes[name]
+	m.mu.RUnlock()
+	if ok {
+		return rt, nil
+	}
+
+	emb, err := m.embedder(ctx, kb.EmbeddingModel)
+	if err != nil {
+		return nil, err
+	}
+	rt, err = m.newRuntime(ctx, kb, emb)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.runtimes[name] = rt
+	m.mu.Unlock()
+	return rt, nil
+}
+
+// embedder 按模型名复用向量模型
+func (m *Manager) embedder(ctx context.Context, model string) (embedding.Embedder, error) {
+	m.mu.RLock()
+	emb, ok := m.embedders[model]
+	m.mu.RUnlock()
+	if ok {
+		return emb, nil
+	}
+
+	emb, err := embedder.NewFromConfig(ctx, &m.cfg.DocumentConfig, model, m.cache)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.embedders[model] = emb
+	m.mu.Unlock()
+	return emb, nil
+}
+
+// load 从注册表文件加载知识库
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.storePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var bases []*KnowledgeBase
+	if err := json.Unmarshal(data, &bases); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", m.storePath, err)
+	}
+	for _, kb := range bases {
+		m.bases[kb.Name] = kb
+	}
+	return nil
+}
+
+// save 把知识库写入注册表文件，先写同目录下的临时文件再替换。
+// 快照、写入和替换在同一把锁中进行，并发保存时最后替换的总是最新的快照
+func (m *Manager) save() error {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	data, err := json.MarshalIndent(m.List(), "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(m.storePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(m.storePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, m.storePath)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+func containsBase(bases []config.KnowledgeBaseConfig, name string) bool {
+	for _, kb := range bases {
+		if kb.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ProvideRetriever 以知识库管理器作为应用的检索器
+func ProvideRetriever(m *Manager) retriever.Retriever {
+	return m
+}
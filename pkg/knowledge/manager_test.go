@@ -0,0 +1,127 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"MoonAgent/pkg/config"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+)
+
+// constEmbedder 对任何文本返回相同的向量
+type constEmbedder struct{}
+
+func (constEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i := range texts {
+		vectors[i] = []float64{1, 0}
+	}
+	return vectors, nil
+}
+
+func TestConcurrentSaveKeepsLatest(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manager{
+		storePath: filepath.Join(dir, "knowledge_bases.json"),
+		bases:     make(map[string]*KnowledgeBase),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("kb%02d", i)
+			m.mu.Lock()
+			m.bases[name] = &KnowledgeBase{Name: name}
+			m.mu.Unlock()
+			if err := m.save(); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(m.storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []*KnowledgeBase
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 20 {
+		t.Errorf("saved %d knowledge bases, want 20", len(saved))
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left: %v", entries)
+	}
+}
+
+func TestLocalRuntimeRejectsRetrieverRestrictions(t *testing.T) {
+	for _, rc := range []config.RetrieverConfig{
+		{Filter: `metadata["tenant"] == "a"`},
+		{Partitions: []string{"tenant_a"}},
+	} {
+		cfg := &config.ServerConfig{
+			DocumentConfig:  config.DocumentConfig{Backend: config.BackendLocal, LocalPath: t.TempDir()},
+			RetrieverConfig: rc,
+		}
+		m := &Manager{cfg: cfg}
+		if _, err := m.newRuntime(context.Background(), &KnowledgeBase{Name: "kb", Collection: "kb"}, nil); err == nil {
+			t.Errorf("local runtime accepted %+v", rc)
+		}
+	}
+}
+
+func TestIngestReplacesExistingChunks(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.ServerConfig{
+		DocumentConfig: config.DocumentConfig{Backend: config.BackendLocal, LocalPath: t.TempDir()},
+	}
+	m := &Manager{
+		cfg:         cfg,
+		defaultName: "kb",
+		bases:       map[string]*KnowledgeBase{"kb": {Name: "kb", Collection: "kb", ChunkSize: 20}},
+		runtimes:    make(map[string]*runtime),
+		embedders:   map[string]embedding.Embedder{"": constEmbedder{}},
+	}
+
+	long := "第一段内容比较长。\n第二段内容也比较长。\n第三段内容同样很长。\n第四段内容还是很长。"
+	first, err := m.Ingest(ctx, "kb", []*schema.Document{
+		{ID: "doc", Content: long},
+		{ID: "other", Content: "另一个文档"},
+	})
+	if err != nil {
+		t.Fatalf("first ingest: %v", err)
+	}
+	if len(first) < 3 {
+		t.Fatalf("first ingest wrote %d chunks, want the long document split", len(first))
+	}
+
+	// 重新写入更短的同一文档，旧片段全部删除，其他文档不受影响
+	second, err := m.Ingest(ctx, "kb", []*schema.Document{{ID: "doc", Content: "短内容"}})
+	if err != nil {
+		t.Fatalf("second ingest: %v", err)
+	}
+	docs, err := m.Retrieve(ctx, "短内容", retriever.WithTopK(10))
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	got := make(map[string]string)
+	for _, doc := range docs {
+		got[doc.ID] = doc.Content
+	}
+	if len(second) != 1 || len(got) != 2 || got[second[0]] != "短内容" || got["other_0"] != "另一个文档" {
+		t.Errorf("after re-ingest got %v, want only the new chunk and the other document", got)
+	}
+}
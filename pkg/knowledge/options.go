@@ -0,0 +1,15 @@
+package knowledge
+
+import "github.com/cloudwego/eino/components/retriever"
+
+// ImplOptions 知识库检索的专有选项
+type ImplOptions struct {
+	// KnowledgeBases 检索的知识库名称，为空时使用默认知识库
+	KnowledgeBases []string
+}
+
+func WithKnowledgeBases(names ...string) retriever.Option {
+	return retriever.WrapImplSpecificOptFn(func(o *ImplOptions) {
+		o.KnowledgeBases = names
+	})
+}
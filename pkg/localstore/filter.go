@@ -0,0 +1,48 @@
+package localstore
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// matchMetadata 与 milvus 后端的 metadata 过滤语义一致：多个条件取交集，值为数组时表示 in
+func matchMetadata(metadata map[string]any, filter map[string]any) bool {
+	for key, want := range filter {
+		got, ok := metadata[key]
+		if !ok {
+			return false
+		}
+		if values, ok := want.([]any); ok {
+			if !containsValue(values, got) {
+				return false
+			}
+			continue
+		}
+		if !equalValue(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsValue(values []any, v any) bool {
+	for _, candidate := range values {
+		if equalValue(v, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValue 按 JSON 编码比较，避免内存中的 int 与文件加载后的 float64 不相等
+func equalValue(a, b any) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(x, y)
+}
@@ -0,0 +1,414 @@
+package localstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	moonretriever "MoonAgent/pkg/retriever"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/indexer"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+const (
+	MetricCosine = "cosine"
+	MetricIP     = "ip"
+)
+
+// Config 本地向量库配置
+type Config struct {
+	// Path 持久化文件路径，为空时只保存在内存中
+	Path      string
+	Embedding embedding.Embedder
+	// Metric 相似度度量: cosine / ip，默认 cosine
+	Metric string
+	// 以下为默认检索参数，可在请求中通过选项覆盖
+	TopK           int
+	ScoreThreshold float64
+}
+
+// record 持久化的单个片段
+type record struct {
+	ID       string         `json:"id"`
+	Content  string         `json:"content"`
+	MetaData map[string]any `json:"metadata,omitempty"`
+	Vector   []float64      `json:"vector"`
+}
+
+// Store 纯 Go 实现的向量库，同时实现 eino 的 indexer.Indexer 和 retriever.Retriever
+// 检索时对全部片段做暴力计算，适用于本地开发和 CI 等数据量较小的场景
+type Store struct {
+	path           string
+	embedder       embedding.Embedder
+	metric         string
+	topK           int
+	scoreThreshold float64
+
+	mu      sync.RWMutex
+	records map[string]*record
+}
+
+var (
+	_ indexer.Indexer     = (*Store)(nil)
+	_ retriever.Retriever = (*Store)(nil)
+)
+
+// NewStore 创建本地向量库，持久化文件存在时从文件加载
+func NewStore(ctx context.Context, cfg *Config) (*Store, error) {
+	if cfg.Embedding == nil {
+		return nil, errors.New("embedding is required")
+	}
+	s := &Store{
+		path:           cfg.Path,
+		embedder:       cfg.Embedding,
+		metric:         cfg.Metric,
+		topK:           cfg.TopK,
+		scoreThreshold: cfg.ScoreThreshold,
+		records:        make(map[string]*record),
+	}
+	if s.metric == "" {
+		s.metric = MetricCosine
+	}
+	if s.metric != MetricCosine && s.metric != MetricIP {
+		return nil, fmt.Errorf("unsupported metric: %s", s.metric)
+	}
+	if s.topK <= 0 {
+		s.topK = moonretriever.DefaultTopK
+	}
+	if err := s.load(); err != nil {
+		zap.S().Error("Failed to load local vector store: %v", zap.String("error", err.Error()))
+		return nil, err
+	}
+	zap.S().Info("Local vector store created", zap.String("path", s.path), zap.Int("count", len(s.records)))
+	return s, nil
+}
+
+// Store 向量化并写入文档，ID 相同的片段会被覆盖
+func (s *Store) Store(ctx context.Context, docs []*schema.Document, opts ...indexer.Option) ([]string, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	emb := s.embedder
+	if o := indexer.GetCommonOptions(&indexer.Options{}, opts...); o.Embedding != nil {
+		emb = o.Embedding
+	}
+
+	texts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		if doc.ID == "" {
+			return nil, errors.New("document id cannot be empty")
+		}
+		texts = append(texts, doc.Content)
+	}
+	vectors, err := emb.EmbedStrings(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed documents failed: %w", err)
+	}
+	if len(vectors) != len(docs) {
+		return nil, fmt.Errorf("embedding returned %d vectors for %d documents", len(vectors), len(docs))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 先校验全部向量的维度，不一致时一个片段也不写入
+	dim := s.dimension()
+	for _, vector := range vectors {
+		if dim > 0 && len(vector) != dim {
+			return nil, fmt.Errorf("vector dimension mismatch: got %d, want %d", len(vector), dim)
+		}
+		dim = len(vector)
+	}
+
+	// 记录被覆盖的片段，持久化失败时恢复
+	previous := make(map[string]*record, len(docs))
+	ids := make([]string, 0, len(docs))
+	for i, doc := range docs {
+		if _, ok := previous[doc.ID]; !ok {
+			previous[doc.ID] = s.records[doc.ID]
+		}
+		s.records[doc.ID] = &record{
+			ID:       doc.ID,
+			Content:  doc.Content,
+			MetaData: doc.MetaData,
+			Vector:   s.prepare(vectors[i]),
+		}
+		ids = append(ids, doc.ID)
+	}
+	if err := s.save(); err != nil {
+		for id, r := range previous {
+			if r == nil {
+				delete(s.records, id)
+			} else {
+				s.records[id] = r
+			}
+		}
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Retrieve 检索与查询最相似的片段，支持 TopK、分数阈值和 metadata 等值过滤
+func (s *Store) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	topK := s.topK
+	scoreThreshold := s.scoreThreshold
+	co := retriever.GetCommonOptions(&retriever.Options{
+		TopK:           &topK,
+		ScoreThreshold: &scoreThreshold,
+		Embedding:      s.embedder,
+	}, opts...)
+	io := retriever.GetImplSpecificOptions(&moonretriever.ImplOptions{}, opts...)
+
+	ctx = callbacks.OnStart(ctx, &retriever.CallbackInput{
+		Query:          query,
+		TopK:           *co.TopK,
+		ScoreThreshold: co.ScoreThreshold,
+	})
+	docs, err := s.search(ctx, query, co, io)
+	if err != nil {
+		callbacks.OnError(ctx, err)
+		return nil, err
+	}
+	callbacks.OnEnd(ctx, &retriever.CallbackOutput{Docs: docs})
+	return docs, nil
+}
+
+func (s *Store) search(ctx context.Context, query string, co *retriever.Options, io *moonretriever.ImplOptions) ([]*schema.Document, error) {
+	// 本地向量库没有分区，也无法执行 milvus 表达式，直接报错避免静默返回未过滤的结果
+	if len(io.Partitions) > 0 {
+		return nil, errors.New("partitions are not supported by the local vector store")
+	}
+	if io.Filter != "" {
+		return nil, errors.New("filter expressions are not supported by the local vector store, use metadata filter instead")
+	}
+
+	typ, _ := components.GetType(co.Embedding)
+	embCtx := callbacks.ReuseHandlers(ctx, &callbacks.RunInfo{
+		Type:      typ,
+		Component: components.ComponentOfEmbedding,
+	})
+	vectors, err := co.Embedding.EmbedStrings(embCtx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query failed: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedding returned %d vectors for query", len(vectors))
+	}
+	queryVector := s.prepare(vectors[0])
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]*schema.Document, 0, len(s.records))
+	for _, r := range s.records {
+		if len(r.Vector) != len(queryVector) {
+			return nil, fmt.Errorf("vector dimension mismatch: query %d, stored %d", len(queryVector), len(r.Vector))
+		}
+		if !matchMetadata(r.MetaData, io.MetadataFilter) {
+			continue
+		}
+		score := dot(queryVector, r.Vector)
+		if *co.ScoreThreshold != 0 && score < *co.ScoreThreshold {
+			continue
+		}
+		doc := &schema.Document{
+			ID:       r.ID,
+			Content:  r.Content,
+			MetaData: copyMetadata(r.MetaData),
+		}
+		results = append(results, doc.WithScore(score))
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score() != results[j].Score() {
+			return results[i].Score() > results[j].Score()
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > *co.TopK {
+		results = results[:*co.TopK]
+	}
+	return results, nil
+}
+
+// HigherIsBetter 分数是否越大越相关，cosine 与 ip 都是越大越相关
+func (s *Store) HigherIsBetter() bool {
+	return true
+}
+
+// DeleteByMetadata 删除 metadata 中 key 的值为 values 之一的片段，返回删除的数量；
+// 持久化失败时恢复被删除的片段
+func (s *Store) DeleteByMetadata(key string, values []string) (int, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+	targets := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		targets[v] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := make([]*record, 0)
+	for id, r := range s.records {
+		v, ok := r.MetaData[key].(string)
+		if !ok {
+			continue
+		}
+		if _, hit := targets[v]; hit {
+			deleted = append(deleted, r)
+			delete(s.records, id)
+		}
+	}
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+	if err := s.save(); err != nil {
+		for _, r := range deleted {
+			s.records[r.ID] = r
+		}
+		return 0, err
+	}
+	return len(deleted), nil
+}
+
+// Count 片段数量
+func (s *Store) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+// Drop 清空数据并删除持久化文件
+func (s *Store) Drop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = make(map[string]*record)
+	if s.path == "" {
+		return nil
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) GetType() string {
+	return "MoonAgentLocal"
+}
+
+// IsCallbacksEnabled 检索时自行触发 eino 回调，回调中带有 TopK 等参数
+func (s *Store) IsCallbacksEnabled() bool {
+	return true
+}
+
+// dimension 已存储向量的维度，没有数据时返回0，调用方需持有锁
+func (s *Store) dimension() int {
+	for _, r := range s.records {
+		return len(r.Vector)
+	}
+	return 0
+}
+
+// prepare cosine 度量下预先归一化，检索时直接点积
+func (s *Store) prepare(vector []float64) []float64 {
+	if s.metric != MetricCosine {
+		return vector
+	}
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	result := make([]float64, len(vector))
+	if norm == 0 {
+		return result
+	}
+	for i, v := range vector {
+		result[i] = v / norm
+	}
+	return result
+}
+
+// load 从持久化文件加载
+func (s *Store) load() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var records []*record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	for _, r := range records {
+		s.records[r.ID] = r
+	}
+	return nil
+}
+
+// save 写入持久化文件，先写临时文件再替换，调用方需持有锁
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	records := make([]*record, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func copyMetadata(metadata map[string]any) map[string]any {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]any, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,249 @@
+package localstore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	moonretriever "MoonAgent/pkg/retriever"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+)
+
+// fakeEmbedder 按文本查表返回向量
+type fakeEmbedder map[string][]float64
+
+func (e fakeEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		v, ok := e[text]
+		if !ok {
+			return nil, fmt.Errorf("no vector for %q", text)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+var testVectors = fakeEmbedder{
+	"query": {1, 0},
+	// 方向与查询相同但长度小
+	"same-direction": {0.5, 0},
+	// 方向有偏差但长度大，内积最大
+	"long-skewed": {3, 3},
+	"orthogonal":  {0, 1},
+	"3d":          {1, 0, 0},
+}
+
+func newTestStore(t *testing.T, metric, path string) *Store {
+	t.Helper()
+	s, err := NewStore(context.Background(), &Config{Path: path, Embedding: testVectors, Metric: metric})
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return s
+}
+
+func storeDocs(t *testing.T, s *Store, docs ...*schema.Document) {
+	t.Helper()
+	if _, err := s.Store(context.Background(), docs); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+}
+
+func ids(docs []*schema.Document) string {
+	result := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		result = append(result, doc.ID)
+	}
+	return strings.Join(result, ",")
+}
+
+func testDocs() []*schema.Document {
+	return []*schema.Document{
+		{ID: "a", Content: "same-direction", MetaData: map[string]any{"source": "wiki", "page": 1}},
+		{ID: "b", Content: "long-skewed", MetaData: map[string]any{"source": "blog", "page": 2}},
+		{ID: "c", Content: "orthogonal", MetaData: map[string]any{"source": "wiki", "page": 3}},
+	}
+}
+
+func TestRetrieveRanking(t *testing.T) {
+	tests := []struct {
+		metric string
+		want   string
+	}{
+		// cosine 只看方向
+		{MetricCosine, "a,b,c"},
+		// 内积受长度影响
+		{MetricIP, "b,a,c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			s := newTestStore(t, tt.metric, "")
+			storeDocs(t, s, testDocs()...)
+			docs, err := s.Retrieve(context.Background(), "query")
+			if err != nil {
+				t.Fatalf("Retrieve: %v", err)
+			}
+			if got := ids(docs); got != tt.want {
+				t.Errorf("order = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrieveTopKAndThreshold(t *testing.T) {
+	s := newTestStore(t, MetricCosine, "")
+	storeDocs(t, s, testDocs()...)
+
+	docs, err := s.Retrieve(context.Background(), "query", retriever.WithTopK(1))
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if got := ids(docs); got != "a" {
+		t.Errorf("topK=1 got %s, want a", got)
+	}
+	if score := docs[0].Score(); score < 0.999 || score > 1.001 {
+		t.Errorf("cosine score = %v, want 1", score)
+	}
+
+	docs, err = s.Retrieve(context.Background(), "query", retriever.WithScoreThreshold(0.5))
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if got := ids(docs); got != "a,b" {
+		t.Errorf("threshold 0.5 got %s, want a,b", got)
+	}
+}
+
+func TestRetrieveMetadataFilter(t *testing.T) {
+	s := newTestStore(t, MetricCosine, "")
+	storeDocs(t, s, testDocs()...)
+
+	tests := []struct {
+		name   string
+		filter map[string]any
+		want   string
+	}{
+		{"equal", map[string]any{"source": "wiki"}, "a,c"},
+		{"in", map[string]any{"page": []any{2, 3}}, "b,c"},
+		{"intersection", map[string]any{"source": "wiki", "page": 3}, "c"},
+		{"missing key", map[string]any{"author": "x"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := s.Retrieve(context.Background(), "query", moonretriever.WithMetadataFilter(tt.filter))
+			if err != nil {
+				t.Fatalf("Retrieve: %v", err)
+			}
+			if got := ids(docs); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrieveRejectsMilvusOptions(t *testing.T) {
+	s := newTestStore(t, MetricCosine, "")
+	storeDocs(t, s, testDocs()...)
+
+	if _, err := s.Retrieve(context.Background(), "query", moonretriever.WithPartitions("p1")); err == nil {
+		t.Error("expected error for partitions")
+	}
+	if _, err := s.Retrieve(context.Background(), "query", moonretriever.WithFilter(`source == "wiki"`)); err == nil {
+		t.Error("expected error for filter expression")
+	}
+}
+
+func TestPersistenceRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kb", "store.json")
+	s := newTestStore(t, MetricCosine, path)
+	storeDocs(t, s, testDocs()...)
+
+	reloaded := newTestStore(t, MetricCosine, path)
+	if reloaded.Count() != 3 {
+		t.Fatalf("reloaded count = %d, want 3", reloaded.Count())
+	}
+	// 加载后数字变为 float64，过滤语义不变
+	docs, err := reloaded.Retrieve(context.Background(), "query", moonretriever.WithMetadataFilter(map[string]any{"page": 1}))
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if got := ids(docs); got != "a" {
+		t.Errorf("got %s, want a", got)
+	}
+	if docs[0].Content != "same-direction" {
+		t.Errorf("content = %q", docs[0].Content)
+	}
+
+	if err := reloaded.Drop(); err != nil {
+		t.Fatalf("Drop: %v", err)
+	}
+	if n := newTestStore(t, MetricCosine, path).Count(); n != 0 {
+		t.Errorf("count after drop = %d, want 0", n)
+	}
+}
+
+func TestStoreDimensionMismatchWritesNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s := newTestStore(t, MetricCosine, path)
+	storeDocs(t, s, &schema.Document{ID: "a", Content: "same-direction"})
+
+	_, err := s.Store(context.Background(), []*schema.Document{
+		{ID: "b", Content: "orthogonal"},
+		{ID: "c", Content: "3d"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "dimension mismatch") {
+		t.Fatalf("err = %v, want dimension mismatch", err)
+	}
+	if s.Count() != 1 {
+		t.Errorf("count = %d, want 1", s.Count())
+	}
+	if n := newTestStore(t, MetricCosine, path).Count(); n != 1 {
+		t.Errorf("persisted count = %d, want 1", n)
+	}
+}
+
+func TestStoreOverwritesSameID(t *testing.T) {
+	s := newTestStore(t, MetricCosine, "")
+	storeDocs(t, s, &schema.Document{ID: "a", Content: "orthogonal"})
+	storeDocs(t, s, &schema.Document{ID: "a", Content: "same-direction"})
+
+	docs, err := s.Retrieve(context.Background(), "query")
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Content != "same-direction" {
+		t.Errorf("got %+v, want the overwritten document", docs)
+	}
+}
+
+func TestDeleteByMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	s := newTestStore(t, MetricCosine, path)
+	storeDocs(t, s,
+		&schema.Document{ID: "a_0", Content: "same-direction", MetaData: map[string]any{"document_id": "a"}},
+		&schema.Document{ID: "a_1", Content: "orthogonal", MetaData: map[string]any{"document_id": "a"}},
+		&schema.Document{ID: "b_0", Content: "long-skewed", MetaData: map[string]any{"document_id": "b"}},
+		&schema.Document{ID: "c", Content: "orthogonal"},
+	)
+
+	n, err := s.DeleteByMetadata("document_id", []string{"a", "missing"})
+	if err != nil {
+		t.Fatalf("DeleteByMetadata: %v", err)
+	}
+	if n != 2 || s.Count() != 2 {
+		t.Errorf("deleted %d, %d left, want 2 deleted and 2 left", n, s.Count())
+	}
+	// 删除结果已持久化
+	if n := newTestStore(t, MetricCosine, path).Count(); n != 2 {
+		t.Errorf("persisted count = %d, want 2", n)
+	}
+	if n, err := s.DeleteByMetadata("document_id", nil); n != 0 || err != nil {
+		t.Errorf("empty values: %d, %v", n, err)
+	}
+}
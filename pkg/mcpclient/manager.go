@@ -0,0 +1,192 @@
+package mcpclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"MoonAgent/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// Manager 管理所有外部 MCP 服务的连接，启动时连接失败的服务在后台继续重连
+type Manager struct {
+	servers []*Server
+	// cancel 结束所有连接，stop 停止心跳检测和重连
+	cancel context.CancelFunc
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+	// version 任一服务的工具列表变化时加一
+	version atomic.Uint64
+}
+
+// ProvideManager 根据配置连接 MCP 服务，没有启用的服务时返回nil
+func ProvideManager(cfg *config.ServerConfig) (*Manager, func(), error) {
+	m, err := NewManager(&cfg.MCPConfig)
+	if err != nil {
+		zap.S().Error("Failed to create mcp manager", zap.String("error", err.Error()))
+		return nil, nil, err
+	}
+	if m == nil {
+		return nil, func() {}, nil
+	}
+	return m, m.Close, nil
+}
+
+// NewManager 连接配置中启用的服务，单个服务连接失败不影响启动，没有启用的服务时返回nil
+func NewManager(cfg *config.MCPConfig) (*Manager, error) {
+	connectTimeout := cfg.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = DefaultConnectTimeout
+	}
+	interval := cfg.HealthInterval
+	if interval <= 0 {
+		interval = DefaultHealthInterval
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{cancel: cancel}
+	names := make(map[string]bool)
+	for _, sc := range cfg.Servers {
+		if !sc.Enable {
+			continue
+		}
+		if names[sc.Name] {
+			cancel()
+			return nil, fmt.Errorf("duplicate mcp server name: %s", sc.Name)
+		}
+		names[sc.Name] = true
+		s, err := newServer(ctx, sc, connectTimeout)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		s.onToolsChanged = func() { m.version.Add(1) }
+		m.servers = append(m.servers, s)
+	}
+	if len(m.servers) == 0 {
+		cancel()
+		return nil, nil
+	}
+
+	// 并发建立初始连接，智能体在启动时挂载工具，需要等待工具列表
+	var wg sync.WaitGroup
+	for _, s := range m.servers {
+		wg.Add(1)
+		go func(s *Server) {
+			defer wg.Done()
+			if err := s.connect(ctx); err != nil {
+				zap.L().Warn("Failed to connect mcp server, retrying in background", zap.String("server", s.Name()), zap.Error(err))
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	loopCtx, stop := context.WithCancel(ctx)
+	m.stop = stop
+	for _, s := range m.servers {
+		m.wg.Add(1)
+		go func(s *Server) {
+			defer m.wg.Done()
+			s.keepalive(loopCtx, interval, maxBackoff)
+		}(s)
+	}
+	return m, nil
+}
+
+// Servers 所有启用的服务
+func (m *Manager) Servers() []*Server {
+	if m == nil {
+		return nil
+	}
+	return m.servers
+}
+
+// Tools 所有服务最近一次连接时获取的工具，按名称排序
+func (m *Manager) Tools() []*Tool {
+	if m == nil {
+		return nil
+	}
+	var tools []*Tool
+	seen := make(map[string]bool)
+	for _, s := range m.servers {
+		for _, t := range s.Tools() {
+			mt := newTool(s, t)
+			if seen[mt.Name()] {
+				zap.L().Warn("Duplicate mcp tool name, skipped", zap.String("server", s.Name()), zap.String("tool", mt.Name()))
+				continue
+			}
+			seen[mt.Name()] = true
+			tools = append(tools, mt)
+		}
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name() < tools[j].Name()
+	})
+	return tools
+}
+
+// Version 工具列表的版本，服务首次连接或重连后工具列表变化时改变，用于判断已挂载的工具是否需要更新
+func (m *Manager) Version() uint64 {
+	if m == nil {
+		return 0
+	}
+	return m.version.Load()
+}
+
+// Owns 名称带有某个启用的服务的前缀，服务未连接时工具可能暂时不存在
+func (m *Manager) Owns(name string) bool {
+	for _, s := range m.Servers() {
+		if strings.HasPrefix(name, ToolName(s.Name(), "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tool 按带前缀的名称查找工具，不存在时返回nil
+func (m *Manager) Tool(name string) *Tool {
+	for _, t := range m.Tools() {
+		if t.Name() == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// ToolNames 所有工具的名称
+func (m *Manager) ToolNames() []string {
+	tools := m.Tools()
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		names = append(names, t.Name())
+	}
+	return names
+}
+
+// Close 停止重连并断开所有连接
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.stop()
+	m.wg.Wait()
+	defer m.cancel()
+	var wg sync.WaitGroup
+	for _, s := range m.servers {
+		wg.Add(1)
+		go func(s *Server) {
+			defer wg.Done()
+			s.close()
+		}(s)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,358 @@
+package mcpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"MoonAgent/pkg/config"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+)
+
+// 默认限制
+const (
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultCallTimeout    = 60 * time.Second
+	DefaultHealthInterval = 30 * time.Second
+	DefaultMaxBackoff     = time.Minute
+	// minBackoff 两次重连之间的最短间隔
+	minBackoff = time.Second
+	// closeTimeout 关闭连接时等待 stdio 子进程退出的时间，超时后直接结束进程
+	closeTimeout = 3 * time.Second
+	clientName   = "MoonAgent"
+	clientVer    = "1.0.0"
+)
+
+var (
+	ErrNotConnected   = errors.New("mcp server is not connected")
+	ErrConnectionLost = errors.New("mcp server connection lost during the call")
+)
+
+// conn 一次连接，ctx 在连接断开时取消，进行中的调用随之结束
+type conn struct {
+	client *client.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Server 与单个 MCP 服务的连接，连接断开后在下次调用或心跳检测时自动重连
+type Server struct {
+	cfg            config.MCPServerConfig
+	connectTimeout time.Duration
+	callTimeout    time.Duration
+	// ctx 连接的生命周期，stdio 子进程随其结束
+	ctx context.Context
+
+	mu sync.Mutex
+	// conn 当前连接，未连接时为nil
+	conn        *conn
+	lastAttempt time.Time
+	// tools 最近一次连接时获取的工具，断开后保留
+	tools []mcp.Tool
+	// onToolsChanged 连接后工具列表与之前不同时调用
+	onToolsChanged func()
+}
+
+func newServer(ctx context.Context, cfg config.MCPServerConfig, connectTimeout time.Duration) (*Server, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("mcp server name cannot be empty")
+	}
+	switch cfg.Transport {
+	case config.MCPTransportStdio, "":
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("mcp server %s: stdio transport requires command", cfg.Name)
+		}
+		cfg.Transport = config.MCPTransportStdio
+	case config.MCPTransportHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("mcp server %s: http transport requires url", cfg.Name)
+		}
+	default:
+		return nil, fmt.Errorf("mcp server %s: unknown transport: %s", cfg.Name, cfg.Transport)
+	}
+	s := &Server{
+		cfg:            cfg,
+		connectTimeout: connectTimeout,
+		callTimeout:    cfg.Timeout,
+		ctx:            ctx,
+	}
+	if s.callTimeout <= 0 {
+		s.callTimeout = DefaultCallTimeout
+	}
+	return s, nil
+}
+
+// Name 服务名称
+func (s *Server) Name() string {
+	return s.cfg.Name
+}
+
+// Connected 当前是否已连接
+func (s *Server) Connected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn != nil
+}
+
+// Tools 最近一次连接时获取的工具
+func (s *Server) Tools() []mcp.Tool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tools
+}
+
+// CallTool 调用工具，连接已断开时先重连；传输层出错时重连后重试一次，调用过程中连接被心跳检测判定断开时不重试，避免重复执行
+func (s *Server) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*mcp.CallToolResult, error) {
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = arguments
+
+	c, err := s.session(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	result, retry, err := s.call(ctx, c, req)
+	if !retry {
+		return result, err
+	}
+	zap.L().Warn("MCP call failed, reconnecting", zap.String("server", s.Name()), zap.String("tool", name), zap.Error(err))
+	s.reset(c)
+	if c, err = s.session(ctx, true); err != nil {
+		return nil, err
+	}
+	result, _, err = s.call(ctx, c, req)
+	return result, err
+}
+
+// call 在指定连接上调用工具，retry 表示传输层出错且请求可以安全重试
+func (s *Server) call(ctx context.Context, c *conn, req mcp.CallToolRequest) (result *mcp.CallToolResult, retry bool, err error) {
+	ctx, cancel := context.WithTimeoutCause(ctx, s.callTimeout, context.DeadlineExceeded)
+	defer cancel()
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	defer cancelCause(nil)
+	stop := context.AfterFunc(c.ctx, func() {
+		cancelCause(ErrConnectionLost)
+	})
+	defer stop()
+
+	result, err = c.client.CallTool(ctx, req)
+	if err == nil {
+		return result, false, nil
+	}
+	if errors.Is(context.Cause(ctx), ErrConnectionLost) {
+		return nil, false, fmt.Errorf("%w: %s", ErrConnectionLost, s.Name())
+	}
+	return nil, isTransportError(err) && ctx.Err() == nil, err
+}
+
+// session 返回当前连接，未连接时重新连接；force 为 false 时距上次尝试不足 minBackoff 则直接返回错误，避免服务不可用时每次调用都重连
+func (s *Server) session(ctx context.Context, force bool) (*conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return s.conn, nil
+	}
+	if !force && time.Since(s.lastAttempt) < minBackoff {
+		return nil, fmt.Errorf("%w: %s", ErrNotConnected, s.Name())
+	}
+	if err := s.connectLocked(ctx); err != nil {
+		return nil, err
+	}
+	return s.conn, nil
+}
+
+// connect 未连接时建立连接
+func (s *Server) connect(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return nil
+	}
+	return s.connectLocked(ctx)
+}
+
+func (s *Server) connectLocked(ctx context.Context) error {
+	s.lastAttempt = time.Now()
+	c, err := s.newClient()
+	if err != nil {
+		return err
+	}
+	connCtx, cancel := context.WithCancel(s.ctx)
+	if err := c.Start(connCtx); err != nil {
+		cancel()
+		return fmt.Errorf("mcp server %s: start failed: %w", s.Name(), err)
+	}
+	if stderr, ok := client.GetStderr(c); ok {
+		go s.drainStderr(bufio.NewScanner(stderr))
+	}
+
+	ctx, cancelInit := context.WithTimeout(ctx, s.connectTimeout)
+	defer cancelInit()
+	init := mcp.InitializeRequest{}
+	init.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	init.Params.ClientInfo = mcp.Implementation{Name: clientName, Version: clientVer}
+	info, err := c.Initialize(ctx, init)
+	if err != nil {
+		closeClient(c, cancel)
+		return fmt.Errorf("mcp server %s: initialize failed: %w", s.Name(), err)
+	}
+	list, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		closeClient(c, cancel)
+		return fmt.Errorf("mcp server %s: list tools failed: %w", s.Name(), err)
+	}
+
+	s.conn = &conn{client: c, ctx: connCtx, cancel: cancel}
+	if tools := filterTools(list.Tools, s.cfg.Tools); !reflect.DeepEqual(tools, s.tools) {
+		s.tools = tools
+		if s.onToolsChanged != nil {
+			s.onToolsChanged()
+		}
+	}
+	zap.L().Info("MCP server connected",
+		zap.String("server", s.Name()),
+		zap.String("remote", info.ServerInfo.Name),
+		zap.String("protocol", info.ProtocolVersion),
+		zap.Int("tools", len(s.tools)))
+	return nil
+}
+
+func (s *Server) newClient() (*client.Client, error) {
+	if s.cfg.Transport == config.MCPTransportHTTP {
+		return client.NewStreamableHttpClient(s.cfg.URL,
+			transport.WithHTTPHeaders(s.cfg.Headers),
+			transport.WithHTTPTimeout(s.callTimeout))
+	}
+	return client.NewClient(transport.NewStdio(s.cfg.Command, s.cfg.Env, s.cfg.Args...)), nil
+}
+
+// ping 心跳检测，失败时断开连接
+func (s *Server) ping(ctx context.Context) error {
+	s.mu.Lock()
+	c := s.conn
+	s.mu.Unlock()
+	if c == nil {
+		return ErrNotConnected
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.connectTimeout)
+	defer cancel()
+	if err := c.client.Ping(ctx); err != nil {
+		s.reset(c)
+		return err
+	}
+	return nil
+}
+
+// reset 断开已失效的连接并结束进行中的调用，连接已被替换时不做处理
+func (s *Server) reset(c *conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != c || c == nil {
+		return
+	}
+	s.conn = nil
+	c.cancel()
+	go func() {
+		_ = c.client.Close()
+	}()
+}
+
+// close 断开连接
+func (s *Server) close() {
+	s.mu.Lock()
+	c := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+	if c != nil {
+		closeClient(c.client, c.cancel)
+	}
+}
+
+// keepalive 定期心跳检测，断开后按指数退避重连，直到 ctx 结束
+func (s *Server) keepalive(ctx context.Context, interval, maxBackoff time.Duration) {
+	backoff := minBackoff
+	wait := interval
+	if !s.Connected() {
+		wait = backoff
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = interval
+		if s.Connected() {
+			if err := s.ping(ctx); err != nil && ctx.Err() == nil {
+				zap.L().Warn("MCP server health check failed", zap.String("server", s.Name()), zap.Error(err))
+				wait = 0
+			}
+			continue
+		}
+		if err := s.connect(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			zap.L().Warn("MCP server reconnect failed", zap.String("server", s.Name()),
+				zap.Duration("retry_in", backoff), zap.Error(err))
+			wait = backoff
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		backoff = minBackoff
+	}
+}
+
+func (s *Server) drainStderr(scanner *bufio.Scanner) {
+	for scanner.Scan() {
+		zap.L().Debug("MCP server stderr", zap.String("server", s.Name()), zap.String("line", scanner.Text()))
+	}
+}
+
+// closeClient 关闭连接，stdio 子进程没有及时退出时通过取消 ctx 结束进程
+func closeClient(c *client.Client, cancel context.CancelFunc) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = c.Close()
+	}()
+	select {
+	case <-done:
+	case <-time.After(closeTimeout):
+	}
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// isTransportError mcp-go 对传输层错误统一加上 "transport error" 前缀，服务端返回的 JSON-RPC 错误不会重连
+func isTransportError(err error) bool {
+	return strings.HasPrefix(err.Error(), "transport error")
+}
+
+func filterTools(tools []mcp.Tool, allow []string) []mcp.Tool {
+	if len(allow) == 0 {
+		return tools
+	}
+	allowed := make(map[string]bool, len(allow))
+	for _, name := range allow {
+		allowed[name] = true
+	}
+	out := make([]mcp.Tool, 0, len(allow))
+	for _, t := range tools {
+		if allowed[t.Name] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxToolNameLength 模型接口允许的工具名最大长度
+const maxToolNameLength = 64
+
+// Tool 把 MCP 服务的工具包装为 eino 工具，名称加上服务名前缀避免不同服务的工具重名
+type Tool struct {
+	server *Server
+	tool   mcp.Tool
+	name   string
+}
+
+var _ tool.InvokableTool = (*Tool)(nil)
+
+func newTool(server *Server, t mcp.Tool) *Tool {
+	return &Tool{server: server, tool: t, name: ToolName(server.Name(), t.Name)}
+}
+
+// ToolName 智能体看到的工具名，只保留字母、数字、下划线和连字符
+func ToolName(server, tool string) string {
+	name := []rune(sanitize(server) + "_" + sanitize(tool))
+	if len(name) > maxToolNameLength {
+		name = name[:maxToolNameLength]
+	}
+	return string(name)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// Name 带服务名前缀的工具名
+func (t *Tool) Name() string {
+	return t.name
+}
+
+// Server 工具所属的服务名称
+func (t *Tool) Server() string {
+	return t.server.Name()
+}
+
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	params, err := convertSchema(t.tool.InputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("mcp tool %s: %w", t.name, err)
+	}
+	desc := t.tool.Description
+	if desc == "" {
+		desc = t.tool.Name
+	}
+	return &schema.ToolInfo{
+		Name:        t.name,
+		Desc:        desc,
+		ParamsOneOf: schema.NewParamsOneOfByOpenAPIV3(params),
+	}, nil
+}
+
+// InvokableRun 调用 MCP 工具，工具返回的错误作为结果交给智能体处理，连接错误直接返回
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	args := json.RawMessage(argumentsInJSON)
+	if strings.TrimSpace(argumentsInJSON) == "" {
+		args = json.RawMessage("{}")
+	}
+	result, err := t.server.CallTool(ctx, t.tool.Name, args)
+	if err != nil {
+		return "", err
+	}
+	text := FormatResult(result)
+	if result.IsError {
+		return "工具执行失败: " + text, nil
+	}
+	return text, nil
+}
+
+// FormatResult 把工具结果转换为文本，图片、音频和二进制资源只保留类型说明
+func FormatResult(result *mcp.CallToolResult) string {
+	parts := make([]string, 0, len(result.Content))
+	for _, content := range result.Content {
+		switch c := content.(type) {
+		case mcp.TextContent:
+			parts = append(parts, c.Text)
+		case mcp.ImageContent:
+			parts = append(parts, fmt.Sprintf("[图片: %s]", c.MIMEType))
+		case mcp.AudioContent:
+			parts = append(parts, fmt.Sprintf("[音频: %s]", c.MIMEType))
+		case mcp.EmbeddedResource:
+			switch r := c.Resource.(type) {
+			case mcp.TextResourceContents:
+				parts = append(parts, r.Text)
+			case mcp.BlobResourceContents:
+				parts = append(parts, fmt.Sprintf("[资源: %s %s]", r.URI, r.MIMEType))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return "工具没有返回内容"
+	}
+	return strings.Join(parts, "\n")
+}
+
+// convertSchema 把 MCP 工具的 JSON Schema 转换为 OpenAPI 3 Schema，type 为数组时取第一个非 null 的类型
+func convertSchema(input mcp.ToolInputSchema) (*openapi3.Schema, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw["type"] == nil || raw["type"] == "" {
+		raw["type"] = openapi3.TypeObject
+	}
+	normalizeSchema(raw)
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	s := &openapi3.Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid input schema: %w", err)
+	}
+	return s, nil
+}
+
+func normalizeSchema(node map[string]any) {
+	if types, ok := node["type"].([]any); ok {
+		delete(node, "type")
+		for _, t := range types {
+			if t == "null" {
+				node["nullable"] = true
+			} else if _, set := node["type"]; !set {
+				node["type"] = t
+			}
+		}
+	}
+	// OpenAPI 3.0 不支持 const，改写为单值枚举
+	if c, ok := node["const"]; ok {
+		delete(node, "const")
+		node["enum"] = []any{c}
+	}
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		if child, ok := node[key].(map[string]any); ok {
+			normalizeSchema(child)
+		}
+	}
+	for _, key := range []string{"properties", "$defs", "definitions"} {
+		if children, ok := node[key].(map[string]any); ok {
+			for _, child := range children {
+				if m, ok := child.(map[string]any); ok {
+					normalizeSchema(m)
+				}
+			}
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf", "items"} {
+		if children, ok := node[key].([]any); ok {
+			for _, child := range children {
+				if m, ok := child.(map[string]any); ok {
+					normalizeSchema(m)
+				}
+			}
+		}
+	}
+}
@@ -9,7 +9,12 @@ import (
 )
 
 // ProvideMilvusClient 提供 Milvus 客户端
+// 使用本地向量库时不连接 Milvus，返回 nil
 func ProvideMilvusClient(cfg *config.ServerConfig) (*client.Client, error) {
+	if cfg.DocumentConfig.Backend == config.BackendLocal {
+		zap.S().Info("Using local vector store, skip connecting to Milvus")
+		return nil, nil
+	}
 	Client, err := client.NewClient(context.Background(), client.Config{
 		Address: cfg.DocumentConfig.Addr,
 	})
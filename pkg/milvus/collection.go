@@ -0,0 +1,280 @@
+package milvus
+
+import (
+	"MoonAgent/pkg/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+	"go.uber.org/zap"
+)
+
+// 集合字段名
+const (
+	FieldID       = "id"
+	FieldVector   = "vector"
+	FieldContent  = "content"
+	FieldMetadata = "metadata"
+)
+
+const (
+	// DefaultContentMaxLength milvus VarChar 字段允许的最大长度
+	DefaultContentMaxLength = 65535
+	idMaxLength             = 255
+
+	defaultHNSWM              = 16
+	defaultHNSWEfConstruction = 200
+	defaultHNSWEf             = 64
+	defaultIVFNlist           = 128
+	defaultIVFNprobe          = 16
+	defaultAutoIndexLevel     = 1
+
+	// 探测向量维度使用的文本
+	probeText = "dimension probe"
+)
+
+// IndexSpec 向量索引及检索参数
+type IndexSpec struct {
+	Type   entity.IndexType
+	Metric entity.MetricType
+
+	M              int
+	EfConstruction int
+	Ef             int
+	Nlist          int
+	Nprobe         int
+}
+
+// NewIndexSpec 根据配置生成索引参数并填充默认值，默认 HNSW + COSINE
+func NewIndexSpec(cfg *config.DocumentConfig) (*IndexSpec, error) {
+	ic := cfg.Index
+	spec := &IndexSpec{
+		Type:           entity.IndexType(strings.ToUpper(ic.Type)),
+		Metric:         entity.MetricType(strings.ToUpper(cfg.Metric)),
+		M:              ic.M,
+		EfConstruction: ic.EfConstruction,
+		Ef:             ic.Ef,
+		Nlist:          ic.Nlist,
+		Nprobe:         ic.Nprobe,
+	}
+	if spec.Type == "" {
+		spec.Type = entity.HNSW
+	}
+	if spec.Metric == "" {
+		spec.Metric = entity.COSINE
+	}
+	switch spec.Metric {
+	case entity.COSINE, entity.IP, entity.L2:
+	default:
+		return nil, fmt.Errorf("unsupported metric for float vectors: %s", cfg.Metric)
+	}
+
+	switch spec.Type {
+	case entity.HNSW, entity.IvfFlat, entity.AUTOINDEX:
+	default:
+		return nil, fmt.Errorf("unsupported index type: %s, expected HNSW, IVF_FLAT or AUTOINDEX", ic.Type)
+	}
+	spec.setDefaults()
+	return spec, nil
+}
+
+// setDefaults 为所有索引类型填充默认参数，沿用已有集合的其他类型索引时检索参数同样有效
+func (s *IndexSpec) setDefaults() {
+	if s.M <= 0 {
+		s.M = defaultHNSWM
+	}
+	if s.EfConstruction <= 0 {
+		s.EfConstruction = defaultHNSWEfConstruction
+	}
+	if s.Ef <= 0 {
+		s.Ef = defaultHNSWEf
+	}
+	if s.Nlist <= 0 {
+		s.Nlist = defaultIVFNlist
+	}
+	if s.Nprobe <= 0 {
+		s.Nprobe = defaultIVFNprobe
+	}
+}
+
+// adopt 沿用已有索引的类型和建索引参数。params 为 DescribeIndex 返回的参数，
+// 建索引参数可能直接在其中，也可能以 JSON 字符串保存在 params 键中
+func (s *IndexSpec) adopt(params map[string]string) {
+	s.Type = entity.IndexType(params["index_type"])
+	build := make(map[string]any)
+	if raw := params["params"]; raw != "" {
+		_ = json.Unmarshal([]byte(raw), &build)
+	}
+	lookup := func(key string) int {
+		v, ok := params[key]
+		if !ok {
+			v = fmt.Sprint(build[key])
+		}
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	if m := lookup("M"); m > 0 {
+		s.M = m
+	}
+	if ef := lookup("efConstruction"); ef > 0 {
+		s.EfConstruction = ef
+	}
+	if nlist := lookup("nlist"); nlist > 0 {
+		s.Nlist = nlist
+	}
+	s.setDefaults()
+	// nprobe 不能超过聚类数
+	if s.Nprobe > s.Nlist {
+		s.Nprobe = s.Nlist
+	}
+}
+
+// Index 建索引参数
+func (s *IndexSpec) Index() (entity.Index, error) {
+	switch s.Type {
+	case entity.HNSW:
+		return entity.NewIndexHNSW(s.Metric, s.M, s.EfConstruction)
+	case entity.IvfFlat:
+		return entity.NewIndexIvfFlat(s.Metric, s.Nlist)
+	default:
+		return entity.NewIndexAUTOINDEX(s.Metric)
+	}
+}
+
+// SearchParam 检索参数
+func (s *IndexSpec) SearchParam() (entity.SearchParam, error) {
+	switch s.Type {
+	case entity.HNSW:
+		return entity.NewIndexHNSWSearchParam(s.Ef)
+	case entity.IvfFlat:
+		return entity.NewIndexIvfFlatSearchParam(s.Nprobe)
+	default:
+		return entity.NewIndexAUTOINDEXSearchParam(defaultAutoIndexLevel)
+	}
+}
+
+// Fields 集合字段，向量字段为指定维度的浮点向量
+func Fields(dim int, contentMaxLength int) []*entity.Field {
+	if contentMaxLength <= 0 {
+		contentMaxLength = DefaultContentMaxLength
+	}
+	return []*entity.Field{
+		entity.NewField().WithName(FieldID).WithDataType(entity.FieldTypeVarChar).WithMaxLength(idMaxLength).WithIsPrimaryKey(true),
+		entity.NewField().WithName(FieldVector).WithDataType(entity.FieldTypeFloatVector).WithDim(int64(dim)),
+		entity.NewField().WithName(FieldContent).WithDataType(entity.FieldTypeVarChar).WithMaxLength(int64(contentMaxLength)),
+		entity.NewField().WithName(FieldMetadata).WithDataType(entity.FieldTypeJSON),
+	}
+}
+
+// ProbeDimension 向量化一段固定文本以获取向量模型的维度
+func ProbeDimension(ctx context.Context, emb embedding.Embedder) (int, error) {
+	vectors, err := emb.EmbedStrings(ctx, []string{probeText})
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe embedding dimension: %w", err)
+	}
+	if len(vectors) != 1 || len(vectors[0]) == 0 {
+		return 0, errors.New("failed to probe embedding dimension: empty vector")
+	}
+	return len(vectors[0]), nil
+}
+
+// EnsureCollection 集合不存在时按字段和索引参数创建，已存在时校验字段、维度和索引，缺少索引时补建，最后加载集合
+func EnsureCollection(ctx context.Context, cli client.Client, name string, fields []*entity.Field, spec *IndexSpec) error {
+	exists, err := cli.HasCollection(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check collection %s: %w", name, err)
+	}
+	if !exists {
+		schema := entity.NewSchema().WithName(name).WithDescription("MoonAgent knowledge base " + name)
+		for _, field := range fields {
+			schema.WithField(field)
+		}
+		if err := cli.CreateCollection(ctx, schema, entity.DefaultShardNumber); err != nil {
+			return fmt.Errorf("failed to create collection %s: %w", name, err)
+		}
+		zap.S().Info("Collection created", zap.String("collection", name))
+	} else {
+		collection, err := cli.DescribeCollection(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to describe collection %s: %w", name, err)
+		}
+		if err := checkFields(collection.Schema, fields); err != nil {
+			return fmt.Errorf("collection %s is incompatible with the current configuration: %w; "+
+				"drop it (DELETE /api/knowledge-bases/:name?purge=true) or use another collection name", name, err)
+		}
+	}
+
+	if err := ensureIndex(ctx, cli, name, spec); err != nil {
+		return err
+	}
+	if err := cli.LoadCollection(ctx, name, false); err != nil {
+		return fmt.Errorf("failed to load collection %s: %w", name, err)
+	}
+	return nil
+}
+
+// ensureIndex 向量字段没有索引时创建，已有索引时校验类型和度量
+func ensureIndex(ctx context.Context, cli client.Client, name string, spec *IndexSpec) error {
+	indexes, err := cli.DescribeIndex(ctx, name, FieldVector)
+	if errors.Is(err, client.ErrClientNotReady) {
+		return fmt.Errorf("milvus client not ready: %w", err)
+	}
+	// 没有索引时 milvus 返回错误，与空结果一样按未建索引处理
+	if err != nil || len(indexes) == 0 {
+		index, err := spec.Index()
+		if err != nil {
+			return fmt.Errorf("invalid index params: %w", err)
+		}
+		if err := cli.CreateIndex(ctx, name, FieldVector, index, false); err != nil {
+			return fmt.Errorf("failed to create %s index on collection %s: %w", spec.Type, name, err)
+		}
+		zap.S().Info("Index created", zap.String("collection", name), zap.String("type", string(spec.Type)), zap.String("metric", string(spec.Metric)))
+		return nil
+	}
+
+	params := indexes[0].Params()
+	indexType := entity.IndexType(params["index_type"])
+	metric := entity.MetricType(params["metric_type"])
+	if metric != spec.Metric {
+		return fmt.Errorf("collection %s has a %s index with metric %s, but metric %s is configured; "+
+			"drop the index or change document.metric", name, indexType, metric, spec.Metric)
+	}
+	if indexType != spec.Type {
+		// 度量一致时仍可检索，只提示索引类型不同，检索参数按已有索引的类型生成
+		zap.S().Warn("Existing index type differs from configuration",
+			zap.String("collection", name), zap.String("existing", string(indexType)), zap.String("configured", string(spec.Type)))
+		spec.adopt(params)
+	}
+	return nil
+}
+
+// checkFields 校验已有集合的字段类型与向量维度
+func checkFields(schema *entity.Schema, fields []*entity.Field) error {
+	existing := make(map[string]*entity.Field, len(schema.Fields))
+	for _, field := range schema.Fields {
+		existing[field.Name] = field
+	}
+	for _, want := range fields {
+		got, ok := existing[want.Name]
+		if !ok {
+			return fmt.Errorf("field %s is missing", want.Name)
+		}
+		if got.DataType != want.DataType {
+			return fmt.Errorf("field %s is %s, want %s", want.Name, got.DataType.Name(), want.DataType.Name())
+		}
+		if want.DataType == entity.FieldTypeFloatVector {
+			gotDim, _ := strconv.Atoi(got.TypeParams[entity.TypeParamDim])
+			wantDim, _ := strconv.Atoi(want.TypeParams[entity.TypeParamDim])
+			if gotDim != wantDim {
+				return fmt.Errorf("field %s has dimension %d, but the embedding model produces %d", want.Name, gotDim, wantDim)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package milvus
+
+import (
+	"context"
+	"testing"
+
+	"MoonAgent/pkg/config"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// fakeClient 只实现 DescribeIndex，返回固定的索引
+type fakeClient struct {
+	client.Client
+	params map[string]string
+}
+
+func (c *fakeClient) DescribeIndex(ctx context.Context, collName string, fieldName string, opts ...client.IndexOption) ([]entity.Index, error) {
+	return []entity.Index{entity.NewGenericIndex("vector", entity.IndexType(c.params["index_type"]), c.params)}, nil
+}
+
+func TestEnsureIndexAdoptsExistingType(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		params     map[string]string
+		check      func(t *testing.T, spec *IndexSpec)
+	}{
+		{
+			name:       "hnsw configured, ivf existing",
+			configured: "HNSW",
+			params:     map[string]string{"index_type": "IVF_FLAT", "metric_type": "COSINE", "nlist": "8"},
+			check: func(t *testing.T, spec *IndexSpec) {
+				if spec.Type != entity.IvfFlat || spec.Nlist != 8 || spec.Nprobe != 8 {
+					t.Errorf("spec = %+v, want IVF_FLAT with nlist 8 and nprobe 8", spec)
+				}
+			},
+		},
+		{
+			name:       "ivf configured, hnsw existing",
+			configured: "IVF_FLAT",
+			params:     map[string]string{"index_type": "HNSW", "metric_type": "COSINE", "params": `{"M":"32","efConstruction":"100"}`},
+			check: func(t *testing.T, spec *IndexSpec) {
+				if spec.Type != entity.HNSW || spec.M != 32 || spec.EfConstruction != 100 || spec.Ef != defaultHNSWEf {
+					t.Errorf("spec = %+v, want HNSW with M 32, efConstruction 100 and default ef", spec)
+				}
+			},
+		},
+		{
+			name:       "autoindex existing",
+			configured: "HNSW",
+			params:     map[string]string{"index_type": "AUTOINDEX", "metric_type": "COSINE"},
+			check: func(t *testing.T, spec *IndexSpec) {
+				if spec.Type != entity.AUTOINDEX {
+					t.Errorf("type = %s, want AUTOINDEX", spec.Type)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := NewIndexSpec(&config.DocumentConfig{Metric: "cosine", Index: config.IndexConfig{Type: tt.configured}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ensureIndex(context.Background(), &fakeClient{params: tt.params}, "kb", spec); err != nil {
+				t.Fatalf("ensureIndex: %v", err)
+			}
+			tt.check(t, spec)
+			// 检索参数必须有效，否则知识库无法加载
+			if _, err := spec.SearchParam(); err != nil {
+				t.Errorf("SearchParam: %v", err)
+			}
+		})
+	}
+}
+
+func TestEnsureIndexRejectsMetricMismatch(t *testing.T) {
+	spec, _ := NewIndexSpec(&config.DocumentConfig{Metric: "cosine"})
+	err := ensureIndex(context.Background(), &fakeClient{params: map[string]string{"index_type": "HNSW", "metric_type": "L2"}}, "kb", spec)
+	if err == nil {
+		t.Fatal("metric mismatch accepted")
+	}
+}
+
+func TestNewIndexSpecDefaults(t *testing.T) {
+	for _, typ := range []string{"", "hnsw", "IVF_FLAT", "autoindex"} {
+		spec, err := NewIndexSpec(&config.DocumentConfig{Index: config.IndexConfig{Type: typ}})
+		if err != nil {
+			t.Fatalf("NewIndexSpec(%q): %v", typ, err)
+		}
+		if spec.Ef <= 0 || spec.Nprobe <= 0 || spec.Nlist <= 0 || spec.M <= 0 {
+			t.Errorf("NewIndexSpec(%q) = %+v, want defaults for every index type", typ, spec)
+		}
+	}
+	if _, err := NewIndexSpec(&config.DocumentConfig{Index: config.IndexConfig{Type: "DISKANN"}}); err == nil {
+		t.Error("unsupported index type accepted")
+	}
+}
@@ -0,0 +1,56 @@
+package queryexpand
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const condensePrompt = `你是一个多轮对话的问题压缩器。请结合对话历史，把用户的最新问题改写为一个不依赖上下文即可理解的独立问题：补全指代和省略的内容，不要回答问题。
+如果最新问题本身已经完整，原样输出。只输出改写后的问题，不要输出其他内容。`
+
+// Condenser 结合对话历史把追问压缩为独立问题
+type Condenser struct {
+	chatModel model.BaseChatModel
+}
+
+func NewCondenser(chatModel model.BaseChatModel) *Condenser {
+	return &Condenser{chatModel: chatModel}
+}
+
+// Condense 返回独立问题，没有历史时直接返回原问题
+func (c *Condenser) Condense(ctx context.Context, history []*schema.Message, question string) (string, error) {
+	if len(history) == 0 {
+		return question, nil
+	}
+
+	var content strings.Builder
+	content.WriteString("对话历史:\n")
+	for _, msg := range history {
+		switch msg.Role {
+		case schema.User:
+			content.WriteString("用户: ")
+		case schema.Assistant:
+			content.WriteString("助手: ")
+		default:
+			continue
+		}
+		content.WriteString(msg.Content + "\n")
+	}
+	content.WriteString("\n最新问题: " + question)
+
+	resp, err := c.chatModel.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(condensePrompt),
+		schema.UserMessage(content.String()),
+	})
+	if err != nil {
+		return question, err
+	}
+	condensed := strings.TrimSpace(resp.Content)
+	if condensed == "" {
+		return question, nil
+	}
+	return condensed, nil
+}
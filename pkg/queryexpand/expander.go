@@ -0,0 +1,129 @@
+package queryexpand
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// MaxMultiQuery 单次最多生成的改写问题数
+const MaxMultiQuery = 5
+
+const (
+	rewritePrompt = `你是一个检索查询改写器。请把用户问题改写为一条适合在知识库中检索的独立查询：补全省略的主语和指代，去掉寒暄和语气词，保留专有名词。
+只输出改写后的查询，不要输出其他内容。`
+
+	multiQueryPrompt = `你是一个检索查询扩展器。请从不同角度为用户问题生成 %d 条语义相近但表述不同的检索查询，用于提高知识库检索的召回率。
+只输出一个 JSON 字符串数组，不要输出其他内容。`
+
+	hydePrompt = `请针对用户问题直接写一段可能出现在知识库文档中的回答，约100字，语气客观，不需要保证事实完全准确。
+只输出这段回答，不要输出其他内容。`
+)
+
+// Config 查询扩展配置
+type Config struct {
+	// Rewrite 把问题改写为适合检索的独立查询
+	Rewrite bool
+	// MultiQuery 额外生成的改写问题数，0 表示不生成
+	MultiQuery int
+	// HyDE 生成假设性回答并用其检索
+	HyDE bool
+}
+
+// Enabled 是否启用了任一扩展方式
+func (c Config) Enabled() bool {
+	return c.Rewrite || c.MultiQuery > 0 || c.HyDE
+}
+
+// Expander 使用大模型把一个问题扩展为多条检索查询
+type Expander struct {
+	chatModel model.BaseChatModel
+}
+
+func NewExpander(chatModel model.BaseChatModel) *Expander {
+	return &Expander{chatModel: chatModel}
+}
+
+// Expand 返回去重后的检索查询，第一条始终是原始问题
+func (e *Expander) Expand(ctx context.Context, query string, cfg Config) ([]string, error) {
+	queries := []string{query}
+	if cfg.Rewrite {
+		rewritten, err := e.generate(ctx, rewritePrompt, query)
+		if err != nil {
+			return queries, fmt.Errorf("rewrite query failed: %w", err)
+		}
+		queries = append(queries, rewritten)
+	}
+	if cfg.MultiQuery > 0 {
+		n := cfg.MultiQuery
+		if n > MaxMultiQuery {
+			n = MaxMultiQuery
+		}
+		content, err := e.generate(ctx, fmt.Sprintf(multiQueryPrompt, n), query)
+		if err != nil {
+			return queries, fmt.Errorf("generate multi queries failed: %w", err)
+		}
+		variants, err := parseQueries(content)
+		if err != nil {
+			return queries, err
+		}
+		if len(variants) > n {
+			variants = variants[:n]
+		}
+		queries = append(queries, variants...)
+	}
+	if cfg.HyDE {
+		answer, err := e.generate(ctx, hydePrompt, query)
+		if err != nil {
+			return queries, fmt.Errorf("generate hypothetical answer failed: %w", err)
+		}
+		queries = append(queries, answer)
+	}
+	return dedupe(queries), nil
+}
+
+func (e *Expander) generate(ctx context.Context, system string, query string) (string, error) {
+	resp, err := e.chatModel.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(system),
+		schema.UserMessage(query),
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// parseQueries 从模型输出中提取第一个JSON字符串数组
+func parseQueries(content string) ([]string, error) {
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("multi query output has no query array: %s", content)
+	}
+	var queries []string
+	if err := json.Unmarshal([]byte(content[start:end+1]), &queries); err != nil {
+		return nil, fmt.Errorf("failed to parse multi queries: %w", err)
+	}
+	return queries, nil
+}
+
+func dedupe(queries []string) []string {
+	seen := make(map[string]struct{}, len(queries))
+	result := make([]string, 0, len(queries))
+	for _, q := range queries {
+		q = strings.TrimSpace(q)
+		if q == "" {
+			continue
+		}
+		if _, ok := seen[q]; ok {
+			continue
+		}
+		seen[q] = struct{}{}
+		result = append(result, q)
+	}
+	return result
+}
@@ -0,0 +1,50 @@
+package queryexpand
+
+import (
+	"sort"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// rrfK 倒数排名融合的平滑常数
+const rrfK = 60
+
+// MetadataKeyKnowledgeBase 检索结果中记录来源知识库的元数据键
+const MetadataKeyKnowledgeBase = "knowledge_base"
+
+// docKey 片段的去重键，不同知识库的片段ID可能相同
+type docKey struct {
+	knowledgeBase string
+	id            string
+}
+
+// Fuse 使用倒数排名融合合并多路检索结果并按知识库和片段ID去重
+// 不同查询、不同知识库的分数不可直接比较，因此只使用排名；文档保留其首次出现时的分数
+func Fuse(lists [][]*schema.Document, topK int) []*schema.Document {
+	fused := make(map[docKey]float64)
+	docs := make(map[docKey]*schema.Document)
+	order := make([]docKey, 0)
+	for _, list := range lists {
+		for rank, doc := range list {
+			key := docKey{id: doc.ID}
+			key.knowledgeBase, _ = doc.MetaData[MetadataKeyKnowledgeBase].(string)
+			if _, ok := docs[key]; !ok {
+				docs[key] = doc
+				order = append(order, key)
+			}
+			fused[key] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return fused[order[i]] > fused[order[j]]
+	})
+	if topK > 0 && len(order) > topK {
+		order = order[:topK]
+	}
+	result := make([]*schema.Document, 0, len(order))
+	for _, key := range order {
+		result = append(result, docs[key])
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package queryexpand
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func doc(kb, id string) *schema.Document {
+	return &schema.Document{ID: id, MetaData: map[string]any{MetadataKeyKnowledgeBase: kb}}
+}
+
+func keys(docs []*schema.Document) string {
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		kb, _ := d.MetaData[MetadataKeyKnowledgeBase].(string)
+		out = append(out, kb+"/"+d.ID)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestFuseRanksByReciprocalRank(t *testing.T) {
+	first := doc("a", "1")
+	lists := [][]*schema.Document{
+		{first, doc("a", "2"), doc("a", "3")},
+		{doc("a", "3"), doc("a", "2")},
+		{doc("a", "2")},
+	}
+	got := Fuse(lists, 0)
+	// a/2: 1/62+1/62+1/61，a/3: 1/63+1/61，a/1: 1/61
+	if keys(got) != "a/2,a/3,a/1" {
+		t.Errorf("order = %s, want a/2,a/3,a/1", keys(got))
+	}
+	if got[2] != first {
+		t.Error("fused result does not keep the first occurrence of a document")
+	}
+	if got := Fuse(lists, 2); keys(got) != "a/2,a/3" {
+		t.Errorf("topK order = %s", keys(got))
+	}
+}
+
+func TestFuseKeepsSameIDFromDifferentKnowledgeBases(t *testing.T) {
+	lists := [][]*schema.Document{
+		{doc("products", "42"), doc("faq", "7")},
+		{doc("faq", "42"), doc("products", "42")},
+	}
+	got := Fuse(lists, 0)
+	// products/42 在两路中出现，合并为一条；faq/42 与它ID相同但来自不同知识库，单独保留
+	if keys(got) != "products/42,faq/42,faq/7" {
+		t.Errorf("fused = %s, want products/42,faq/42,faq/7", keys(got))
+	}
+}
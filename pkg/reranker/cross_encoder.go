@@ -0,0 +1,105 @@
+package reranker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// CrossEncoderConfig 交叉编码器重排服务配置
+// 服务需兼容 Jina/Cohere 风格的 /v1/rerank 接口
+type CrossEncoderConfig struct {
+	Endpoint string
+	APIKey   string
+	Model    string
+	Timeout  time.Duration
+}
+
+// CrossEncoderScorer 调用远程交叉编码器服务打分
+type CrossEncoderScorer struct {
+	config *CrossEncoderConfig
+	client *http.Client
+}
+
+func NewCrossEncoderScorer(config *CrossEncoderConfig) *CrossEncoderScorer {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &CrossEncoderScorer{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type crossEncoderRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+type crossEncoderResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+func (s *CrossEncoderScorer) Score(ctx context.Context, query string, docs []*schema.Document) ([]float64, error) {
+	if s.config.Endpoint == "" {
+		return nil, fmt.Errorf("cross encoder endpoint not provided")
+	}
+
+	texts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		texts = append(texts, doc.Content)
+	}
+	body, err := json.Marshal(&crossEncoderRequest{
+		Model:     s.config.Model,
+		Query:     query,
+		Documents: texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("cross encoder returned status %d: %s", resp.StatusCode, string(msg))
+	}
+
+	var result crossEncoderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode cross encoder response: %w", err)
+	}
+
+	scores := make([]float64, len(docs))
+	for _, item := range result.Results {
+		if item.Index < 0 || item.Index >= len(docs) {
+			return nil, fmt.Errorf("cross encoder returned invalid index %d", item.Index)
+		}
+		scores[item.Index] = item.RelevanceScore
+	}
+	return scores, nil
+}
@@ -0,0 +1,102 @@
+package reranker
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// LexicalScorer 基于BM25的离线词法打分，不依赖外部服务
+// 中文按单字和相邻双字切分，其他文字按单词切分
+type LexicalScorer struct {
+	k1 float64
+	b  float64
+}
+
+func NewLexicalScorer() *LexicalScorer {
+	return &LexicalScorer{k1: 1.2, b: 0.75}
+}
+
+func (s *LexicalScorer) Score(ctx context.Context, query string, docs []*schema.Document) ([]float64, error) {
+	queryTerms := Tokenize(query)
+	scores := make([]float64, len(docs))
+	if len(queryTerms) == 0 {
+		return scores, nil
+	}
+
+	// 以候选集合作为语料统计文档频率
+	docTerms := make([]map[string]int, len(docs))
+	docFreq := make(map[string]int)
+	totalLen := 0
+	for i, doc := range docs {
+		tf := make(map[string]int)
+		terms := Tokenize(doc.Content)
+		for _, term := range terms {
+			tf[term]++
+		}
+		for term := range tf {
+			docFreq[term]++
+		}
+		docTerms[i] = tf
+		totalLen += len(terms)
+	}
+	avgLen := float64(totalLen) / float64(len(docs))
+	if avgLen == 0 {
+		return scores, nil
+	}
+
+	n := float64(len(docs))
+	for i, tf := range docTerms {
+		docLen := 0
+		for _, c := range tf {
+			docLen += c
+		}
+		for _, term := range queryTerms {
+			freq := float64(tf[term])
+			if freq == 0 {
+				continue
+			}
+			df := float64(docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			scores[i] += idf * freq * (s.k1 + 1) / (freq + s.k1*(1-s.b+s.b*float64(docLen)/avgLen))
+		}
+	}
+	return scores, nil
+}
+
+// Tokenize 简单分词：英文数字按词切分并转小写，中日韩文字输出单字和双字
+func Tokenize(text string) []string {
+	terms := make([]string, 0)
+	var word strings.Builder
+	var prevHan rune
+
+	flushWord := func() {
+		if word.Len() > 0 {
+			terms = append(terms, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flushWord()
+			terms = append(terms, string(r))
+			if prevHan != 0 {
+				terms = append(terms, string([]rune{prevHan, r}))
+			}
+			prevHan = r
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+			prevHan = 0
+		default:
+			flushWord()
+			prevHan = 0
+		}
+	}
+	flushWord()
+	return terms
+}
@@ -0,0 +1,65 @@
+package reranker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const llmJudgePrompt = `你是一个检索结果相关性评估器。请针对用户问题，为下面每个编号的文档片段给出 0 到 10 的相关性分数，10 表示完全相关，0 表示完全无关。
+只输出一个 JSON 数字数组，数组长度必须与文档数量一致，按编号顺序排列，不要输出其他内容。`
+
+// LLMScorer 使用大模型作为评审为文档打分
+type LLMScorer struct {
+	chatModel model.BaseChatModel
+}
+
+func NewLLMScorer(chatModel model.BaseChatModel) *LLMScorer {
+	return &LLMScorer{chatModel: chatModel}
+}
+
+func (s *LLMScorer) Score(ctx context.Context, query string, docs []*schema.Document) ([]float64, error) {
+	var content strings.Builder
+	content.WriteString("用户问题: " + query + "\n\n")
+	for i, doc := range docs {
+		content.WriteString(fmt.Sprintf("[%d] %s\n\n", i+1, doc.Content))
+	}
+
+	resp, err := s.chatModel.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(llmJudgePrompt),
+		schema.UserMessage(content.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scores, err := parseScores(resp.Content)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(docs) {
+		return nil, fmt.Errorf("llm judge returned %d scores for %d documents", len(scores), len(docs))
+	}
+	for i := range scores {
+		scores[i] /= 10
+	}
+	return scores, nil
+}
+
+// parseScores 从模型输出中提取第一个JSON数组
+func parseScores(content string) ([]float64, error) {
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("llm judge output has no score array: %s", content)
+	}
+	var scores []float64
+	if err := json.Unmarshal([]byte(content[start:end+1]), &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse llm judge scores: %w", err)
+	}
+	return scores, nil
+}
@@ -0,0 +1,70 @@
+package reranker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	TypeCrossEncoder = "cross_encoder"
+	TypeLLM          = "llm"
+	TypeLexical      = "lexical"
+)
+
+// MetadataKeyScore 重排分数在文档元数据中的键
+const MetadataKeyScore = "rerank_score"
+
+// Scorer 为候选文档计算与查询的相关性分数，返回值与docs一一对应
+type Scorer interface {
+	Score(ctx context.Context, query string, docs []*schema.Document) ([]float64, error)
+}
+
+// Reranker 对检索结果按相关性重新排序并截断
+type Reranker struct {
+	scorer Scorer
+	topN   int
+}
+
+// NewReranker 创建重排器，topN<=0 时保留全部文档
+func NewReranker(scorer Scorer, topN int) *Reranker {
+	return &Reranker{
+		scorer: scorer,
+		topN:   topN,
+	}
+}
+
+// Rerank 打分、排序并保留前topN个文档，分数写入文档元数据
+func (r *Reranker) Rerank(ctx context.Context, query string, docs []*schema.Document) ([]*schema.Document, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+
+	scores, err := r.scorer.Score(ctx, query, docs)
+	if err != nil {
+		return nil, fmt.Errorf("rerank score failed: %w", err)
+	}
+	if len(scores) != len(docs) {
+		return nil, fmt.Errorf("rerank score length not match, need: %d, got: %d", len(docs), len(scores))
+	}
+
+	results := make([]*schema.Document, len(docs))
+	copy(results, docs)
+	for i, doc := range results {
+		if doc.MetaData == nil {
+			doc.MetaData = make(map[string]any)
+		}
+		doc.MetaData[MetadataKeyScore] = scores[i]
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].MetaData[MetadataKeyScore].(float64) > results[j].MetaData[MetadataKeyScore].(float64)
+	})
+
+	if r.topN > 0 && len(results) > r.topN {
+		results = results[:r.topN]
+	}
+	return results, nil
+}
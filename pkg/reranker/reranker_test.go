@@ -0,0 +1,152 @@
+package reranker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func docs(contents ...string) []*schema.Document {
+	out := make([]*schema.Document, 0, len(contents))
+	for i, c := range contents {
+		out = append(out, &schema.Document{ID: string(rune('a' + i)), Content: c})
+	}
+	return out
+}
+
+func ids(docs []*schema.Document) string {
+	out := make([]string, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, d.ID)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hello, World 42", "hello world 42"},
+		{"向量检索", "向 量 向量 检 量检 索 检索"},
+		{"Go语言", "go 语 言 语言"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Tokenize(tt.text), " "); got != tt.want {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLexicalScorer(t *testing.T) {
+	candidates := docs("天气预报和出行建议", "Milvus 向量检索的索引参数", "向量检索")
+	scores, err := NewLexicalScorer().Score(context.Background(), "向量检索", candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores[0] != 0 {
+		t.Errorf("unrelated document scored %v", scores[0])
+	}
+	// 词频相同时较短的文档得分更高
+	if !(scores[2] > scores[1] && scores[1] > 0) {
+		t.Errorf("scores = %v", scores)
+	}
+
+	scores, _ = NewLexicalScorer().Score(context.Background(), "!!", candidates)
+	for _, s := range scores {
+		if s != 0 {
+			t.Fatalf("empty query scores = %v", scores)
+		}
+	}
+}
+
+type fixedScorer struct {
+	scores []float64
+	err    error
+}
+
+func (s *fixedScorer) Score(ctx context.Context, query string, docs []*schema.Document) ([]float64, error) {
+	return s.scores, s.err
+}
+
+func TestRerank(t *testing.T) {
+	candidates := docs("a", "b", "c", "d")
+	out, err := NewReranker(&fixedScorer{scores: []float64{0.1, 0.9, 0.5, 0.9}}, 3).Rerank(context.Background(), "q", candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同分时保持原来的顺序
+	if got := ids(out); got != "b,d,c" {
+		t.Errorf("order = %s, want b,d,c", got)
+	}
+	if out[0].MetaData[MetadataKeyScore] != 0.9 {
+		t.Errorf("score metadata = %v", out[0].MetaData)
+	}
+
+	out, _ = NewReranker(&fixedScorer{scores: []float64{0.1, 0.9, 0.5, 0.2}}, 0).Rerank(context.Background(), "q", docs("a", "b", "c", "d"))
+	if got := ids(out); got != "b,c,d,a" {
+		t.Errorf("order without topN = %s", got)
+	}
+
+	if _, err := NewReranker(&fixedScorer{scores: []float64{1}}, 0).Rerank(context.Background(), "q", docs("a", "b")); err == nil {
+		t.Error("score length mismatch accepted")
+	}
+	if _, err := NewReranker(&fixedScorer{err: errors.New("down")}, 0).Rerank(context.Background(), "q", docs("a")); err == nil {
+		t.Error("scorer error ignored")
+	}
+}
+
+func TestParseScores(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		ok      bool
+	}{
+		{"[8, 2, 10]", "[8 2 10]", true},
+		{"分数如下：\n```json\n[7.5, 0]\n```", "[7.5 0]", true},
+		{"没有分数", "", false},
+		{"[1, \"高\"]", "", false},
+	}
+	for _, tt := range tests {
+		scores, err := parseScores(tt.content)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseScores(%q) err = %v", tt.content, err)
+			continue
+		}
+		if tt.ok && fmt.Sprint(scores) != tt.want {
+			t.Errorf("parseScores(%q) = %v, want %s", tt.content, scores, tt.want)
+		}
+	}
+}
+
+// fakeChatModel 返回固定的回复
+type fakeChatModel struct {
+	reply string
+}
+
+func (m *fakeChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	return schema.AssistantMessage(m.reply, nil), nil
+}
+
+func (m *fakeChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestLLMScorer(t *testing.T) {
+	scores, err := NewLLMScorer(&fakeChatModel{reply: "[10, 5]"}).Score(context.Background(), "q", docs("a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(scores) != "[1 0.5]" {
+		t.Errorf("scores = %v, want [1 0.5]", scores)
+	}
+	if _, err := NewLLMScorer(&fakeChatModel{reply: "[10]"}).Score(context.Background(), "q", docs("a", "b")); err == nil {
+		t.Error("score count mismatch accepted")
+	}
+}
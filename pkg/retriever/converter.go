@@ -0,0 +1,85 @@
+package retriever
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// vectorConverter 把查询向量转换为浮点向量
+func vectorConverter(ctx context.Context, vectors [][]float64) ([]entity.Vector, error) {
+	result := make([]entity.Vector, 0, len(vectors))
+	for _, vector := range vectors {
+		fv := make(entity.FloatVector, len(vector))
+		for i, v := range vector {
+			fv[i] = float32(v)
+		}
+		result = append(result, fv)
+	}
+	return result, nil
+}
+
+// documentConverter 在默认转换逻辑之外保留检索分数
+func documentConverter(ctx context.Context, result client.SearchResult) ([]*schema.Document, error) {
+	docs := make([]*schema.Document, result.IDs.Len())
+	for i := range docs {
+		id, err := result.IDs.GetAsString(i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get id: %w", err)
+		}
+		docs[i] = &schema.Document{
+			ID:       id,
+			MetaData: make(map[string]any),
+		}
+		if i < len(result.Scores) {
+			docs[i].WithScore(float64(result.Scores[i]))
+		}
+	}
+
+	for _, field := range result.Fields {
+		switch field.Name() {
+		case "id":
+		case "content":
+			for i, doc := range docs {
+				content, err := field.GetAsString(i)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get content: %w", err)
+				}
+				doc.Content = content
+			}
+		case "metadata":
+			for i, doc := range docs {
+				raw, err := field.Get(i)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get metadata: %w", err)
+				}
+				bytes, ok := raw.([]byte)
+				if !ok {
+					return nil, fmt.Errorf("unexpected metadata type %T", raw)
+				}
+				metadata := make(map[string]any)
+				if len(bytes) > 0 {
+					if err := json.Unmarshal(bytes, &metadata); err != nil {
+						return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+					}
+				}
+				for k, v := range metadata {
+					doc.MetaData[k] = v
+				}
+			}
+		default:
+			for i, doc := range docs {
+				value, err := field.GetAsString(i)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get field %s: %w", field.Name(), err)
+				}
+				doc.MetaData[field.Name()] = value
+			}
+		}
+	}
+	return docs, nil
+}
@@ -0,0 +1,139 @@
+package retriever
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// MetadataExpr 把 metadata 等值过滤条件转换为形如 metadata["product"] == "muelsyse" 的表达式，多个条件取交集
+func MetadataExpr(f map[string]any) (string, error) {
+	if len(f) == 0 {
+		return "", nil
+	}
+
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	exprs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !metadataKeyPattern.MatchString(key) {
+			return "", fmt.Errorf("invalid metadata filter key: %q", key)
+		}
+		field := fmt.Sprintf(`metadata["%s"]`, key)
+
+		if values, ok := f[key].([]any); ok {
+			literals := make([]string, 0, len(values))
+			for _, v := range values {
+				literal, err := toLiteral(v)
+				if err != nil {
+					return "", fmt.Errorf("metadata filter %q: %w", key, err)
+				}
+				literals = append(literals, literal)
+			}
+			exprs = append(exprs, fmt.Sprintf("%s in [%s]", field, strings.Join(literals, ", ")))
+			continue
+		}
+
+		literal, err := toLiteral(f[key])
+		if err != nil {
+			return "", fmt.Errorf("metadata filter %q: %w", key, err)
+		}
+		exprs = append(exprs, fmt.Sprintf("%s == %s", field, literal))
+	}
+	return strings.Join(exprs, " and "), nil
+}
+
+// JoinFilters 合并默认表达式、请求表达式和 metadata 过滤条件。
+// 表达式按字符串拼接，括号或引号不配对的表达式可以闭合外层括号、绕过默认表达式，直接拒绝
+func JoinFilters(base string, filter string, metadata map[string]any) (string, error) {
+	if err := checkBalanced(base); err != nil {
+		return "", fmt.Errorf("invalid default filter: %w", err)
+	}
+	if err := checkBalanced(filter); err != nil {
+		return "", fmt.Errorf("invalid filter: %w", err)
+	}
+	metadataExpr, err := MetadataExpr(metadata)
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, 0, 3)
+	for _, expr := range []string{base, filter, metadataExpr} {
+		if strings.TrimSpace(expr) != "" {
+			parts = append(parts, "("+expr+")")
+		}
+	}
+	return strings.Join(parts, " and "), nil
+}
+
+// checkBalanced 检查表达式中字符串字面量外的圆括号和方括号是否配对，字符串字面量是否闭合
+func checkBalanced(expr string) error {
+	var stack []rune
+	var quote rune
+	escaped := false
+	for _, r := range expr {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '"', '\'':
+			quote = r
+		case '(', '[':
+			stack = append(stack, r)
+		case ')', ']':
+			open := '('
+			if r == ']' {
+				open = '['
+			}
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				return fmt.Errorf("unbalanced %q", r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if quote != 0 {
+		return errors.New("unterminated string literal")
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed %q", stack[len(stack)-1])
+	}
+	return nil
+}
+
+// toLiteral 把 JSON 值转换为 milvus 表达式字面量
+func toLiteral(v any) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return strconv.Quote(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(val), nil
+	case int64:
+		return strconv.FormatInt(val, 10), nil
+	case json.Number:
+		return val.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
@@ -0,0 +1,66 @@
+package retriever
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMetadataExpr(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]any
+		want     string
+		ok       bool
+	}{
+		{"empty", nil, "", true},
+		{"sorted keys", map[string]any{"version": 2.0, "product": "muelsyse"}, `metadata["product"] == "muelsyse" and metadata["version"] == 2`, true},
+		{"quote escaped", map[string]any{"title": `a" or "1" == "1`}, `metadata["title"] == "a\" or \"1\" == \"1"`, true},
+		{"list", map[string]any{"tag": []any{"a", true, json.Number("3")}}, `metadata["tag"] in ["a", true, 3]`, true},
+		{"invalid key", map[string]any{`x"] == 1 or metadata["y`: "v"}, "", false},
+		{"unsupported value", map[string]any{"k": map[string]any{}}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MetadataExpr(tt.metadata)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expr = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		filter   string
+		metadata map[string]any
+		want     string
+		ok       bool
+	}{
+		{"all parts", `tenant == "a"`, "x == 1", map[string]any{"k": "v"}, `(tenant == "a") and (x == 1) and (metadata["k"] == "v")`, true},
+		{"only filter", "", "x in [1, 2]", nil, "(x in [1, 2])", true},
+		{"parentheses in string", `tenant == "a"`, `title == "a) or (true"`, nil, `(tenant == "a") and (title == "a) or (true")`, true},
+		{"escaped quote", "", `title == "say \"hi\")"`, nil, `(title == "say \"hi\")")`, true},
+		{"close base", `tenant == "a"`, "x == 1) or (true", nil, "", false},
+		{"unclosed", `tenant == "a"`, "(x == 1", nil, "", false},
+		{"mismatched", "", "x in [1, 2)", nil, "", false},
+		{"unterminated string", `tenant == "a"`, `x == "1) or (true`, nil, "", false},
+		{"single quote", "", `x == 'a) or (true`, nil, "", false},
+		{"invalid base", "x == 1)", "", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JoinFilters(tt.base, tt.filter, tt.metadata)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expr = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
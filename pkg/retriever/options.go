@@ -0,0 +1,31 @@
+package retriever
+
+import "github.com/cloudwego/eino/components/retriever"
+
+// ImplOptions 检索器的专有选项
+type ImplOptions struct {
+	// Partitions 检索的分区，为空时检索全部分区
+	Partitions []string
+	// Filter milvus 布尔过滤表达式
+	Filter string
+	// MetadataFilter 对 metadata 字段的等值过滤，值为数组时表示 in
+	MetadataFilter map[string]any
+}
+
+func WithPartitions(partitions ...string) retriever.Option {
+	return retriever.WrapImplSpecificOptFn(func(o *ImplOptions) {
+		o.Partitions = partitions
+	})
+}
+
+func WithFilter(filter string) retriever.Option {
+	return retriever.WrapImplSpecificOptFn(func(o *ImplOptions) {
+		o.Filter = filter
+	})
+}
+
+func WithMetadataFilter(filter map[string]any) retriever.Option {
+	return retriever.WrapImplSpecificOptFn(func(o *ImplOptions) {
+		o.MetadataFilter = filter
+	})
+}
@@ -2,31 +2,185 @@ package retriever
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
-	"github.com/cloudwego/eino-ext/components/embedding/ark"
 	"github.com/cloudwego/eino-ext/components/retriever/milvus"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 	"go.uber.org/zap"
 )
 
-// ProvideRetriever 提供检索器
-func ProvideRetriever(milvusCli *client.Client, embedder *ark.Embedder) (*milvus.Retriever, error) {
-	retriever, err := milvus.NewRetriever(context.Background(), &milvus.RetrieverConfig{
-		Client:      *milvusCli,
-		Collection:  "muelsyse",
-		Partition:   nil,
-		VectorField: "",
-		OutputFields: []string{
-			"id",
-			"content",
-			"metadata",
-		},
-		TopK:      1,
-		Embedding: embedder,
-	})
-	if err != nil {
+const (
+	defaultCollection = "muelsyse"
+	DefaultTopK       = 3
+)
+
+var outputFields = []string{
+	"id",
+	"content",
+	"metadata",
+}
+
+// Retriever 在 milvus 检索器的基础上支持按请求覆盖 TopK、分数阈值、分区与过滤条件
+type Retriever struct {
+	cli            client.Client
+	embedder       embedding.Embedder
+	collection     string
+	topK           int
+	scoreThreshold float64
+	partitions     []string
+	filter         string
+	metricType     entity.MetricType
+	searchParam    entity.SearchParam
+
+	mu sync.Mutex
+	// 按分区组合缓存的 milvus 检索器
+	retrievers map[string]*milvus.Retriever
+}
+
+// Config 检索器配置
+type Config struct {
+	Client     client.Client
+	Embedding  embedding.Embedder
+	Collection string
+	// 以下为默认检索参数，可在请求中通过选项覆盖
+	TopK           int
+	ScoreThreshold float64
+	Partitions     []string
+	Filter         string
+	// 与集合索引一致的度量和检索参数
+	MetricType  entity.MetricType
+	SearchParam entity.SearchParam
+}
+
+// NewRetriever 创建检索器
+func NewRetriever(ctx context.Context, cfg *Config) (*Retriever, error) {
+	r := &Retriever{
+		cli:            cfg.Client,
+		embedder:       cfg.Embedding,
+		collection:     cfg.Collection,
+		topK:           cfg.TopK,
+		scoreThreshold: cfg.ScoreThreshold,
+		partitions:     cfg.Partitions,
+		filter:         cfg.Filter,
+		metricType:     cfg.MetricType,
+		searchParam:    cfg.SearchParam,
+		retrievers:     make(map[string]*milvus.Retriever),
+	}
+	if r.collection == "" {
+		r.collection = defaultCollection
+	}
+	if r.topK <= 0 {
+		r.topK = DefaultTopK
+	}
+	if r.metricType == "" {
+		r.metricType = entity.COSINE
+	}
+
+	// 提前创建默认分区的检索器，尽早暴露集合不存在等问题
+	if _, err := r.getRetriever(ctx, r.partitions); err != nil {
 		zap.S().Error("Failed to create retriever: %v", zap.String("error", err.Error()))
 		return nil, err
 	}
-	return retriever, nil
+	return r, nil
+}
+
+func (r *Retriever) Retrieve(ctx context.Context, query string, opts ...retriever.Option) ([]*schema.Document, error) {
+	topK := r.topK
+	scoreThreshold := r.scoreThreshold
+	co := retriever.GetCommonOptions(&retriever.Options{
+		TopK:           &topK,
+		ScoreThreshold: &scoreThreshold,
+	}, opts...)
+	io := retriever.GetImplSpecificOptions(&ImplOptions{
+		Partitions: r.partitions,
+	}, opts...)
+
+	expr, err := JoinFilters(r.filter, io.Filter, io.MetadataFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	mr, err := r.getRetriever(ctx, io.Partitions)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := mr.Retrieve(ctx, query, retriever.WithTopK(*co.TopK), milvus.WithFilter(expr))
+	if err != nil {
+		return nil, err
+	}
+
+	if *co.ScoreThreshold == 0 {
+		return docs, nil
+	}
+	results := make([]*schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if passThreshold(r.metricType, doc.Score(), *co.ScoreThreshold) {
+			results = append(results, doc)
+		}
+	}
+	return results, nil
+}
+
+// HigherIsBetter 分数是否越大越相关
+func (r *Retriever) HigherIsBetter() bool {
+	return r.metricType == entity.IP || r.metricType == entity.COSINE
+}
+
+func (r *Retriever) GetType() string {
+	return "MoonAgentMilvus"
+}
+
+// IsCallbacksEnabled 内部的 milvus 检索器会触发 eino 回调
+func (r *Retriever) IsCallbacksEnabled() bool {
+	return true
+}
+
+// getRetriever 获取指定分区组合对应的 milvus 检索器，不存在时创建
+func (r *Retriever) getRetriever(ctx context.Context, partitions []string) (*milvus.Retriever, error) {
+	sorted := append([]string(nil), partitions...)
+	sort.Strings(sorted)
+	key := strings.Join(sorted, ",")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if mr, ok := r.retrievers[key]; ok {
+		return mr, nil
+	}
+
+	mr, err := milvus.NewRetriever(ctx, &milvus.RetrieverConfig{
+		Client:            r.cli,
+		Collection:        r.collection,
+		Partition:         sorted,
+		VectorField:       "vector",
+		OutputFields:      outputFields,
+		DocumentConverter: documentConverter,
+		VectorConverter:   vectorConverter,
+		MetricType:        r.metricType,
+		Sp:                r.searchParam,
+		TopK:              r.topK,
+		Embedding:         r.embedder,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retriever for partitions [%s]: %w", key, err)
+	}
+	r.retrievers[key] = mr
+	return mr, nil
+}
+
+// passThreshold 判断分数是否满足阈值，相似度类度量越大越好，距离类度量越小越好
+func passThreshold(metric entity.MetricType, score, threshold float64) bool {
+	switch metric {
+	case entity.IP, entity.COSINE:
+		return score >= threshold
+	default:
+		return score <= threshold
+	}
 }
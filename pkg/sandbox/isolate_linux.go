@@ -0,0 +1,62 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func namespacesSupported() bool {
+	return true
+}
+
+// isolate 子进程放入独立的进程组，超时时整组终止；禁止网络时放入新的用户和网络命名空间，其中只有回环网卡
+func isolate(cmd *exec.Cmd, network bool) {
+	attr := &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if !network {
+		attr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+	cmd.SysProcAttr = attr
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// confine 子进程放入新的用户、挂载和 PID 命名空间，禁止网络时还有新的网络命名空间。
+// 命名空间中的 root 对应宿主上的 uid 和 gid，只在命名空间内有权限，用于搭建只读的根文件系统；
+// 子进程是 PID 命名空间中的 1 号进程，它退出或被终止时命名空间中的所有进程随之终止，setsid 也无法逃逸
+func confine(cmd *exec.Cmd, network bool, uid, gid int) {
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID)
+	if !network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	attr := &syscall.SysProcAttr{
+		Setpgid:     true,
+		Pdeathsig:   syscall.SIGKILL,
+		Cloneflags:  flags,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: gid, Size: 1}},
+	}
+	if os.Getuid() == 0 {
+		// 服务以 root 运行时子进程仍是宿主的 root，需要切换到映射的用户，并清空附加组，
+		// 否则仍以宿主 root 和它的附加组访问文件
+		attr.Credential = &syscall.Credential{Uid: 0, Gid: 0, Groups: []uint32{}}
+		attr.GidMappingsEnableSetgroups = true
+	}
+	cmd.SysProcAttr = attr
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// killGroup 终止子进程所在的整个进程组
+func killGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
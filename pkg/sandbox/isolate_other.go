@@ -0,0 +1,21 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"os/exec"
+)
+
+func namespacesSupported() bool {
+	return false
+}
+
+// isolate 非 Linux 平台不支持命名空间隔离，只在超时时终止子进程
+func isolate(cmd *exec.Cmd, network bool) {
+}
+
+func confine(cmd *exec.Cmd, network bool, uid, gid int) {
+}
+
+func killGroup(cmd *exec.Cmd) {
+}
@@ -0,0 +1,194 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 挂载到隔离环境中的系统目录，是符号链接时在根中重建同样的链接
+var systemDirs = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32"}
+
+// 绑定到隔离环境中的设备
+var jailDevices = []string{"null", "zero", "random", "urandom"}
+
+// jail 代码执行的隔离环境：在新的挂载命名空间中以 tmpfs 为根，只读挂载系统目录和解释器，
+// 读写挂载工作区和临时目录，以共用的 Go 编译缓存为只读下层叠加本次执行的可写层，
+// 切换根目录后丢弃全部 capability 再执行命令。
+// 根中看不到宿主的其他文件，包括服务的配置和密钥
+type jail struct {
+	mount     string
+	umount    string
+	pivotRoot string
+	setpriv   string
+	prlimit   string
+	// mounts 只读挂载到根中同一路径的目录
+	mounts []string
+	// links 在根中重建的符号链接，键为路径，值为链接目标
+	links map[string]string
+}
+
+// newJail 查找搭建隔离环境需要的 util-linux 命令，确定需要挂载的目录；
+// interpreters 为解释器的真实路径，不在系统目录中时挂载它的安装目录
+func newJail(interpreters []string) (*jail, error) {
+	j := &jail{links: make(map[string]string)}
+	for _, t := range []struct {
+		name string
+		path *string
+	}{
+		{"mount", &j.mount},
+		{"umount", &j.umount},
+		{"pivot_root", &j.pivotRoot},
+		{"setpriv", &j.setpriv},
+		{"prlimit", &j.prlimit},
+	} {
+		path, err := lookSbin(t.name)
+		if err != nil {
+			return nil, fmt.Errorf("%s not found, install util-linux or set insecure to true: %w", t.name, err)
+		}
+		*t.path = path
+	}
+
+	for _, dir := range systemDirs {
+		info, err := os.Lstat(dir)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(dir)
+			if err != nil {
+				return nil, err
+			}
+			j.links[dir] = target
+		} else if info.IsDir() {
+			j.mounts = append(j.mounts, dir)
+		}
+	}
+	for _, bin := range interpreters {
+		if !j.covers(bin) {
+			j.mounts = append(j.mounts, filepath.Dir(filepath.Dir(bin)))
+		}
+	}
+	// 命令在切换根目录之后执行，必须位于挂载的目录中
+	for _, bin := range []string{j.umount, j.setpriv, j.prlimit} {
+		if real, err := filepath.EvalSymlinks(bin); err != nil || !j.covers(real) {
+			return nil, fmt.Errorf("%s is outside the system directories mounted into the sandbox", bin)
+		}
+	}
+	sort.Strings(j.mounts)
+	return j, nil
+}
+
+// covers 路径位于只读挂载的目录中
+func (j *jail) covers(path string) bool {
+	for _, dir := range j.mounts {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// script 生成在新命名空间中以命名空间 root 执行的 sh 脚本：搭建根文件系统，切换根目录，
+// 以 no_new_privs 丢弃全部 capability，用 prlimit 设置资源限制后执行命令。
+// root 为根文件系统的挂载点，dir 为工作区，goCache 为空时不挂载编译缓存。
+// 共用的编译缓存只作为 overlay 的只读下层，写入落在工作区中本次执行的可写层，
+// 避免一次执行写入的缓存条目被之后其他会话的编译链接；内核不支持在用户命名空间中挂载 overlay 时
+// 退化为只使用本次执行的空缓存。writeCache 为 true 时直接读写挂载共用缓存，只用于启动时执行可信代码预热
+func (j *jail) script(root, dir, goCache string, writeCache bool, limits []string, command string) string {
+	var s strings.Builder
+	line := func(args ...string) {
+		for i, arg := range args {
+			if i > 0 {
+				s.WriteByte(' ')
+			}
+			s.WriteString(arg)
+		}
+		s.WriteByte('\n')
+	}
+	q := shellQuote
+	mount := q(j.mount)
+
+	line("set -e")
+	// 挂载不传播回宿主
+	line(mount, "--make-rprivate", "/")
+	line(mount, "-t", "tmpfs", "-o", "size=1m,mode=755", "moonagent-sandbox", q(root))
+	line("cd", q(root))
+	line("mkdir", "-p", "work", "tmp", "gocache", "dev", "proc", ".old")
+	for path, target := range j.links {
+		line("ln", "-s", q(target), q(strings.TrimPrefix(path, "/")))
+	}
+	for _, path := range j.mounts {
+		rel := q(strings.TrimPrefix(path, "/"))
+		line("mkdir", "-p", rel)
+		line(mount, "--rbind", q(path), rel)
+		line(mount, "-o", "remount,bind,ro", rel)
+	}
+	line(mount, "--bind", q(dir), "work")
+	line(mount, "--bind", q(filepath.Join(dir, tmpDirName)), "tmp")
+	if goCache != "" && writeCache {
+		line(mount, "--bind", q(goCache), "gocache")
+	} else if goCache != "" {
+		upper := filepath.Join(dir, cacheDirName, "upper")
+		work := filepath.Join(dir, cacheDirName, "work")
+		line(mount, "-t", "overlay", "-o", q("lowerdir="+goCache+",upperdir="+upper+",workdir="+work),
+			"moonagent-gocache", "gocache", "2>/dev/null", "||", mount, "--bind", q(upper), "gocache")
+	}
+	for _, dev := range jailDevices {
+		line("touch", "dev/"+dev)
+		line(mount, "--bind", "/dev/"+dev, "dev/"+dev)
+	}
+	// 容器中 /proc 被部分遮盖时无法挂载，不影响执行
+	line(mount, "-t", "proc", "proc", "proc", "2>/dev/null", "||", "true")
+	line(mount, "-o", "remount,bind,ro", q(root))
+	line(q(j.pivotRoot), ".", ".old")
+	line(q(j.umount), "-l", "/.old")
+	line("cd", "/work")
+	args := []string{"exec", q(j.setpriv), "--no-new-privs", "--inh-caps=-all", "--bounding-set=-all", "--", q(j.prlimit)}
+	args = append(args, limits...)
+	args = append(args, "--", "/bin/sh", "-c", q(command))
+	line(args...)
+	return s.String()
+}
+
+// lookSbin 在 PATH 中查找命令，服务的 PATH 中常常没有 sbin 目录，找不到时再查找这些目录
+func lookSbin(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err == nil {
+		return filepath.Abs(path)
+	}
+	for _, dir := range []string{"/usr/sbin", "/sbin", "/usr/bin", "/bin"} {
+		candidate := filepath.Join(dir, name)
+		if info, statErr := os.Stat(candidate); statErr == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", err
+}
+
+// chownTree 把工作区中的文件交给执行代码的用户，使其可以修改输入文件
+func chownTree(dir string, uid, gid int) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// dirSize 目录中普通文件的总大小，不跟随符号链接
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,631 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"MoonAgent/pkg/config"
+)
+
+// 支持的语言
+const (
+	LanguagePython = "python"
+	LanguageGo     = "go"
+)
+
+// 默认限制
+const (
+	DefaultTimeout        = 30 * time.Second
+	DefaultCPUTime        = 10 * time.Second
+	DefaultMemoryMB       = 512
+	DefaultMaxOutputBytes = 16 * 1024
+	DefaultMaxFileBytes   = 64 * 1024
+	DefaultMaxDiskMB      = 256
+	DefaultMaxProcesses   = 256
+	// nobodyID 以 root 运行的服务默认使用的 uid 和 gid
+	nobodyID = 65534
+	// tmpDirName 工作区中用作临时目录的子目录，写入量计入磁盘限制
+	tmpDirName = ".tmp"
+	// cacheDirName 工作区中存放本次执行 Go 编译缓存可写层的子目录，写入量计入磁盘限制
+	cacheDirName = ".gocache"
+	// diskCheckInterval 检查磁盘写入量的间隔
+	diskCheckInterval = 100 * time.Millisecond
+)
+
+var (
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	ErrEmptyCode           = errors.New("code cannot be empty")
+)
+
+// Request 一次代码执行请求
+type Request struct {
+	Language string
+	Code     string
+	// Files 执行前写入工作区的输入文件，键为相对路径
+	Files map[string]string
+}
+
+// File 执行后工作区中新增或修改的文件
+type File struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// Content 文本文件的内容，二进制文件或超出总大小上限时为空
+	Content   string `json:"content,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// Result 代码执行结果
+type Result struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exitCode"`
+	// TimedOut 超过墙钟超时被终止
+	TimedOut bool `json:"timedOut"`
+	// OutputTruncated stdout 或 stderr 超过上限被截断
+	OutputTruncated bool `json:"outputTruncated"`
+	// DiskLimitExceeded 写入量超过磁盘上限被终止
+	DiskLimitExceeded bool          `json:"diskLimitExceeded,omitempty"`
+	Files             []File        `json:"files"`
+	Duration          time.Duration `json:"duration"`
+}
+
+// Runner 在临时工作区中以子进程执行代码，限制 CPU 时间、内存、进程数、磁盘写入、输出大小和墙钟时间。
+// 默认在独立的用户、挂载、PID 和网络命名空间中以专用 uid 运行，只能看到只读的系统目录和自己的工作区
+type Runner struct {
+	languages map[string]string
+	workDir   string
+	timeout   time.Duration
+	cpuTime   time.Duration
+	memoryMB  int
+	maxOutput int
+	maxFile   int
+	maxDisk   int64
+	maxProcs  int
+	network   bool
+	uid       int
+	gid       int
+	// jail 为空时不做隔离，对应 insecure 配置
+	jail *jail
+	// goCache go 编译缓存，所有执行共用以避免每次重新编译标准库。
+	// 隔离执行时只读挂载，只由启动时的预热写入
+	goCache string
+}
+
+// NewRunner 根据配置创建执行器，只保留本机可用的语言
+func NewRunner(cfg *config.CodeExecConfig) (*Runner, error) {
+	r := &Runner{
+		languages: make(map[string]string),
+		workDir:   cfg.WorkDir,
+		timeout:   cfg.Timeout,
+		cpuTime:   cfg.CPUTime,
+		memoryMB:  cfg.MemoryMB,
+		maxOutput: cfg.MaxOutputBytes,
+		maxFile:   cfg.MaxFileBytes,
+		maxDisk:   int64(cfg.MaxDiskMB) * 1024 * 1024,
+		maxProcs:  cfg.MaxProcesses,
+		network:   cfg.Network,
+	}
+	if r.workDir == "" {
+		r.workDir = os.TempDir()
+	}
+	if r.timeout <= 0 {
+		r.timeout = DefaultTimeout
+	}
+	if r.cpuTime <= 0 {
+		r.cpuTime = DefaultCPUTime
+	}
+	if r.memoryMB <= 0 {
+		r.memoryMB = DefaultMemoryMB
+	}
+	if r.maxOutput <= 0 {
+		r.maxOutput = DefaultMaxOutputBytes
+	}
+	if r.maxFile <= 0 {
+		r.maxFile = DefaultMaxFileBytes
+	}
+	if r.maxDisk <= 0 {
+		r.maxDisk = DefaultMaxDiskMB * 1024 * 1024
+	}
+	if r.maxProcs <= 0 {
+		r.maxProcs = DefaultMaxProcesses
+	}
+	if !cfg.Insecure && !namespacesSupported() {
+		return nil, errors.New("code sandbox requires linux namespaces, set insecure to true to run without isolation")
+	}
+	workDir, err := filepath.Abs(r.workDir)
+	if err != nil {
+		return nil, err
+	}
+	r.workDir = workDir
+	if err := os.MkdirAll(r.workDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	languages := cfg.Languages
+	if len(languages) == 0 {
+		languages = []string{LanguagePython, LanguageGo}
+	}
+	for _, lang := range languages {
+		var name, path string
+		switch lang {
+		case LanguagePython:
+			name, path = "python3", cfg.Python
+		case LanguageGo:
+			name, path = "go", cfg.Go
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, lang)
+		}
+		if path == "" {
+			found, err := exec.LookPath(name)
+			if err != nil {
+				continue
+			}
+			path = found
+		}
+		// 隔离环境中只挂载解释器所在的目录，使用解析符号链接后的真实路径
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil, fmt.Errorf("interpreter for %s not found: %w", lang, err)
+		}
+		real, err = filepath.Abs(real)
+		if err != nil {
+			return nil, err
+		}
+		r.languages[lang] = real
+	}
+	if len(r.languages) == 0 {
+		return nil, fmt.Errorf("no interpreter found for languages %v", languages)
+	}
+
+	if !cfg.Insecure {
+		if err := r.setupJail(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := r.languages[LanguageGo]; ok {
+		r.goCache = filepath.Join(r.workDir, "moonagent-sandbox-gocache")
+		if err := os.MkdirAll(r.goCache, 0o755); err != nil {
+			return nil, err
+		}
+		if r.jail != nil {
+			if err := chownTree(r.goCache, r.uid, r.gid); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if r.jail != nil {
+		if err := r.probe(); err != nil {
+			return nil, err
+		}
+		if r.goCache != "" {
+			r.warmCache()
+		}
+	}
+	return r, nil
+}
+
+// setupJail 确定执行代码的用户并准备隔离环境。只有 root 可以把命名空间映射到其他用户，
+// 以 root 运行的服务默认使用 nobody，避免代码以宿主 root 的身份访问挂载进来的文件
+func (r *Runner) setupJail(cfg *config.CodeExecConfig) error {
+	r.uid, r.gid = cfg.UID, cfg.GID
+	if os.Getuid() == 0 {
+		if r.uid == 0 {
+			r.uid = nobodyID
+		}
+		if r.gid == 0 {
+			r.gid = nobodyID
+		}
+	} else {
+		if r.uid == 0 {
+			r.uid = os.Getuid()
+		}
+		if r.gid == 0 {
+			r.gid = os.Getgid()
+		}
+		if r.uid != os.Getuid() || r.gid != os.Getgid() {
+			return errors.New("running code as another uid or gid requires the server to run as root")
+		}
+	}
+	interpreters := make([]string, 0, len(r.languages))
+	for _, bin := range r.languages {
+		interpreters = append(interpreters, bin)
+	}
+	j, err := newJail(interpreters)
+	if err != nil {
+		return err
+	}
+	r.jail = j
+	return nil
+}
+
+// probe 启动时在隔离环境中执行一次空命令，确认用户命名空间等功能在当前环境中可用。
+// Docker 默认的 seccomp 配置会禁止创建用户命名空间，这时在启动阶段报错，而不是每次执行都失败
+func (r *Runner) probe() error {
+	dir, err := os.MkdirTemp(r.workDir, "moonagent-sandbox-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	res, err := r.exec(context.Background(), dir, "exit 0", false)
+	if err == nil && res.ExitCode != 0 {
+		err = fmt.Errorf("exit code %d: %s", res.ExitCode, strings.TrimSpace(res.Stderr))
+	}
+	if err != nil {
+		return fmt.Errorf("code sandbox is not usable here, it needs user, mount and pid namespaces "+
+			"(blocked by the default seccomp profile of docker) and a work_dir accessible to uid %d; "+
+			"set insecure to true to run without isolation: %w", r.uid, err)
+	}
+	return nil
+}
+
+// warmProgram 预热编译缓存的程序，引用代码中常用的标准库
+const warmProgram = `package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	_ = bufio.NewWriter(os.Stdout)
+	_, _ = json.Marshal(sort.IntSlice{int(math.Sqrt(4))})
+	fmt.Println(strings.TrimSpace(strconv.Itoa(time.Now().Year())))
+}
+`
+
+// warmCache 在隔离环境中编译一次可信的程序，把标准库写入共用的编译缓存。
+// 隔离执行时共用缓存只读，只有这里可以写入；预热失败时各次执行在自己的可写层中重新编译，不影响使用
+func (r *Runner) warmCache() {
+	dir, err := os.MkdirTemp(r.workDir, "moonagent-sandbox-*")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(warmProgram), 0o644); err != nil {
+		return
+	}
+	command := shellQuote(r.languages[LanguageGo]) + ` build -o "$TMPDIR/main.bin" main.go`
+	_, _ = r.exec(context.Background(), dir, command, true)
+}
+
+// Isolated 代码在隔离环境中执行
+func (r *Runner) Isolated() bool {
+	return r.jail != nil
+}
+
+// Network 代码可以访问网络
+func (r *Runner) Network() bool {
+	return r.network || r.jail == nil
+}
+
+// Languages 可用的语言，按名称排序
+func (r *Runner) Languages() []string {
+	langs := make([]string, 0, len(r.languages))
+	for lang := range r.languages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Run 在新建的临时工作区中执行代码，执行结束后删除工作区
+func (r *Runner) Run(ctx context.Context, req *Request) (*Result, error) {
+	bin, ok := r.languages[req.Language]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s, available: %v", ErrUnsupportedLanguage, req.Language, r.Languages())
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		return nil, ErrEmptyCode
+	}
+
+	dir, err := os.MkdirTemp(r.workDir, "moonagent-sandbox-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range req.Files {
+		path, err := workspacePath(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	var source, command string
+	switch req.Language {
+	case LanguagePython:
+		source = "main.py"
+		command = shellQuote(bin) + " -I main.py"
+	case LanguageGo:
+		// 先编译再运行，编译产物放在临时目录中，避免出现在产出文件中
+		source = "main.go"
+		command = shellQuote(bin) + ` build -o "$TMPDIR/main.bin" main.go && exec "$TMPDIR/main.bin"`
+	}
+	if err := os.WriteFile(filepath.Join(dir, source), []byte(req.Code), 0o644); err != nil {
+		return nil, err
+	}
+
+	before := snapshot(dir)
+	result, err := r.exec(ctx, dir, command, false)
+	if err != nil {
+		return nil, err
+	}
+	result.Files = r.collectFiles(dir, before, source)
+	return result, nil
+}
+
+// exec 在工作区中执行命令，限制 CPU 时间、内存、进程数和写入量。
+// 内存限制使用数据段大小而不是虚拟内存，Go 运行时启动时会预留大量地址空间，限制虚拟内存会导致无法启动。
+// writeCache 为 true 时隔离环境中可以写入共用的编译缓存，只用于执行可信代码
+func (r *Runner) exec(ctx context.Context, dir, command string, writeCache bool) (*Result, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	ctx, stop := context.WithCancel(timeoutCtx)
+	defer stop()
+
+	if err := os.Mkdir(filepath.Join(dir, tmpDirName), 0o755); err != nil {
+		return nil, err
+	}
+	cpuSeconds := int(r.cpuTime.Seconds())
+	if cpuSeconds < 1 {
+		cpuSeconds = 1
+	}
+	dataBytes := int64(r.memoryMB) * 1024 * 1024
+
+	var cmd *exec.Cmd
+	if r.jail != nil {
+		root := dir + ".root"
+		if err := os.Mkdir(root, 0o755); err != nil {
+			return nil, err
+		}
+		defer os.Remove(root)
+		if r.goCache != "" && !writeCache {
+			for _, sub := range []string{"upper", "work"} {
+				if err := os.MkdirAll(filepath.Join(dir, cacheDirName, sub), 0o755); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := chownTree(dir, r.uid, r.gid); err != nil {
+			return nil, err
+		}
+		if err := os.Lchown(root, r.uid, r.gid); err != nil {
+			return nil, err
+		}
+		limits := []string{
+			fmt.Sprintf("--cpu=%d", cpuSeconds),
+			fmt.Sprintf("--data=%d", dataBytes),
+			fmt.Sprintf("--fsize=%d", r.maxDisk),
+			fmt.Sprintf("--nproc=%d", r.maxProcs),
+		}
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", r.jail.script(root, dir, r.goCache, writeCache, limits, command))
+		cmd.Dir = "/"
+		cmd.Env = r.env("/work", "/tmp", "/gocache")
+		confine(cmd, r.network, r.uid, r.gid)
+	} else {
+		// 不隔离时进程数按服务所属用户的全部进程计算，无法单独限制
+		limits := fmt.Sprintf("ulimit -t %d && ulimit -d %d && ulimit -f %d && %s",
+			cpuSeconds, dataBytes/1024, r.maxDisk/512, command)
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", limits)
+		cmd.Dir = dir
+		// 不隔离时代码本就可以写入服务用户的全部文件，编译缓存直接读写共用
+		cmd.Env = r.env(dir, filepath.Join(dir, tmpDirName), r.goCache)
+		isolate(cmd, true)
+	}
+	stdout := newLimitedBuffer(r.maxOutput)
+	stderr := newLimitedBuffer(r.maxOutput)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start sandbox process failed: %w", err)
+	}
+	var exceeded atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if r.watchDisk(ctx, dir) {
+			exceeded.Store(true)
+			stop()
+		}
+	}()
+	err := cmd.Wait()
+	stop()
+	<-done
+	// 终止进程组中残留的后台进程
+	killGroup(cmd)
+	result := &Result{
+		Stdout:            stdout.String(),
+		Stderr:            stderr.String(),
+		ExitCode:          cmd.ProcessState.ExitCode(),
+		TimedOut:          errors.Is(timeoutCtx.Err(), context.DeadlineExceeded),
+		OutputTruncated:   stdout.truncated || stderr.truncated,
+		DiskLimitExceeded: exceeded.Load(),
+		Duration:          time.Since(start),
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		// 后台进程持有输出管道时等待超过 WaitDelay 后返回 ErrWaitDelay，已经拿到退出码，不视为错误
+		if !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) && !result.TimedOut && !result.DiskLimitExceeded {
+			return nil, fmt.Errorf("run sandbox process failed: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// watchDisk 定期统计工作区的写入量，超过上限时返回 true，ctx 结束时返回 false。
+// 隔离执行时编译缓存的写入落在工作区中的可写层，一并统计
+func (r *Runner) watchDisk(ctx context.Context, dir string) bool {
+	ticker := time.NewTicker(diskCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		if dirSize(dir) > r.maxDisk {
+			return true
+		}
+	}
+}
+
+// env 子进程的环境变量，不继承服务进程的环境，避免泄露密钥；
+// home、tmp 和 goCache 为子进程中看到的路径
+func (r *Runner) env(home, tmp, goCache string) []string {
+	path := "/usr/local/bin:/usr/bin:/bin"
+	env := []string{
+		"HOME=" + home,
+		"TMPDIR=" + tmp,
+		"LANG=C.UTF-8",
+		"PYTHONDONTWRITEBYTECODE=1",
+	}
+	if goBin, ok := r.languages[LanguageGo]; ok {
+		path = filepath.Dir(goBin) + ":" + path
+		env = append(env,
+			"GOCACHE="+goCache,
+			"GOPATH="+filepath.Join(home, ".gopath"),
+			"GOPROXY=off",
+			"GO111MODULE=off",
+			"GOTOOLCHAIN=local",
+			"CGO_ENABLED=0",
+		)
+	}
+	return append(env, "PATH="+path)
+}
+
+// collectFiles 收集执行后新增或修改的文件，文本文件的内容按总大小上限返回
+func (r *Runner) collectFiles(dir string, before map[string]time.Time, source string) []File {
+	files := make([]File, 0)
+	budget := r.maxFile
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") && rel != "." {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel == source || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if modTime, ok := before[rel]; ok && modTime.Equal(info.ModTime()) {
+			return nil
+		}
+		file := File{Path: filepath.ToSlash(rel), Size: info.Size()}
+		if budget > 0 {
+			if data, err := os.ReadFile(path); err == nil && utf8.Valid(data) && !bytes.ContainsRune(data, 0) {
+				if len(data) > budget {
+					data = data[:budget]
+					file.Truncated = true
+				}
+				file.Content = string(data)
+				budget -= len(data)
+			}
+		} else {
+			file.Truncated = true
+		}
+		files = append(files, file)
+		return nil
+	})
+	return files
+}
+
+// snapshot 记录执行前工作区中文件的修改时间
+func snapshot(dir string) map[string]time.Time {
+	files := make(map[string]time.Time)
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			rel, _ := filepath.Rel(dir, path)
+			files[rel] = info.ModTime()
+		}
+		return nil
+	})
+	return files
+}
+
+// workspacePath 把相对路径解析到工作区中，拒绝越出工作区的路径
+func workspacePath(dir, name string) (string, error) {
+	if name == "" || filepath.IsAbs(name) {
+		return "", fmt.Errorf("invalid file path: %q", name)
+	}
+	path := filepath.Join(dir, name)
+	if path != dir && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("file path escapes workspace: %q", name)
+	}
+	return path, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// limitedBuffer 只保留前 limit 字节的输出，超出部分丢弃但不阻塞子进程
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func newLimitedBuffer(limit int) *limitedBuffer {
+	return &limitedBuffer{limit: limit}
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.limit - b.buf.Len(); remain > 0 {
+		if len(p) > remain {
+			b.buf.Write(p[:remain])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	s := b.buf.String()
+	if b.truncated {
+		s = strings.ToValidUTF8(s, "") + "\n...(输出过长，已截断，共保留 " + strconv.Itoa(b.limit) + " 字节)"
+	}
+	return s
+}
@@ -0,0 +1,231 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"MoonAgent/pkg/config"
+)
+
+// newTestRunner 创建在隔离环境中执行 python 的执行器，环境不支持命名空间或没有系统 python 时跳过。
+// python 必须位于挂载进隔离环境的系统目录中，pyenv 等安装在用户目录中的解释器对执行代码的用户不可见
+func newTestRunner(t *testing.T, cfg config.CodeExecConfig) *Runner {
+	t.Helper()
+	python, err := filepath.EvalSymlinks("/usr/bin/python3")
+	if err != nil {
+		t.Skip("system python3 not found")
+	}
+	workDir, err := os.MkdirTemp("", "moonagent-sandbox-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(workDir) })
+	// 执行代码的用户需要能进入工作区的父目录
+	if err := os.Chmod(workDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Languages = []string{LanguagePython}
+	cfg.Python = python
+	cfg.WorkDir = workDir
+	r, err := NewRunner(&cfg)
+	if err != nil {
+		t.Skipf("sandbox not available: %v", err)
+	}
+	return r
+}
+
+func runPython(t *testing.T, r *Runner, code string) *Result {
+	t.Helper()
+	res, err := r.Run(context.Background(), &Request{Language: LanguagePython, Code: code})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	return res
+}
+
+func TestWorkspacePath(t *testing.T) {
+	dir := "/work/run"
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"a.txt", "/work/run/a.txt", true},
+		{"sub/b.txt", "/work/run/sub/b.txt", true},
+		{"sub/../c.txt", "/work/run/c.txt", true},
+		{"", "", false},
+		{"/etc/passwd", "", false},
+		{"../x", "", false},
+		{"sub/../../x", "", false},
+		{"..", "", false},
+	}
+	for _, tt := range tests {
+		got, err := workspacePath(dir, tt.name)
+		if (err == nil) != tt.ok {
+			t.Errorf("workspacePath(%q) err = %v, want ok %v", tt.name, err, tt.ok)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("workspacePath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := newLimitedBuffer(5)
+	for _, chunk := range []string{"abc", "defg", "hij"} {
+		if n, err := b.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v, want full length without error", chunk, n, err)
+		}
+	}
+	if !b.truncated {
+		t.Fatal("buffer not marked truncated")
+	}
+	if got := b.String(); !strings.HasPrefix(got, "abcde\n") || !strings.Contains(got, "5 字节") {
+		t.Errorf("String() = %q", got)
+	}
+
+	// 恰好写满不算截断
+	b = newLimitedBuffer(3)
+	_, _ = b.Write([]byte("abc"))
+	_, _ = b.Write(nil)
+	if b.truncated || b.String() != "abc" {
+		t.Errorf("exact fit: truncated %v, String() = %q", b.truncated, b.String())
+	}
+}
+
+func TestRunRejectsEscapingFiles(t *testing.T) {
+	r := newTestRunner(t, config.CodeExecConfig{})
+	for _, name := range []string{"../escape.txt", "/etc/escape.txt"} {
+		_, err := r.Run(context.Background(), &Request{
+			Language: LanguagePython,
+			Code:     "print(1)",
+			Files:    map[string]string{name: "x"},
+		})
+		if err == nil {
+			t.Errorf("file %q accepted", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(r.workDir), "escape.txt")); err == nil {
+		t.Error("file written outside the work dir")
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := newTestRunner(t, config.CodeExecConfig{Timeout: time.Second})
+	start := time.Now()
+	res := runPython(t, r, "import time\ntime.sleep(30)\n")
+	if !res.TimedOut {
+		t.Errorf("TimedOut = false, result %+v", res)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("run took %v after a 1s timeout", elapsed)
+	}
+}
+
+func TestRunOutputTruncated(t *testing.T) {
+	r := newTestRunner(t, config.CodeExecConfig{MaxOutputBytes: 100})
+	res := runPython(t, r, "print('x' * 1000)\n")
+	if res.ExitCode != 0 {
+		t.Fatalf("exit code %d, stderr %q", res.ExitCode, res.Stderr)
+	}
+	if !res.OutputTruncated {
+		t.Error("OutputTruncated = false")
+	}
+	if !strings.HasPrefix(res.Stdout, strings.Repeat("x", 100)+"\n") {
+		t.Errorf("stdout = %q", res.Stdout)
+	}
+}
+
+func TestRunDiskLimit(t *testing.T) {
+	r := newTestRunner(t, config.CodeExecConfig{MaxDiskMB: 1, Timeout: 20 * time.Second})
+	// 每个文件都在 fsize 限制之内，只有总写入量超限，由磁盘检查终止
+	code := `import time
+for i in range(20):
+    with open("f%d.bin" % i, "wb") as f:
+        f.write(b"0" * 256 * 1024)
+time.sleep(15)
+print("not killed")
+`
+	res := runPython(t, r, code)
+	if !res.DiskLimitExceeded {
+		t.Errorf("DiskLimitExceeded = false, result %+v", res)
+	}
+	if res.TimedOut || strings.Contains(res.Stdout, "not killed") {
+		t.Errorf("run was not stopped by the disk check: %+v", res)
+	}
+}
+
+func TestRunNetworkDenied(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	r := newTestRunner(t, config.CodeExecConfig{})
+	if r.Network() {
+		t.Fatal("network allowed by default")
+	}
+	code := `import urllib.request
+try:
+    urllib.request.urlopen("` + srv.URL + `", timeout=3)
+    print("connected")
+except Exception:
+    print("blocked")
+`
+	res := runPython(t, r, code)
+	if strings.TrimSpace(res.Stdout) != "blocked" {
+		t.Errorf("stdout = %q, stderr = %q", res.Stdout, res.Stderr)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("server received %d requests from the sandbox", hits.Load())
+	}
+}
+
+func TestRunSharedCacheReadOnly(t *testing.T) {
+	r := newTestRunner(t, config.CodeExecConfig{})
+	// 只启用 python 时不创建编译缓存，这里手动设置，检查挂载方式
+	r.goCache = filepath.Join(r.workDir, "moonagent-sandbox-gocache")
+	if err := os.MkdirAll(r.goCache, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(r.goCache, "seed"), []byte("trusted"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := chownTree(r.goCache, r.uid, r.gid); err != nil {
+		t.Fatal(err)
+	}
+
+	// 执行的代码可以读到共用缓存，写入只落在本次执行的可写层
+	res := runPython(t, r, `print(open("/gocache/seed").read())
+open("/gocache/seed", "w").write("poisoned")
+open("/gocache/planted", "w").write("poisoned")
+`)
+	if res.ExitCode != 0 || strings.TrimSpace(res.Stdout) != "trusted" {
+		t.Fatalf("first run: exit %d, stdout %q, stderr %q", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	if data, _ := os.ReadFile(filepath.Join(r.goCache, "seed")); string(data) != "trusted" {
+		t.Errorf("shared cache entry modified: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(r.goCache, "planted")); err == nil {
+		t.Error("entry planted into the shared cache")
+	}
+
+	// 之后的执行看不到上一次执行的写入
+	res = runPython(t, r, `import os
+print(open("/gocache/seed").read(), os.path.exists("/gocache/planted"))
+`)
+	if strings.TrimSpace(res.Stdout) != "trusted False" {
+		t.Errorf("second run: stdout %q, stderr %q", res.Stdout, res.Stderr)
+	}
+}
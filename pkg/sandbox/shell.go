@@ -0,0 +1,322 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"MoonAgent/pkg/config"
+	"MoonAgent/pkg/workspace"
+)
+
+// 命令行工具的默认限制
+const (
+	DefaultShellTimeout = 15 * time.Second
+	DefaultShellMaxArgs = 16
+	// ShellPathArg 参数规则中表示工作目录中路径的占位符
+	ShellPathArg = "{path}"
+)
+
+// ErrCommandDenied 命令或参数不在白名单中，命令没有执行
+var ErrCommandDenied = errors.New("command denied")
+
+// ShellCommand 允许执行的命令
+type ShellCommand struct {
+	Name        string
+	Description string
+	// Args 允许的参数规则
+	Args            []string
+	MaxArgs         int
+	RequireApproval bool
+
+	path     string
+	timeout  time.Duration
+	patterns []*regexp.Regexp
+	// allowPath 参数规则中包含 {path}
+	allowPath bool
+}
+
+// ShellRunner 只执行白名单中的命令，逐个校验参数，不经过 shell 解释，
+// 子进程的工作目录固定为 work_dir，路径参数不能越出该目录，环境变量不继承服务进程
+type ShellRunner struct {
+	commands  map[string]*ShellCommand
+	root      string
+	maxOutput int
+	network   bool
+	env       []string
+}
+
+// NewShellRunner 根据配置创建执行器，本机找不到的命令返回错误
+func NewShellRunner(cfg *config.ShellConfig) (*ShellRunner, error) {
+	if cfg.WorkDir == "" {
+		return nil, errors.New("shell work_dir cannot be empty")
+	}
+	root, err := filepath.Abs(cfg.WorkDir)
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	r := &ShellRunner{
+		commands:  make(map[string]*ShellCommand),
+		root:      root,
+		maxOutput: cfg.MaxOutputBytes,
+		network:   cfg.Network,
+	}
+	if r.maxOutput <= 0 {
+		r.maxOutput = DefaultMaxOutputBytes
+	}
+	if !r.network {
+		if err := probeNetworkIsolation(); err != nil {
+			return nil, err
+		}
+	}
+	r.env = []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=" + root,
+		"LANG=C.UTF-8",
+	}
+	for _, kv := range cfg.Env {
+		if !strings.Contains(kv, "=") {
+			return nil, fmt.Errorf("invalid shell env %q, expected KEY=VALUE", kv)
+		}
+		r.env = append(r.env, kv)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultShellTimeout
+	}
+	for _, c := range cfg.Commands {
+		if c.Name == "" || strings.ContainsAny(c.Name, `/\ `) {
+			return nil, fmt.Errorf("invalid shell command name %q", c.Name)
+		}
+		if _, ok := r.commands[c.Name]; ok {
+			return nil, fmt.Errorf("duplicate shell command %q", c.Name)
+		}
+		cmd := &ShellCommand{
+			Name:            c.Name,
+			Description:     c.Description,
+			Args:            c.Args,
+			MaxArgs:         c.MaxArgs,
+			RequireApproval: c.RequireApproval,
+			path:            c.Path,
+			timeout:         c.Timeout,
+		}
+		if cmd.MaxArgs <= 0 {
+			cmd.MaxArgs = DefaultShellMaxArgs
+		}
+		if cmd.timeout <= 0 {
+			cmd.timeout = timeout
+		}
+		if cmd.path == "" {
+			cmd.path, err = exec.LookPath(c.Name)
+			if err != nil {
+				return nil, fmt.Errorf("shell command %q not found: %w", c.Name, err)
+			}
+		}
+		for _, rule := range c.Args {
+			if rule == ShellPathArg {
+				cmd.allowPath = true
+				continue
+			}
+			re, err := regexp.Compile(`^(?:` + rule + `)$`)
+			if err != nil {
+				return nil, fmt.Errorf("invalid arg pattern %q for shell command %q: %w", rule, c.Name, err)
+			}
+			cmd.patterns = append(cmd.patterns, re)
+		}
+		r.commands[c.Name] = cmd
+	}
+	if len(r.commands) == 0 {
+		return nil, errors.New("no shell commands configured")
+	}
+	return r, nil
+}
+
+// Commands 允许执行的命令，按名称排序
+func (r *ShellRunner) Commands() []*ShellCommand {
+	commands := make([]*ShellCommand, 0, len(r.commands))
+	for _, c := range r.commands {
+		commands = append(commands, c)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+// WorkDir 命令的工作目录
+func (r *ShellRunner) WorkDir() string {
+	return r.root
+}
+
+// Check 校验命令和参数，不允许时返回 ErrCommandDenied
+func (r *ShellRunner) Check(name string, args []string) (*ShellCommand, error) {
+	cmd, ok := r.commands[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not an allowed command", ErrCommandDenied, name)
+	}
+	if len(args) > cmd.MaxArgs {
+		return nil, fmt.Errorf("%w: %s accepts at most %d args", ErrCommandDenied, name, cmd.MaxArgs)
+	}
+	for _, arg := range args {
+		if err := r.checkArg(cmd, arg); err != nil {
+			return nil, fmt.Errorf("%w: %s arg %q: %v", ErrCommandDenied, name, arg, err)
+		}
+	}
+	return cmd, nil
+}
+
+// checkArg 参数需要匹配一条规则；绝对路径、包含 .. 或在工作目录中存在的参数无论匹配哪条规则都必须位于工作目录中
+func (r *ShellRunner) checkArg(cmd *ShellCommand, arg string) error {
+	if strings.ContainsRune(arg, 0) {
+		return errors.New("contains NUL")
+	}
+	matched := false
+	for _, re := range cmd.patterns {
+		if re.MatchString(arg) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		if !cmd.allowPath || arg == "" || strings.HasPrefix(arg, "-") {
+			return errors.New("does not match any allowed pattern")
+		}
+		return r.checkPath(arg)
+	}
+	if !strings.HasPrefix(arg, "-") {
+		return r.checkValue(arg)
+	}
+	// --file=/etc/passwd 这类选项检查等号后的值
+	if _, v, ok := strings.Cut(arg, "="); ok {
+		if err := r.checkValue(v); err != nil {
+			return err
+		}
+	}
+	// -f/etc/shadow 这类短选项的值紧跟在选项字母后，无法确定哪个字母带值，检查选项字母后的每个后缀
+	if !strings.HasPrefix(arg, "--") {
+		for i := 2; i < len(arg); i++ {
+			if err := r.checkValue(arg[i:]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkValue 参数值是绝对路径、含有 .. 或是工作目录中已存在的路径时检查它的位置
+func (r *ShellRunner) checkValue(value string) error {
+	if filepath.IsAbs(value) || hasDotDot(value) {
+		return r.checkPath(value)
+	}
+	// 工作目录中已存在的相对路径可能是指向外部的符号链接
+	if value != "" {
+		if _, err := os.Lstat(filepath.Join(r.root, value)); err == nil {
+			return r.checkPath(value)
+		}
+	}
+	return nil
+}
+
+// checkPath 路径解析符号链接后必须位于工作目录中
+func (r *ShellRunner) checkPath(arg string) error {
+	path := arg
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.root, path)
+	}
+	path, err := workspace.EvalExisting(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	if path != r.root && !strings.HasPrefix(path, r.root+string(filepath.Separator)) {
+		return errors.New("path escapes work dir")
+	}
+	return nil
+}
+
+// Run 校验后直接执行命令，参数原样传给进程，通配符、管道和重定向不会被解释
+func (r *ShellRunner) Run(ctx context.Context, name string, args []string) (*Result, error) {
+	cmd, err := r.Check(name, args)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cmd.timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, cmd.path, args...)
+	c.Dir = r.root
+	c.Env = r.env
+	stdout := newLimitedBuffer(r.maxOutput)
+	stderr := newLimitedBuffer(r.maxOutput)
+	c.Stdout = stdout
+	c.Stderr = stderr
+	isolate(c, r.network)
+	c.WaitDelay = time.Second
+
+	start := time.Now()
+	err = c.Run()
+	// 终止进程组中残留的后台进程
+	killGroup(c)
+	result := &Result{
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		ExitCode:        c.ProcessState.ExitCode(),
+		TimedOut:        errors.Is(ctx.Err(), context.DeadlineExceeded),
+		OutputTruncated: stdout.truncated || stderr.truncated,
+		Duration:        time.Since(start),
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) && !result.TimedOut {
+			return nil, fmt.Errorf("start shell command failed: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// probeNetworkIsolation 在新的网络命名空间中执行一次空命令，确认当前环境允许创建用户命名空间，
+// Docker 默认的 seccomp 配置会禁止
+func probeNetworkIsolation() error {
+	if !namespacesSupported() {
+		return errors.New("network isolation is not supported on this platform, set network to true to run without it")
+	}
+	cmd := exec.CommandContext(context.Background(), "/bin/sh", "-c", "exit 0")
+	isolate(cmd, false)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("network isolation is not usable here, it needs user and network namespaces; "+
+			"set network to true to run without it: %w %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func hasDotDot(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandLine 把命令和参数拼成便于人工审阅的形式，用于审批和日志
+func CommandLine(name string, args []string) string {
+	parts := []string{name}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+			arg = shellQuote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
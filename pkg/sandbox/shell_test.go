@@ -0,0 +1,105 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"MoonAgent/pkg/config"
+)
+
+// newTestShell 在临时工作目录中创建只允许 cat 的执行器，目录中有普通文件、子目录和指向内外的符号链接
+func newTestShell(t *testing.T) *ShellRunner {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc", filepath.Join(dir, "out")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(dir, "in")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewShellRunner(&config.ShellConfig{
+		WorkDir: dir,
+		// 不检查网络隔离，只测试参数校验
+		Network: true,
+		Commands: []config.ShellCommandConfig{{
+			Name:    "cat",
+			Args:    []string{ShellPathArg, "-n", "-f.*", "--file=.*"},
+			MaxArgs: 3,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewShellRunner: %v", err)
+	}
+	return r
+}
+
+func TestShellCheck(t *testing.T) {
+	r := newTestShell(t)
+	tests := []struct {
+		name string
+		cmd  string
+		args []string
+		ok   bool
+	}{
+		{"relative path", "cat", []string{"a.txt"}, true},
+		{"dot dot inside", "cat", []string{"sub/../a.txt"}, true},
+		{"absolute inside", "cat", []string{filepath.Join(r.WorkDir(), "a.txt")}, true},
+		{"new file", "cat", []string{"sub/new.txt"}, true},
+		{"allowed flag", "cat", []string{"-n", "a.txt"}, true},
+		{"no args", "cat", nil, true},
+		{"absolute outside", "cat", []string{"/etc/passwd"}, false},
+		{"dot dot outside", "cat", []string{"../x"}, false},
+		{"dot dot through sub", "cat", []string{"sub/../../x"}, false},
+		{"long option value outside", "cat", []string{"--file=/etc/x"}, false},
+		{"long option dot dot", "cat", []string{"--file=../x"}, false},
+		{"long option inside", "cat", []string{"--file=a.txt"}, true},
+		{"short option value outside", "cat", []string{"-f/etc/x"}, false},
+		{"short option dot dot", "cat", []string{"-f../x"}, false},
+		{"short option inside", "cat", []string{"-fa.txt"}, true},
+		{"symlink leaving work dir", "cat", []string{"out"}, false},
+		{"path under outside symlink", "cat", []string{"out/passwd"}, false},
+		{"option through outside symlink", "cat", []string{"--file=out/passwd"}, false},
+		{"symlink inside", "cat", []string{"in"}, true},
+		{"NUL", "cat", []string{"a.txt\x00/etc/passwd"}, false},
+		{"unknown flag", "cat", []string{"-x"}, false},
+		{"empty arg", "cat", []string{""}, false},
+		{"too many args", "cat", []string{"a.txt", "a.txt", "a.txt", "a.txt"}, false},
+		{"unknown command", "rm", []string{"a.txt"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Check(tt.cmd, tt.args)
+			if tt.ok && err != nil {
+				t.Errorf("Check(%s %q) = %v, want allowed", tt.cmd, tt.args, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrCommandDenied) {
+				t.Errorf("Check(%s %q) = %v, want ErrCommandDenied", tt.cmd, tt.args, err)
+			}
+		})
+	}
+}
+
+func TestShellRun(t *testing.T) {
+	r := newTestShell(t)
+	res, err := r.Run(context.Background(), "cat", []string{"-n", "a.txt"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, "hello") {
+		t.Errorf("result = %+v", res)
+	}
+	// 被拒绝的命令不会执行
+	if _, err := r.Run(context.Background(), "cat", []string{"/etc/passwd"}); !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("denied Run err = %v", err)
+	}
+}
@@ -5,12 +5,33 @@ import (
 	"strconv"
 
 	"github.com/cloudwego/eino-ext/components/document/transformer/splitter/recursive"
-	"github.com/cloudwego/eino-ext/components/embedding/ark"
 	"github.com/cloudwego/eino/schema"
 	"go.uber.org/zap"
 )
 
-func SplitDocs(ctx context.Context, embedder *ark.Embedder, docs []*schema.Document) ([]*schema.Document, error) {
+// MetadataKeyDocumentID 切分后的片段记录原始文档ID的元数据键
+const MetadataKeyDocumentID = "document_id"
+
+// Config 切分配置
+type Config struct {
+	// 目标片段大小
+	ChunkSize int
+	// 片段重叠大小
+	OverlapSize int
+}
+
+// DefaultConfig 默认切分配置
+func DefaultConfig() *Config {
+	return &Config{
+		ChunkSize:   400,
+		OverlapSize: 10,
+	}
+}
+
+func SplitDocs(ctx context.Context, docs []*schema.Document, cfg *Config) ([]*schema.Document, error) {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
 	// splitter, err := semantic.NewSplitter(ctx, &semantic.Config{
 	// 	Embedding:    embedder,
 	// 	BufferSize:   2,
@@ -18,24 +39,31 @@ func SplitDocs(ctx context.Context, embedder *ark.Embedder, docs []*schema.Docum
 	// 	Percentile:   0.9,
 	// })
 	splitter, err := recursive.NewSplitter(ctx, &recursive.Config{
-    ChunkSize:    400,           // 必需：目标片段大小
-    OverlapSize:  10,            // 可选：片段重叠大小
-    Separators:   []string{"\n", ".", "?", "!"}, // 可选：分隔符列表
-    LenFunc:      nil,            // 可选：自定义长度计算函数
-    KeepType:     recursive.KeepTypeNone, // 可选：分隔符保留策略
-})
+		ChunkSize:   cfg.ChunkSize,                 // 必需：目标片段大小
+		OverlapSize: cfg.OverlapSize,               // 可选：片段重叠大小
+		Separators:  []string{"\n", ".", "?", "!"}, // 可选：分隔符列表
+		LenFunc:     nil,                           // 可选：自定义长度计算函数
+		KeepType:    recursive.KeepTypeNone,        // 可选：分隔符保留策略
+		IDGenerator: func(ctx context.Context, originalID string, splitIndex int) string {
+			return originalID + "_" + strconv.Itoa(splitIndex)
+		},
+	})
 	if err != nil {
 		zap.S().Error("Failed to create splitter: %v", zap.String("error", err.Error()))
 		return nil, err
 	}
+	// 记录原始文档ID，片段会继承元数据
+	for _, doc := range docs {
+		if doc.MetaData == nil {
+			doc.MetaData = make(map[string]any)
+		}
+		doc.MetaData[MetadataKeyDocumentID] = doc.ID
+	}
 	// 执行分割
 	results, err := splitter.Transform(ctx, docs)
 	if err != nil {
 		zap.S().Error("Failed to transform: %v", zap.String("error", err.Error()))
 		return nil, err
 	}
-	for i, result := range results {
-		result.ID = docs[0].ID + "_" + strconv.Itoa(i)
-	}
 	return results, nil
 }
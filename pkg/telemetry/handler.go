@@ -0,0 +1,243 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// span 属性
+const (
+	AttrComponent = attribute.Key("eino.component")
+	AttrType      = attribute.Key("eino.type")
+	AttrName      = attribute.Key("eino.name")
+
+	AttrModel            = attribute.Key("gen_ai.request.model")
+	AttrModelMessages    = attribute.Key("gen_ai.request.messages")
+	AttrModelTools       = attribute.Key("gen_ai.request.tools")
+	AttrInputTokens      = attribute.Key("gen_ai.usage.input_tokens")
+	AttrOutputTokens     = attribute.Key("gen_ai.usage.output_tokens")
+	AttrTotalTokens      = attribute.Key("gen_ai.usage.total_tokens")
+	AttrModelToolCalls   = attribute.Key("gen_ai.response.tool_calls")
+	AttrRetrieverQuery   = attribute.Key("retriever.query")
+	AttrRetrieverTopK    = attribute.Key("retriever.top_k")
+	AttrRetrieverScore   = attribute.Key("retriever.score_threshold")
+	AttrRetrieverDocs    = attribute.Key("retriever.documents")
+	AttrToolName         = attribute.Key("tool.name")
+	AttrToolArgsLength   = attribute.Key("tool.arguments_length")
+	AttrToolResultLength = attribute.Key("tool.response_length")
+	AttrEmbeddingTexts   = attribute.Key("embedding.texts")
+)
+
+type spanKey struct{}
+
+// spanEntry 记录 span 对应的组件，结束时只结束自己开启的 span
+type spanEntry struct {
+	span trace.Span
+	info *callbacks.RunInfo
+}
+
+// NewCallbackHandler 创建 eino 回调，为每次组件运行生成一个 span，嵌套的组件成为子 span
+func NewCallbackHandler(tracer trace.Tracer) callbacks.Handler {
+	h := &callbackHandler{tracer: tracer}
+	return callbacks.NewHandlerBuilder().
+		OnStartFn(h.onStart).
+		OnEndFn(h.onEnd).
+		OnErrorFn(h.onError).
+		OnStartWithStreamInputFn(h.onStartWithStreamInput).
+		OnEndWithStreamOutputFn(h.onEndWithStreamOutput).
+		Build()
+}
+
+type callbackHandler struct {
+	tracer trace.Tracer
+}
+
+func (h *callbackHandler) onStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	ctx, span := h.start(ctx, info)
+	span.SetAttributes(inputAttributes(info, input)...)
+	return ctx
+}
+
+func (h *callbackHandler) onStartWithStreamInput(ctx context.Context, info *callbacks.RunInfo, input *schema.StreamReader[callbacks.CallbackInput]) context.Context {
+	input.Close()
+	ctx, _ = h.start(ctx, info)
+	return ctx
+}
+
+func (h *callbackHandler) onEnd(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+	span := spanFromContext(ctx, info)
+	if span == nil {
+		return ctx
+	}
+	span.SetAttributes(outputAttributes(info, output)...)
+	span.End()
+	return ctx
+}
+
+func (h *callbackHandler) onError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+	span := spanFromContext(ctx, info)
+	if span == nil {
+		return ctx
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.End()
+	return ctx
+}
+
+// onEndWithStreamOutput 流式输出在后台读完后结束 span，模型的用量在最后的数据块中
+func (h *callbackHandler) onEndWithStreamOutput(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+	span := spanFromContext(ctx, info)
+	if span == nil {
+		output.Close()
+		return ctx
+	}
+	go func() {
+		defer output.Close()
+		defer span.End()
+
+		var attrs []attribute.KeyValue
+		for {
+			chunk, err := output.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return
+			}
+			if info.Component == components.ComponentOfChatModel {
+				if out := model.ConvCallbackOutput(chunk); out != nil && out.TokenUsage != nil {
+					attrs = usageAttributes(out.TokenUsage)
+				}
+			}
+		}
+		span.SetAttributes(attrs...)
+	}()
+	return ctx
+}
+
+func (h *callbackHandler) start(ctx context.Context, info *callbacks.RunInfo) (context.Context, trace.Span) {
+	name := spanName(info)
+	ctx, span := h.tracer.Start(ctx, name, trace.WithAttributes(
+		AttrComponent.String(string(info.Component)),
+		AttrType.String(info.Type),
+		AttrName.String(info.Name),
+	))
+	return context.WithValue(ctx, spanKey{}, &spanEntry{span: span, info: info}), span
+}
+
+func spanFromContext(ctx context.Context, info *callbacks.RunInfo) trace.Span {
+	entry, ok := ctx.Value(spanKey{}).(*spanEntry)
+	if !ok || entry.info != info {
+		return nil
+	}
+	return entry.span
+}
+
+// spanName 组件类别加名称，例如 ChatModel ChatModel12、Tool knowledge_search
+func spanName(info *callbacks.RunInfo) string {
+	name := info.Name
+	if name == "" {
+		name = info.Type
+	}
+	if info.Component == "" {
+		return name
+	}
+	if name == "" {
+		return string(info.Component)
+	}
+	return string(info.Component) + " " + name
+}
+
+func inputAttributes(info *callbacks.RunInfo, input callbacks.CallbackInput) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	switch info.Component {
+	case components.ComponentOfChatModel:
+		in := model.ConvCallbackInput(input)
+		if in == nil {
+			return nil
+		}
+		attrs = append(attrs, AttrModelMessages.Int(len(in.Messages)), AttrModelTools.Int(len(in.Tools)))
+		if in.Config != nil && in.Config.Model != "" {
+			attrs = append(attrs, AttrModel.String(in.Config.Model))
+		}
+	case components.ComponentOfRetriever:
+		in := retriever.ConvCallbackInput(input)
+		if in == nil {
+			return nil
+		}
+		attrs = append(attrs, AttrRetrieverQuery.String(in.Query))
+		if in.TopK > 0 {
+			attrs = append(attrs, AttrRetrieverTopK.Int(in.TopK))
+		}
+		if in.ScoreThreshold != nil {
+			attrs = append(attrs, AttrRetrieverScore.Float64(*in.ScoreThreshold))
+		}
+	case components.ComponentOfTool:
+		attrs = append(attrs, AttrToolName.String(info.Name))
+		if in := tool.ConvCallbackInput(input); in != nil {
+			attrs = append(attrs, AttrToolArgsLength.Int(len(in.ArgumentsInJSON)))
+		}
+	case components.ComponentOfEmbedding:
+		if in := embedding.ConvCallbackInput(input); in != nil {
+			attrs = append(attrs, AttrEmbeddingTexts.Int(len(in.Texts)))
+		}
+	}
+	return attrs
+}
+
+func outputAttributes(info *callbacks.RunInfo, output callbacks.CallbackOutput) []attribute.KeyValue {
+	switch info.Component {
+	case components.ComponentOfChatModel:
+		out := model.ConvCallbackOutput(output)
+		if out == nil {
+			return nil
+		}
+		attrs := usageAttributes(out.TokenUsage)
+		if out.Message != nil {
+			attrs = append(attrs, AttrModelToolCalls.Int(len(out.Message.ToolCalls)))
+		}
+		return attrs
+	case components.ComponentOfRetriever:
+		if out := retriever.ConvCallbackOutput(output); out != nil {
+			return []attribute.KeyValue{AttrRetrieverDocs.Int(len(out.Docs))}
+		}
+	case components.ComponentOfTool:
+		if out := tool.ConvCallbackOutput(output); out != nil {
+			return []attribute.KeyValue{AttrToolResultLength.Int(len(out.Response))}
+		}
+	case components.ComponentOfEmbedding:
+		if out := embedding.ConvCallbackOutput(output); out != nil && out.TokenUsage != nil {
+			return []attribute.KeyValue{
+				AttrInputTokens.Int(out.TokenUsage.PromptTokens),
+				AttrTotalTokens.Int(out.TokenUsage.TotalTokens),
+			}
+		}
+	}
+	return nil
+}
+
+func usageAttributes(usage *model.TokenUsage) []attribute.KeyValue {
+	if usage == nil {
+		return nil
+	}
+	return []attribute.KeyValue{
+		AttrInputTokens.Int(usage.PromptTokens),
+		AttrOutputTokens.Int(usage.CompletionTokens),
+		AttrTotalTokens.Int(usage.TotalTokens),
+	}
+}
@@ -0,0 +1,167 @@
+package telemetry
+
+import (
+	"MoonAgent/pkg/config"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// 导出方式
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// OTLP 协议
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+const (
+	defaultServiceName  = "MoonAgent"
+	instrumentationName = "MoonAgent/pkg/telemetry"
+	shutdownTimeout     = 5 * time.Second
+)
+
+// Provider 链路追踪，为流水线节点、模型、检索和工具调用生成 span
+type Provider struct {
+	tp      *sdktrace.TracerProvider
+	tracer  trace.Tracer
+	handler callbacks.Handler
+}
+
+// ProvideTelemetry 根据配置创建链路追踪并设置为全局 TracerProvider，未启用时返回nil
+func ProvideTelemetry(cfg *config.ServerConfig) (*Provider, func(), error) {
+	tc := cfg.TelemetryConfig
+	if !tc.Enable {
+		return nil, func() {}, nil
+	}
+	exporter, err := newExporter(context.Background(), &tc)
+	if err != nil {
+		zap.S().Error("Failed to create trace exporter", zap.String("error", err.Error()))
+		return nil, nil, err
+	}
+	p, err := NewProvider(&tc, sdktrace.NewBatchSpanProcessor(exporter))
+	if err != nil {
+		return nil, nil, err
+	}
+	otel.SetTracerProvider(p.tp)
+	zap.S().Info("Tracing enabled", zap.String("exporter", tc.Exporter), zap.String("endpoint", tc.Endpoint))
+
+	cleanup := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := p.Shutdown(ctx); err != nil {
+			zap.S().Warn("Failed to shutdown tracer provider", zap.String("error", err.Error()))
+		}
+	}
+	return p, cleanup, nil
+}
+
+// NewProvider 使用指定的 span 处理器创建链路追踪，测试时可以传入同步导出到内存导出器的处理器
+func NewProvider(cfg *config.TelemetryConfig, processor sdktrace.SpanProcessor) (*Provider, error) {
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	tracer := tp.Tracer(instrumentationName)
+	return &Provider{
+		tp:      tp,
+		tracer:  tracer,
+		handler: NewCallbackHandler(tracer),
+	}, nil
+}
+
+func newExporter(ctx context.Context, cfg *config.TelemetryConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP, "":
+		switch cfg.Protocol {
+		case ProtocolGRPC, "":
+			opts := []otlptracegrpc.Option{}
+			if cfg.Endpoint != "" {
+				opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+			}
+			if cfg.Insecure {
+				opts = append(opts, otlptracegrpc.WithInsecure())
+			}
+			return otlptracegrpc.New(ctx, opts...)
+		case ProtocolHTTP:
+			opts := []otlptracehttp.Option{}
+			if cfg.Endpoint != "" {
+				opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+			}
+			if cfg.Insecure {
+				opts = append(opts, otlptracehttp.WithInsecure())
+			}
+			return otlptracehttp.New(ctx, opts...)
+		default:
+			return nil, fmt.Errorf("unknown otlp protocol: %s", cfg.Protocol)
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", cfg.Exporter)
+	}
+}
+
+// Handler 返回生成 span 的 eino 回调，未启用时返回nil
+func (p *Provider) Handler() callbacks.Handler {
+	if p == nil {
+		return nil
+	}
+	return p.handler
+}
+
+// Tracer 返回用于手动埋点的 Tracer
+func (p *Provider) Tracer() trace.Tracer {
+	if p == nil {
+		return otel.Tracer(instrumentationName)
+	}
+	return p.tracer
+}
+
+// ForceFlush 立即导出已结束的 span
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	return p.tp.ForceFlush(ctx)
+}
+
+// Shutdown 导出剩余的 span 并关闭导出器
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	return p.tp.Shutdown(ctx)
+}
@@ -0,0 +1,204 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"MoonAgent/pkg/config"
+	"MoonAgent/pkg/localstore"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/embedding"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) EmbedStrings(ctx context.Context, texts []string, opts ...embedding.Option) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for range texts {
+		vectors = append(vectors, []float64{1, 0})
+	}
+	return vectors, nil
+}
+
+// fakeChatModel 自己触发回调，输出中带有用量，固定返回一次工具调用
+type fakeChatModel struct{}
+
+func (fakeChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	ctx = callbacks.OnStart(ctx, &model.CallbackInput{Messages: input, Config: &model.Config{Model: "fake-model"}})
+	msg := schema.AssistantMessage("", []schema.ToolCall{{
+		ID:       "call_1",
+		Function: schema.FunctionCall{Name: "echo", Arguments: `{"text":"hi"}`},
+	}})
+	callbacks.OnEnd(ctx, &model.CallbackOutput{
+		Message:    msg,
+		TokenUsage: &model.TokenUsage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17},
+	})
+	return msg, nil
+}
+
+func (fakeChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (fakeChatModel) IsCallbacksEnabled() bool {
+	return true
+}
+
+type echoTool struct{}
+
+func (echoTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "echo", Desc: "echo"}, nil
+}
+
+func (echoTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return argumentsInJSON, nil
+}
+
+func newTestProvider(t *testing.T) (*Provider, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	p, err := NewProvider(&config.TelemetryConfig{}, sdktrace.NewSimpleSpanProcessor(exporter))
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+	return p, exporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func attr(span *tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestCallbackHandlerSpans(t *testing.T) {
+	ctx := context.Background()
+	p, exporter := newTestProvider(t)
+
+	store, err := localstore.NewStore(ctx, &localstore.Config{Embedding: fakeEmbedder{}, TopK: 3})
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if _, err := store.Store(ctx, []*schema.Document{{ID: "1", Content: "doc"}}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	toolsNode, err := compose.NewToolNode(ctx, &compose.ToolsNodeConfig{Tools: []tool.BaseTool{echoTool{}}})
+	if err != nil {
+		t.Fatalf("NewToolNode: %v", err)
+	}
+
+	chain := compose.NewChain[string, []*schema.Message]()
+	chain.
+		AppendRetriever(store, compose.WithNodeName("retrieve")).
+		AppendLambda(compose.InvokableLambda(func(ctx context.Context, docs []*schema.Document) ([]*schema.Message, error) {
+			return []*schema.Message{schema.UserMessage(docs[0].Content)}, nil
+		}), compose.WithNodeName("prompt")).
+		AppendChatModel(fakeChatModel{}, compose.WithNodeName("model")).
+		AppendToolsNode(toolsNode, compose.WithNodeName("tools"))
+	runnable, err := chain.Compile(ctx, compose.WithGraphName("rag"))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if _, err := runnable.Invoke(ctx, "question", compose.WithCallbacks(p.Handler()),
+		compose.WithRetrieverOption(retriever.WithTopK(2))); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	names := make([]string, 0, len(spans))
+	for _, s := range spans {
+		names = append(names, s.Name)
+	}
+	t.Logf("spans: %s", strings.Join(names, ", "))
+
+	root := findSpan(spans, "Chain rag")
+	if root == nil {
+		t.Fatalf("graph span not found in %v", names)
+	}
+	for _, name := range []string{"Lambda prompt", "ChatModel model", "Retriever retrieve", "Tool echo"} {
+		span := findSpan(spans, name)
+		if span == nil {
+			t.Errorf("span %q not found in %v", name, names)
+			continue
+		}
+		if span.SpanContext.TraceID() != root.SpanContext.TraceID() || !span.Parent.IsValid() {
+			t.Errorf("span %q is not part of the graph trace", name)
+		}
+	}
+
+	if span := findSpan(spans, "Retriever retrieve"); span != nil {
+		if v, ok := attr(span, AttrRetrieverTopK); !ok || v.AsInt64() != 2 {
+			t.Errorf("retriever top_k = %v, want 2", v.Emit())
+		}
+		if v, ok := attr(span, AttrRetrieverQuery); !ok || v.AsString() != "question" {
+			t.Errorf("retriever query = %q", v.Emit())
+		}
+		if v, ok := attr(span, AttrRetrieverDocs); !ok || v.AsInt64() != 1 {
+			t.Errorf("retriever documents = %v, want 1", v.Emit())
+		}
+	}
+	if span := findSpan(spans, "ChatModel model"); span != nil {
+		want := map[attribute.Key]int64{AttrInputTokens: 12, AttrOutputTokens: 5, AttrTotalTokens: 17, AttrModelToolCalls: 1}
+		for key, n := range want {
+			if v, ok := attr(span, key); !ok || v.AsInt64() != n {
+				t.Errorf("%s = %v, want %d", key, v.Emit(), n)
+			}
+		}
+		if v, _ := attr(span, AttrModel); v.AsString() != "fake-model" {
+			t.Errorf("model = %q, want fake-model", v.Emit())
+		}
+	}
+	if span := findSpan(spans, "Tool echo"); span != nil {
+		if v, ok := attr(span, AttrToolArgsLength); !ok || v.AsInt64() != int64(len(`{"text":"hi"}`)) {
+			t.Errorf("tool arguments_length = %v", v.Emit())
+		}
+	}
+}
+
+func TestCallbackHandlerRecordsErrors(t *testing.T) {
+	ctx := context.Background()
+	p, exporter := newTestProvider(t)
+
+	chain := compose.NewChain[string, string]()
+	chain.AppendLambda(compose.InvokableLambda(func(ctx context.Context, in string) (string, error) {
+		return "", fmt.Errorf("boom")
+	}), compose.WithNodeName("fail"))
+	runnable, err := chain.Compile(ctx)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if _, err := runnable.Invoke(ctx, "x", compose.WithCallbacks(p.Handler())); err == nil {
+		t.Fatal("expected error")
+	}
+
+	span := findSpan(exporter.GetSpans(), "Lambda fail")
+	if span == nil {
+		t.Fatal("lambda span not found")
+	}
+	if span.Status.Code.String() != "Error" || !strings.Contains(span.Status.Description, "boom") {
+		t.Errorf("status = %v %q, want error", span.Status.Code, span.Status.Description)
+	}
+}
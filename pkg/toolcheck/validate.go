@@ -0,0 +1,108 @@
+package toolcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Issue 一处参数错误，Path 为出错字段的路径，顶层为空
+type Issue struct {
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError 工具参数不符合工具声明的参数定义
+type ValidationError struct {
+	Tool   string  `json:"tool"`
+	Issues []Issue `json:"issues"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		if issue.Path != "" {
+			parts = append(parts, issue.Path+": "+issue.Message)
+		} else {
+			parts = append(parts, issue.Message)
+		}
+	}
+	return fmt.Sprintf("invalid arguments for tool %s: %s", e.Tool, strings.Join(parts, "; "))
+}
+
+// Validate 按工具的参数定义校验模型给出的 JSON 参数，检查必填字段、类型和枚举值，不符合时返回 *ValidationError
+func Validate(info *schema.ToolInfo, argumentsInJSON string) error {
+	var args any
+	if strings.TrimSpace(argumentsInJSON) == "" {
+		args = map[string]any{}
+	} else if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+		return &ValidationError{Tool: info.Name, Issues: []Issue{{Message: "arguments are not valid JSON: " + err.Error()}}}
+	}
+	if _, ok := args.(map[string]any); !ok {
+		return &ValidationError{Tool: info.Name, Issues: []Issue{{Message: "arguments must be a JSON object"}}}
+	}
+	// 模型常把未填写的可选参数写成 null，按未提供处理
+	dropNulls(args)
+	if info.ParamsOneOf == nil {
+		return nil
+	}
+
+	s, err := info.ParamsOneOf.ToOpenAPIV3()
+	if err != nil {
+		return fmt.Errorf("tool %s: invalid parameter schema: %w", info.Name, err)
+	}
+	if s == nil {
+		return nil
+	}
+	err = s.VisitJSON(args, openapi3.MultiErrors())
+	if err == nil {
+		return nil
+	}
+	issues := collectIssues(err, nil)
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Path < issues[j].Path
+	})
+	return &ValidationError{Tool: info.Name, Issues: issues}
+}
+
+// collectIssues 展开 kin-openapi 返回的嵌套错误
+func collectIssues(err error, issues []Issue) []Issue {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		for _, e := range multi {
+			issues = collectIssues(e, issues)
+		}
+		return issues
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return append(issues, Issue{
+			Path:    strings.Join(schemaErr.JSONPointer(), "."),
+			Message: schemaErr.Reason,
+		})
+	}
+	return append(issues, Issue{Message: err.Error()})
+}
+
+// dropNulls 删除对象中值为 null 的字段
+func dropNulls(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if child == nil {
+				delete(v, k)
+				continue
+			}
+			dropNulls(child)
+		}
+	case []any:
+		for _, child := range v {
+			dropNulls(child)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package toolcheck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+var searchInfo = &schema.ToolInfo{
+	Name: "search",
+	ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+		"query": {Type: schema.String, Required: true},
+		"top_k": {Type: schema.Integer},
+		"mode":  {Type: schema.String, Enum: []string{"fast", "deep"}},
+		"filter": {Type: schema.Object, SubParams: map[string]*schema.ParameterInfo{
+			"tag": {Type: schema.String, Required: true},
+		}},
+	}),
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		// paths 期望出错的字段路径，按路径排序；为 nil 时期望通过
+		paths []string
+		// message 期望错误信息包含的内容
+		message string
+	}{
+		{name: "valid", args: `{"query":"q","top_k":3,"mode":"deep","filter":{"tag":"x"}}`},
+		{name: "null optional", args: `{"query":"q","top_k":null,"mode":null}`},
+		{name: "null nested", args: `{"query":"q","filter":{"tag":"x","extra":null}}`},
+		{name: "missing required", args: `{"top_k":3}`, paths: []string{"query"}, message: "missing"},
+		{name: "null required", args: `{"query":null}`, paths: []string{"query"}, message: "missing"},
+		{name: "empty arguments", args: ` `, paths: []string{"query"}, message: "missing"},
+		{name: "wrong type", args: `{"query":"q","top_k":"3"}`, paths: []string{"top_k"}},
+		{name: "enum", args: `{"query":"q","mode":"slow"}`, paths: []string{"mode"}},
+		{name: "nested required", args: `{"query":"q","filter":{}}`, paths: []string{"filter.tag"}, message: "missing"},
+		{name: "multiple issues", args: `{"top_k":"3","mode":"slow"}`, paths: []string{"mode", "query", "top_k"}},
+		{name: "array", args: `[1,2]`, paths: []string{""}, message: "JSON object"},
+		{name: "string", args: `"q"`, paths: []string{""}, message: "JSON object"},
+		{name: "invalid JSON", args: `{"query":`, paths: []string{""}, message: "not valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(searchInfo, tt.args)
+			if tt.paths == nil {
+				if err != nil {
+					t.Fatalf("Validate(%s) = %v, want nil", tt.args, err)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Validate(%s) = %v, want *ValidationError", tt.args, err)
+			}
+			if invalid.Tool != "search" {
+				t.Errorf("Tool = %q", invalid.Tool)
+			}
+			paths := make([]string, 0, len(invalid.Issues))
+			for _, issue := range invalid.Issues {
+				paths = append(paths, issue.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.paths, ",") {
+				t.Errorf("issue paths = %q, want %q (issues %+v)", paths, tt.paths, invalid.Issues)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q does not mention %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestValidateWithoutParams(t *testing.T) {
+	info := &schema.ToolInfo{Name: "now"}
+	if err := Validate(info, `{"anything":1}`); err != nil {
+		t.Errorf("Validate without params = %v", err)
+	}
+	// 没有参数定义时仍然要求对象
+	if err := Validate(info, `[]`); err == nil {
+		t.Error("non-object arguments accepted")
+	}
+}
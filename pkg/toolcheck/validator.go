@@ -0,0 +1,221 @@
+package toolcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"MoonAgent/pkg/config"
+	"MoonAgent/pkg/toolwrap"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxRetries 单次运行中同一工具允许的参数错误次数
+const DefaultMaxRetries = 3
+
+// ErrTooManyInvalidCalls 参数错误次数超过上限，运行随之中止
+var ErrTooManyInvalidCalls = errors.New("too many invalid tool calls")
+
+// Stats 参数校验统计
+type Stats struct {
+	// 校验过的调用次数
+	Calls int64 `json:"calls"`
+	// 参数不合法的调用次数
+	Invalid int64 `json:"invalid"`
+	// 因超过重试上限而中止的运行次数
+	Aborted int64 `json:"aborted"`
+	// 按工具统计的参数错误，只包含出过错的工具
+	Tools map[string]ToolStats `json:"tools"`
+}
+
+type ToolStats struct {
+	Invalid int64 `json:"invalid"`
+	Aborted int64 `json:"aborted"`
+}
+
+// Validator 在工具执行前校验参数，参数错误作为工具结果返回给模型修正，同一次运行中错误过多时中止
+type Validator struct {
+	maxRetries int
+
+	calls   atomic.Int64
+	invalid atomic.Int64
+	aborted atomic.Int64
+	mu      sync.Mutex
+	tools   map[string]*ToolStats
+}
+
+func ProvideValidator(cfg *config.ServerConfig) *Validator {
+	return NewValidator(cfg.ToolsConfig.Validation.MaxRetries)
+}
+
+// NewValidator maxRetries 不大于 0 时使用默认值
+func NewValidator(maxRetries int) *Validator {
+	if maxRetries <= 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	return &Validator{maxRetries: maxRetries, tools: make(map[string]*ToolStats)}
+}
+
+// Check 校验参数，参数合法时 feedback 为空；不合法时 feedback 为交给模型的结构化错误，
+// 超过重试上限时返回 ErrTooManyInvalidCalls。重试次数按 WithBudget 挂载的计数器统计，没有计数器时不限制
+func (v *Validator) Check(ctx context.Context, info *schema.ToolInfo, argumentsInJSON string) (feedback string, err error) {
+	if v != nil {
+		v.calls.Add(1)
+	}
+	err = Validate(info, argumentsInJSON)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		return "", err
+	}
+
+	maxRetries := DefaultMaxRetries
+	if v != nil {
+		maxRetries = v.maxRetries
+	}
+	attempts := budgetFromContext(ctx).add(info.Name)
+	zap.L().Warn("Invalid tool arguments",
+		zap.String("tool", info.Name),
+		zap.String("arguments", argumentsInJSON),
+		zap.Int("attempt", attempts),
+		zap.Error(invalid))
+	if attempts > maxRetries {
+		v.record(info.Name, true)
+		return "", fmt.Errorf("%w: tool %s failed validation %d times: %w", ErrTooManyInvalidCalls, info.Name, attempts, invalid)
+	}
+	v.record(info.Name, false)
+	return feedbackOf(invalid, maxRetries-attempts), nil
+}
+
+func (v *Validator) record(tool string, aborted bool) {
+	if v == nil {
+		return
+	}
+	v.invalid.Add(1)
+	if aborted {
+		v.aborted.Add(1)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.tools[tool]
+	if !ok {
+		s = &ToolStats{}
+		v.tools[tool] = s
+	}
+	s.Invalid++
+	if aborted {
+		s.Aborted++
+	}
+}
+
+// Stats 返回校验统计
+func (v *Validator) Stats() Stats {
+	if v == nil {
+		return Stats{Tools: map[string]ToolStats{}}
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	tools := make(map[string]ToolStats, len(v.tools))
+	for name, s := range v.tools {
+		tools[name] = *s
+	}
+	return Stats{
+		Calls:   v.calls.Load(),
+		Invalid: v.invalid.Load(),
+		Aborted: v.aborted.Load(),
+		Tools:   tools,
+	}
+}
+
+// feedbackOf 返回给模型的结构化错误，模型根据 issues 修正参数后重试
+func feedbackOf(err *ValidationError, retriesLeft int) string {
+	data, _ := json.Marshal(struct {
+		Error       string  `json:"error"`
+		Tool        string  `json:"tool"`
+		Issues      []Issue `json:"issues"`
+		RetriesLeft int     `json:"retriesLeft"`
+		Hint        string  `json:"hint"`
+	}{
+		Error:       "invalid_arguments",
+		Tool:        err.Tool,
+		Issues:      err.Issues,
+		RetriesLeft: retriesLeft,
+		Hint:        "参数不符合工具的参数定义，工具没有执行。请按 issues 修正参数后重新调用",
+	})
+	return string(data)
+}
+
+// Wrap 为工具加上参数校验，非 InvokableTool 原样返回
+func (v *Validator) Wrap(t tool.BaseTool) tool.BaseTool {
+	it, ok := t.(tool.InvokableTool)
+	if !ok {
+		return t
+	}
+	if _, ok := it.(*validatedTool); ok {
+		return t
+	}
+	return &validatedTool{Wrapper: toolwrap.Wrapper{Tool: it}, validator: v}
+}
+
+// WrapAll 为所有工具加上参数校验
+func (v *Validator) WrapAll(tools []tool.BaseTool) []tool.BaseTool {
+	out := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		out = append(out, v.Wrap(t))
+	}
+	return out
+}
+
+type validatedTool struct {
+	toolwrap.Wrapper
+	validator *Validator
+}
+
+func (t *validatedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	info, err := t.Tool.Info(ctx)
+	if err != nil {
+		return "", err
+	}
+	feedback, err := t.validator.Check(ctx, info, argumentsInJSON)
+	if err != nil {
+		return "", err
+	}
+	if feedback != "" {
+		return feedback, nil
+	}
+	return t.Tool.InvokableRun(ctx, argumentsInJSON, opts...)
+}
+
+type budgetKey struct{}
+
+// budget 单次运行中每个工具的参数错误次数
+type budget struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// WithBudget 挂载新的参数错误计数器，每次智能体运行开始时调用，重试上限按运行计算
+func WithBudget(ctx context.Context) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &budget{counts: make(map[string]int)})
+}
+
+func budgetFromContext(ctx context.Context) *budget {
+	b, _ := ctx.Value(budgetKey{}).(*budget)
+	return b
+}
+
+// add 记录一次参数错误并返回累计次数，没有计数器时始终返回 1
+func (b *budget) add(tool string) int {
+	if b == nil {
+		return 1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.counts[tool]++
+	return b.counts[tool]
+}
@@ -0,0 +1,146 @@
+package toolcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+type feedback struct {
+	Error       string  `json:"error"`
+	Tool        string  `json:"tool"`
+	Issues      []Issue `json:"issues"`
+	RetriesLeft int     `json:"retriesLeft"`
+	Hint        string  `json:"hint"`
+}
+
+func parseFeedback(t *testing.T, s string) feedback {
+	t.Helper()
+	var f feedback
+	if err := json.Unmarshal([]byte(s), &f); err != nil {
+		t.Fatalf("feedback is not JSON: %q", s)
+	}
+	return f
+}
+
+func TestCheckFeedback(t *testing.T) {
+	v := NewValidator(3)
+	ctx := WithBudget(context.Background())
+	out, err := v.Check(ctx, searchInfo, `{"query":"q"}`)
+	if out != "" || err != nil {
+		t.Fatalf("valid call = %q, %v", out, err)
+	}
+
+	out, err = v.Check(ctx, searchInfo, `{"top_k":"3"}`)
+	if err != nil {
+		t.Fatalf("invalid call err = %v", err)
+	}
+	f := parseFeedback(t, out)
+	if f.Error != "invalid_arguments" || f.Tool != "search" || f.RetriesLeft != 2 || f.Hint == "" {
+		t.Errorf("feedback = %+v", f)
+	}
+	if len(f.Issues) != 2 || f.Issues[0].Path != "query" || f.Issues[1].Path != "top_k" {
+		t.Errorf("issues = %+v", f.Issues)
+	}
+}
+
+func TestCheckRetryBudget(t *testing.T) {
+	v := NewValidator(2)
+	other := &schema.ToolInfo{Name: "other", ParamsOneOf: searchInfo.ParamsOneOf}
+	ctx := WithBudget(context.Background())
+
+	// 前 maxRetries 次错误返回给模型修正，剩余次数递减
+	for want := 1; want >= 0; want-- {
+		out, err := v.Check(ctx, searchInfo, `{}`)
+		if err != nil {
+			t.Fatalf("attempt with %d left: %v", want, err)
+		}
+		if f := parseFeedback(t, out); f.RetriesLeft != want {
+			t.Errorf("retriesLeft = %d, want %d", f.RetriesLeft, want)
+		}
+	}
+	// 其他工具的次数单独计算
+	if _, err := v.Check(ctx, other, `{}`); err != nil {
+		t.Errorf("other tool aborted: %v", err)
+	}
+	// 超过上限后中止运行
+	_, err := v.Check(ctx, searchInfo, `{}`)
+	if !errors.Is(err, ErrTooManyInvalidCalls) {
+		t.Fatalf("err = %v, want ErrTooManyInvalidCalls", err)
+	}
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Errorf("abort error does not wrap the validation error: %v", err)
+	}
+
+	// 新的运行重新计数
+	if _, err := v.Check(WithBudget(context.Background()), searchInfo, `{}`); err != nil {
+		t.Errorf("new run aborted: %v", err)
+	}
+
+	stats := v.Stats()
+	if stats.Calls != 5 || stats.Invalid != 5 || stats.Aborted != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if s := stats.Tools["search"]; s.Invalid != 4 || s.Aborted != 1 {
+		t.Errorf("search stats = %+v", s)
+	}
+	if s := stats.Tools["other"]; s.Invalid != 1 || s.Aborted != 0 {
+		t.Errorf("other stats = %+v", s)
+	}
+}
+
+func TestCheckWithoutBudget(t *testing.T) {
+	// 没有挂载计数器时不限制重试，nil 校验器使用默认上限
+	for _, v := range []*Validator{NewValidator(1), nil} {
+		for i := 0; i < 5; i++ {
+			out, err := v.Check(context.Background(), searchInfo, `{}`)
+			if err != nil || out == "" {
+				t.Fatalf("attempt %d = %q, %v", i, out, err)
+			}
+		}
+	}
+}
+
+// fakeTool 记录是否被执行
+type fakeTool struct {
+	runs int
+}
+
+func (t *fakeTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return searchInfo, nil
+}
+
+func (t *fakeTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	t.runs++
+	return "ok", nil
+}
+
+func TestWrap(t *testing.T) {
+	v := NewValidator(1)
+	inner := &fakeTool{}
+	wrapped := v.Wrap(inner).(tool.InvokableTool)
+	if v.Wrap(wrapped) != wrapped {
+		t.Error("tool wrapped twice")
+	}
+	ctx := WithBudget(context.Background())
+
+	if out, err := wrapped.InvokableRun(ctx, `{"query":"q"}`); out != "ok" || err != nil {
+		t.Errorf("valid run = %q, %v", out, err)
+	}
+	out, err := wrapped.InvokableRun(ctx, `{"query":1}`)
+	if err != nil || parseFeedback(t, out).Error != "invalid_arguments" {
+		t.Errorf("invalid run = %q, %v", out, err)
+	}
+	if _, err := wrapped.InvokableRun(ctx, `{"query":1}`); !errors.Is(err, ErrTooManyInvalidCalls) {
+		t.Errorf("err = %v, want ErrTooManyInvalidCalls", err)
+	}
+	// 参数不合法时工具不执行
+	if inner.runs != 1 {
+		t.Errorf("inner tool ran %d times, want 1", inner.runs)
+	}
+}
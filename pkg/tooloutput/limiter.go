@@ -0,0 +1,160 @@
+package tooloutput
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"MoonAgent/pkg/config"
+	"MoonAgent/pkg/toolwrap"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+// 默认限制，按字符数计算
+const (
+	DefaultMaxChars          = 8000
+	DefaultSummaryInputChars = 30000
+	// ReadToolName 分页读取完整输出的工具名
+	ReadToolName = "read_tool_output"
+)
+
+const summarizePrompt = `你是工具输出的摘要器。智能体调用了一个工具，工具返回的内容过长，请提炼出与这次调用目的相关的信息。
+保留关键事实、数字、名称、链接和结论，去掉导航、广告、重复和无关内容。直接输出摘要，不要评价内容，不要编造原文没有的信息。`
+
+// Limiter 限制工具输出的长度，超过上限时截断或用模型生成摘要，完整输出保存在 Store 中供智能体分页读取
+type Limiter struct {
+	store    *Store
+	maxChars int
+	limits   map[string]int
+	// chatModel 生成摘要的模型，为 nil 时只截断
+	chatModel         model.BaseChatModel
+	summaryInputChars int
+}
+
+// NewLimiter chatModel 为 nil 或配置未开启摘要时只截断
+func NewLimiter(cfg *config.ToolOutputConfig, store *Store, chatModel model.BaseChatModel) *Limiter {
+	l := &Limiter{
+		store:             store,
+		maxChars:          cfg.MaxChars,
+		limits:            cfg.Limits,
+		summaryInputChars: cfg.SummaryInputChars,
+	}
+	if l.maxChars == 0 {
+		l.maxChars = DefaultMaxChars
+	}
+	if l.summaryInputChars <= 0 {
+		l.summaryInputChars = DefaultSummaryInputChars
+	}
+	if cfg.Summarize {
+		l.chatModel = chatModel
+	}
+	return l
+}
+
+// Enabled 是否限制了输出长度
+func (l *Limiter) Enabled() bool {
+	if l == nil {
+		return false
+	}
+	if l.maxChars > 0 {
+		return true
+	}
+	for _, limit := range l.limits {
+		if limit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxChars 默认的输出上限，小于 0 表示不限制
+func (l *Limiter) MaxChars() int {
+	return l.maxChars
+}
+
+// Limit 工具的输出上限，小于 0 表示不限制
+func (l *Limiter) Limit(tool string) int {
+	if limit, ok := l.limits[tool]; ok && limit != 0 {
+		return limit
+	}
+	return l.maxChars
+}
+
+// Process 输出超过工具的上限时保存完整输出，返回摘要或首尾截断后的内容
+func (l *Limiter) Process(ctx context.Context, tool, arguments, output string) string {
+	limit := l.Limit(tool)
+	total := utf8.RuneCountInString(output)
+	if limit < 0 || total <= limit {
+		return output
+	}
+	id := l.store.Put(tool, output)
+
+	if l.chatModel != nil {
+		summary, err := l.summarize(ctx, tool, arguments, output)
+		if err == nil && summary != "" {
+			return fmt.Sprintf("[输出过长（共 %d 个字符），以下为摘要。完整输出已保存为 %s，可调用 %s 分页读取原文]\n%s",
+				total, id, ReadToolName, truncate(summary, limit, ""))
+		}
+		zap.L().Warn("Summarize tool output failed, truncating", zap.String("tool", tool), zap.Error(err))
+	}
+	marker := fmt.Sprintf("\n\n[... 输出过长，已省略中间 %%d 个字符。完整输出共 %d 个字符，已保存为 %s，可调用 %s 按 offset 分页读取 ...]\n\n",
+		total, id, ReadToolName)
+	return truncate(output, limit, marker)
+}
+
+func (l *Limiter) summarize(ctx context.Context, tool, arguments, output string) (string, error) {
+	input := output
+	if utf8.RuneCountInString(input) > l.summaryInputChars {
+		input = string([]rune(input)[:l.summaryInputChars])
+	}
+	resp, err := l.chatModel.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(summarizePrompt),
+		schema.UserMessage(fmt.Sprintf("工具: %s\n调用参数: %s\n\n工具输出:\n%s", tool, arguments, input)),
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// truncate 保留开头约三分之二和结尾约三分之一，中间插入 marker，marker 中的 %d 替换为省略的字符数；marker 为空时只保留开头
+func truncate(s string, limit int, marker string) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	if marker == "" {
+		return string(runes[:limit]) + "..."
+	}
+	head := limit * 2 / 3
+	tail := limit - head
+	return string(runes[:head]) + fmt.Sprintf(marker, len(runes)-head-tail) + string(runes[len(runes)-tail:])
+}
+
+// Wrap 为工具加上输出长度限制，name 为配置中使用的工具名，非 InvokableTool 或未限制时原样返回
+func (l *Limiter) Wrap(name string, t tool.BaseTool) tool.BaseTool {
+	it, ok := t.(tool.InvokableTool)
+	if !ok || !l.Enabled() || l.Limit(name) < 0 {
+		return t
+	}
+	return &limitedTool{Wrapper: toolwrap.Wrapper{Tool: it}, limiter: l, name: name}
+}
+
+type limitedTool struct {
+	toolwrap.Wrapper
+	limiter *Limiter
+	name    string
+}
+
+func (t *limitedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	output, err := t.Tool.InvokableRun(ctx, argumentsInJSON, opts...)
+	if err != nil {
+		return "", err
+	}
+	return t.limiter.Process(ctx, t.name, argumentsInJSON, output), nil
+}
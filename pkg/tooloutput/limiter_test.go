@@ -0,0 +1,165 @@
+package tooloutput
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"MoonAgent/pkg/config"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		limit  int
+		marker string
+		want   string
+	}{
+		{"within limit", "abcdef", 6, "[%d]", "abcdef"},
+		{"head and tail", "abcdefghij", 6, "[%d]", "abcd[4]ij"},
+		{"odd limit", "abcdefghij", 7, "[%d]", "abcd[3]hij"},
+		{"runes", "一二三四五六七八九十", 3, "[%d]", "一二[7]十"},
+		{"head only", "abcdefghij", 4, "", "abcd..."},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.limit, tt.marker); got != tt.want {
+			t.Errorf("%s: truncate(%q, %d) = %q, want %q", tt.name, tt.s, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	l := NewLimiter(&config.ToolOutputConfig{Limits: map[string]int{"shell": 100, "code_exec": -1, "zero": 0}}, NewStore(0, 0), nil)
+	tests := map[string]int{
+		"shell":     100,
+		"code_exec": -1,
+		// 为 0 时使用默认上限
+		"zero":  DefaultMaxChars,
+		"other": DefaultMaxChars,
+	}
+	for name, want := range tests {
+		if got := l.Limit(name); got != want {
+			t.Errorf("Limit(%s) = %d, want %d", name, got, want)
+		}
+	}
+	if !l.Enabled() {
+		t.Error("limiter with default max chars not enabled")
+	}
+	if NewLimiter(&config.ToolOutputConfig{MaxChars: -1}, NewStore(0, 0), nil).Enabled() {
+		t.Error("unlimited limiter enabled")
+	}
+}
+
+var markerPattern = regexp.MustCompile(`已省略中间 (\d+) 个字符。完整输出共 (\d+) 个字符，已保存为 (out_[0-9a-f]+)`)
+
+func TestProcessTruncates(t *testing.T) {
+	store := NewStore(0, 0)
+	l := NewLimiter(&config.ToolOutputConfig{MaxChars: 30}, store, nil)
+	output := strings.Repeat("头", 20) + strings.Repeat("中", 60) + strings.Repeat("尾", 20)
+
+	got := l.Process(context.Background(), "web_fetch", `{}`, output)
+	if !strings.HasPrefix(got, strings.Repeat("头", 20)+"\n") || !strings.HasSuffix(got, "\n"+strings.Repeat("尾", 10)) {
+		t.Errorf("head or tail not kept: %q", got)
+	}
+	m := markerPattern.FindStringSubmatch(got)
+	if m == nil {
+		t.Fatalf("marker missing: %q", got)
+	}
+	if m[1] != "70" || m[2] != "100" {
+		t.Errorf("marker counts = %s omitted of %s, want 70 of 100", m[1], m[2])
+	}
+	a, ok := store.Get(m[3])
+	if !ok || a.Content != output || a.Tool != "web_fetch" {
+		t.Errorf("full output not stored under %s", m[3])
+	}
+}
+
+func TestProcessUnlimited(t *testing.T) {
+	output := strings.Repeat("x", 100)
+	for _, cfg := range []config.ToolOutputConfig{
+		{MaxChars: -1},
+		{MaxChars: 10, Limits: map[string]int{"web_fetch": -1}},
+		{MaxChars: 100},
+	} {
+		store := NewStore(0, 0)
+		l := NewLimiter(&cfg, store, nil)
+		if got := l.Process(context.Background(), "web_fetch", `{}`, output); got != output {
+			t.Errorf("config %+v changed the output: %q", cfg, got)
+		}
+		if len(store.items) != 0 {
+			t.Errorf("config %+v stored an artifact", cfg)
+		}
+	}
+}
+
+// fakeChatModel 返回固定的摘要或错误
+type fakeChatModel struct {
+	reply string
+	err   error
+}
+
+func (m *fakeChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return schema.AssistantMessage(m.reply, nil), nil
+}
+
+func (m *fakeChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestProcessSummarize(t *testing.T) {
+	output := strings.Repeat("x", 100)
+	cfg := &config.ToolOutputConfig{MaxChars: 20, Summarize: true}
+
+	l := NewLimiter(cfg, NewStore(0, 0), &fakeChatModel{reply: " 摘要内容 "})
+	got := l.Process(context.Background(), "web_fetch", `{}`, output)
+	if !strings.HasSuffix(got, "\n摘要内容") || !strings.Contains(got, "共 100 个字符") {
+		t.Errorf("summary result = %q", got)
+	}
+
+	// 摘要失败时退回截断
+	l = NewLimiter(cfg, NewStore(0, 0), &fakeChatModel{err: errors.New("boom")})
+	if got := l.Process(context.Background(), "web_fetch", `{}`, output); markerPattern.FindString(got) == "" {
+		t.Errorf("fallback result = %q", got)
+	}
+
+	// 未开启摘要时不调用模型
+	l = NewLimiter(&config.ToolOutputConfig{MaxChars: 20}, NewStore(0, 0), &fakeChatModel{reply: "摘要内容"})
+	if got := l.Process(context.Background(), "web_fetch", `{}`, output); strings.Contains(got, "摘要内容") {
+		t.Errorf("summarized without summarize enabled: %q", got)
+	}
+}
+
+// echoTool 返回固定的输出
+type echoTool struct {
+	output string
+}
+
+func (t *echoTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: "echo"}, nil
+}
+
+func (t *echoTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.output, nil
+}
+
+func TestWrap(t *testing.T) {
+	l := NewLimiter(&config.ToolOutputConfig{MaxChars: 10, Limits: map[string]int{"raw": -1}}, NewStore(0, 0), nil)
+	inner := &echoTool{output: strings.Repeat("x", 50)}
+	if l.Wrap("raw", inner) != tool.BaseTool(inner) {
+		t.Error("unlimited tool wrapped")
+	}
+	out, err := l.Wrap("echo", inner).(tool.InvokableTool).InvokableRun(context.Background(), `{}`)
+	if err != nil || markerPattern.FindString(out) == "" {
+		t.Errorf("wrapped run = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,87 @@
+package tooloutput
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"MoonAgent/pkg/config"
+)
+
+// 默认限制
+const (
+	DefaultMaxArtifacts = 100
+	DefaultArtifactTTL  = time.Hour
+)
+
+// Artifact 被截断或摘要的工具输出的完整内容
+type Artifact struct {
+	ID        string
+	Tool      string
+	Content   string
+	CreatedAt time.Time
+}
+
+// Store 在内存中保存完整的工具输出，超过数量上限时淘汰最早的，过期后不可读取
+type Store struct {
+	mu    sync.Mutex
+	items map[string]*Artifact
+	// order 按保存时间排列的ID
+	order []string
+	max   int
+	ttl   time.Duration
+}
+
+func ProvideStore(cfg *config.ServerConfig) *Store {
+	c := cfg.ToolsConfig.Output
+	return NewStore(c.MaxArtifacts, c.ArtifactTTL)
+}
+
+// NewStore max 和 ttl 不大于 0 时使用默认值
+func NewStore(max int, ttl time.Duration) *Store {
+	if max <= 0 {
+		max = DefaultMaxArtifacts
+	}
+	if ttl <= 0 {
+		ttl = DefaultArtifactTTL
+	}
+	return &Store{items: make(map[string]*Artifact), max: max, ttl: ttl}
+}
+
+// Put 保存工具输出并返回ID
+func (s *Store) Put(tool, content string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	a := &Artifact{ID: newID(), Tool: tool, Content: content, CreatedAt: now}
+	s.items[a.ID] = a
+	s.order = append(s.order, a.ID)
+
+	for len(s.order) > 0 {
+		oldest := s.items[s.order[0]]
+		if len(s.order) <= s.max && now.Sub(oldest.CreatedAt) < s.ttl {
+			break
+		}
+		delete(s.items, oldest.ID)
+		s.order = s.order[1:]
+	}
+	return a.ID
+}
+
+// Get 按ID读取，不存在或已过期时返回 false
+func (s *Store) Get(id string) (*Artifact, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.items[id]
+	if !ok || time.Since(a.CreatedAt) >= s.ttl {
+		return nil, false
+	}
+	return a, true
+}
+
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return "out_" + hex.EncodeToString(b[:])
+}
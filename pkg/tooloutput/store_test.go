@@ -0,0 +1,56 @@
+package tooloutput
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStorePutGet(t *testing.T) {
+	s := NewStore(0, 0)
+	id := s.Put("web_fetch", "完整输出")
+	a, ok := s.Get(id)
+	if !ok || a.Tool != "web_fetch" || a.Content != "完整输出" {
+		t.Fatalf("Get(%s) = %+v, %v", id, a, ok)
+	}
+	if other := s.Put("web_fetch", "x"); other == id {
+		t.Error("duplicate artifact id")
+	}
+	if _, ok := s.Get("out_missing"); ok {
+		t.Error("missing artifact found")
+	}
+}
+
+func TestStoreEvictsOldest(t *testing.T) {
+	s := NewStore(2, time.Hour)
+	first := s.Put("a", "1")
+	second := s.Put("b", "2")
+	third := s.Put("c", "3")
+	if _, ok := s.Get(first); ok {
+		t.Error("oldest artifact not evicted")
+	}
+	for _, id := range []string{second, third} {
+		if _, ok := s.Get(id); !ok {
+			t.Errorf("artifact %s evicted", id)
+		}
+	}
+	if len(s.items) != 2 || len(s.order) != 2 {
+		t.Errorf("store holds %d items and %d ids, want 2", len(s.items), len(s.order))
+	}
+}
+
+func TestStoreExpires(t *testing.T) {
+	s := NewStore(10, 20*time.Millisecond)
+	old := s.Put("a", "1")
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := s.Get(old); ok {
+		t.Error("expired artifact readable")
+	}
+	// 新的保存顺带清理已过期的输出
+	fresh := s.Put("b", "2")
+	if _, ok := s.items[old]; ok {
+		t.Error("expired artifact kept after Put")
+	}
+	if _, ok := s.Get(fresh); !ok || len(s.order) != 1 {
+		t.Errorf("fresh artifact missing, order %v", s.order)
+	}
+}
@@ -0,0 +1,64 @@
+package toolpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AuditEntry 审计日志中的一条被拒绝的调用
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Agent     string    `json:"agent,omitempty"`
+	SessionID string    `json:"sessionId,omitempty"`
+	Tool      string    `json:"tool"`
+	Arguments string    `json:"arguments"`
+	Reason    string    `json:"reason"`
+}
+
+// auditLog 以 JSON Lines 格式追加写入审计日志
+type auditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("tool policy: create audit log dir failed: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("tool policy: open audit log failed: %w", err)
+	}
+	return &auditLog{file: file}, nil
+}
+
+func (a *auditLog) write(entry AuditEntry) {
+	if a == nil {
+		return
+	}
+	entry.Time = time.Now()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(data, '\n')); err != nil {
+		zap.L().Error("Write tool audit log failed", zap.Error(err))
+	}
+}
+
+func (a *auditLog) close() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_ = a.file.Close()
+}
@@ -0,0 +1,310 @@
+package toolpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"MoonAgent/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// allAgents 对所有智能体生效的规则名称
+const allAgents = "*"
+
+// defaultURLTools 默认检查域名的工具及其地址参数
+var defaultURLTools = map[string]string{
+	"http_fetch":    "url",
+	"jump_web_page": "url",
+}
+
+type argumentRule struct {
+	config.ArgumentRule
+	pattern *regexp.Regexp
+}
+
+// Policy 工具权限策略，按智能体、会话、域名和参数约束判断工具调用是否允许
+type Policy struct {
+	agents    []config.AgentToolPolicy
+	domains   config.DomainPolicy
+	urlTools  map[string]string
+	arguments []argumentRule
+	audit     *auditLog
+	denied    atomic.Int64
+}
+
+// ProvidePolicy 根据配置创建策略，配置了审计日志时打开日志文件
+func ProvidePolicy(cfg *config.ServerConfig) (*Policy, func(), error) {
+	p, err := NewPolicy(&cfg.ToolsConfig.Policy)
+	if err != nil {
+		zap.S().Error("Failed to create tool policy", zap.String("error", err.Error()))
+		return nil, nil, err
+	}
+	return p, p.Close, nil
+}
+
+func NewPolicy(cfg *config.ToolPolicyConfig) (*Policy, error) {
+	p := &Policy{
+		agents:   cfg.Agents,
+		domains:  cfg.Domains,
+		urlTools: cfg.Domains.Tools,
+	}
+	if len(p.urlTools) == 0 {
+		p.urlTools = defaultURLTools
+	}
+	for _, rule := range cfg.Arguments {
+		if rule.Tool == "" || rule.Argument == "" {
+			return nil, fmt.Errorf("tool policy: argument rule requires tool and argument")
+		}
+		r := argumentRule{ArgumentRule: rule}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("tool policy: invalid pattern for %s.%s: %w", rule.Tool, rule.Argument, err)
+			}
+			r.pattern = re
+		}
+		p.arguments = append(p.arguments, r)
+	}
+	if cfg.AuditLog != "" {
+		audit, err := openAuditLog(cfg.AuditLog)
+		if err != nil {
+			return nil, err
+		}
+		p.audit = audit
+	}
+	return p, nil
+}
+
+// Check 判断调用是否允许，拒绝时返回原因。依次检查会话禁用、智能体的白名单和黑名单、域名和参数约束
+func (p *Policy) Check(ctx context.Context, tool, argumentsInJSON string) (reason string) {
+	if slices.Contains(sessionFromContext(ctx).disabled, tool) {
+		return "当前会话已禁用该工具"
+	}
+	if p == nil {
+		return ""
+	}
+	agent := AgentFromContext(ctx)
+	for _, rule := range p.agents {
+		if rule.Agent != allAgents && rule.Agent != agent {
+			continue
+		}
+		if slices.Contains(rule.Deny, tool) {
+			return fmt.Sprintf("智能体 %s 不允许使用该工具", agent)
+		}
+		if len(rule.Allow) > 0 && !slices.Contains(rule.Allow, tool) {
+			return fmt.Sprintf("该工具不在智能体 %s 允许使用的工具中", agent)
+		}
+	}
+
+	var args map[string]any
+	_ = json.Unmarshal([]byte(argumentsInJSON), &args)
+	if arg, ok := p.urlTools[tool]; ok {
+		for _, v := range lookup(args, arg) {
+			if raw, ok := v.(string); ok {
+				if reason := p.checkURL(raw); reason != "" {
+					return reason
+				}
+			}
+		}
+	}
+	for _, rule := range p.arguments {
+		if rule.Tool != tool {
+			continue
+		}
+		for _, v := range lookup(args, rule.Argument) {
+			if v == nil {
+				continue
+			}
+			if reason := rule.check(v); reason != "" {
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
+// lookup 参数中与 name 只有大小写不同的所有键的值。工具按 encoding/json 的规则解码参数，
+// 键名不区分大小写且后出现的生效，只检查同名的键会被 {"url": ..., "URL": ...} 绕过
+func lookup(args map[string]any, name string) []any {
+	var values []any
+	for key, v := range args {
+		if strings.EqualFold(key, name) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// CheckURL 检查工具实际访问的地址，包括重定向后的地址和浏览器最终打开的地址，拒绝时返回原因
+func (p *Policy) CheckURL(tool, rawURL string) string {
+	if p == nil {
+		return ""
+	}
+	if _, ok := p.urlTools[tool]; !ok {
+		return ""
+	}
+	return p.checkURL(rawURL)
+}
+
+// checkURL 检查地址的域名是否允许访问
+func (p *Policy) checkURL(raw string) string {
+	if len(p.domains.Allow) == 0 && len(p.domains.Deny) == 0 {
+		return ""
+	}
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Hostname() == "" {
+		return "无法解析网页地址的域名"
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchDomain(host, p.domains.Deny) {
+		return fmt.Sprintf("不允许访问域名 %s", host)
+	}
+	if len(p.domains.Allow) > 0 && !matchDomain(host, p.domains.Allow) {
+		return fmt.Sprintf("域名 %s 不在允许访问的域名中，允许: %s", host, strings.Join(p.domains.Allow, ", "))
+	}
+	return ""
+}
+
+// matchDomain 域名等于列表中的某一项或是其子域名
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "*."))
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *argumentRule) check(v any) string {
+	s, ok := v.(string)
+	if !ok {
+		data, _ := json.Marshal(v)
+		s = string(data)
+	}
+	if r.MaxLength > 0 && utf8.RuneCountInString(s) > r.MaxLength {
+		return fmt.Sprintf("参数 %s 超过 %d 个字符的上限", r.Argument, r.MaxLength)
+	}
+	if len(r.Enum) > 0 && !slices.Contains(r.Enum, s) {
+		return fmt.Sprintf("参数 %s 只能是: %s", r.Argument, strings.Join(r.Enum, ", "))
+	}
+	if r.pattern != nil && !r.pattern.MatchString(s) {
+		return fmt.Sprintf("参数 %s 不符合规则 %s", r.Argument, r.Pattern)
+	}
+	return ""
+}
+
+// Authorize 检查调用，拒绝时写入审计日志并返回交给模型的结构化错误，允许时返回空字符串
+func (p *Policy) Authorize(ctx context.Context, tool, argumentsInJSON string) string {
+	reason := p.Check(ctx, tool, argumentsInJSON)
+	if reason == "" {
+		return ""
+	}
+	return p.deny(ctx, tool, argumentsInJSON, reason)
+}
+
+// AuthorizeURL 同 Authorize，检查工具实际访问的地址
+func (p *Policy) AuthorizeURL(ctx context.Context, tool, rawURL string) string {
+	reason := p.CheckURL(tool, rawURL)
+	if reason == "" {
+		return ""
+	}
+	data, _ := json.Marshal(map[string]string{"url": rawURL})
+	return p.deny(ctx, tool, string(data), reason)
+}
+
+// deny 记录被拒绝的调用，返回交给模型的结构化错误
+func (p *Policy) deny(ctx context.Context, tool, argumentsInJSON, reason string) string {
+	session := sessionFromContext(ctx)
+	entry := AuditEntry{
+		Agent:     AgentFromContext(ctx),
+		SessionID: session.id,
+		Tool:      tool,
+		Arguments: argumentsInJSON,
+		Reason:    reason,
+	}
+	zap.L().Warn("Tool call denied",
+		zap.String("agent", entry.Agent),
+		zap.String("session", entry.SessionID),
+		zap.String("tool", tool),
+		zap.String("arguments", argumentsInJSON),
+		zap.String("reason", reason))
+	if p != nil {
+		p.denied.Add(1)
+		p.audit.write(entry)
+	}
+
+	data, _ := json.Marshal(struct {
+		Error  string `json:"error"`
+		Tool   string `json:"tool"`
+		Reason string `json:"reason"`
+		Hint   string `json:"hint"`
+	}{
+		Error:  "permission_denied",
+		Tool:   tool,
+		Reason: reason,
+		Hint:   "这次调用被权限策略拒绝，工具没有执行。不要用相同的参数重试，换用其他允许的方式或直接回答",
+	})
+	return string(data)
+}
+
+// Denied 被拒绝的调用次数
+func (p *Policy) Denied() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.denied.Load()
+}
+
+// Close 关闭审计日志
+func (p *Policy) Close() {
+	if p == nil {
+		return
+	}
+	p.audit.close()
+}
+
+type agentKey struct{}
+
+type sessionKey struct{}
+
+type session struct {
+	id       string
+	disabled []string
+}
+
+// WithAgent 记录当前运行的智能体名称，按智能体的规则检查工具调用
+func WithAgent(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, agentKey{}, name)
+}
+
+// WithSession 记录当前请求的会话及其禁用的工具
+func WithSession(ctx context.Context, id string, disabled []string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{id: id, disabled: disabled})
+}
+
+// AgentFromContext 当前运行的智能体名称，未记录时为空
+func AgentFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(agentKey{}).(string)
+	return name
+}
+
+// SessionIDFromContext 当前请求的会话ID，未记录时为空
+func SessionIDFromContext(ctx context.Context) string {
+	return sessionFromContext(ctx).id
+}
+
+func sessionFromContext(ctx context.Context) *session {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return s
+	}
+	return &session{}
+}
@@ -0,0 +1,49 @@
+package toolpolicy
+
+import (
+	"context"
+	"testing"
+
+	"MoonAgent/pkg/config"
+)
+
+func TestCheckDomainsIgnoresKeyCase(t *testing.T) {
+	p, err := NewPolicy(&config.ToolPolicyConfig{Domains: config.DomainPolicy{Allow: []string{"example.com"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args    string
+		allowed bool
+	}{
+		{`{"url":"https://docs.example.com/a"}`, true},
+		{`{"url":"https://evil.test/"}`, false},
+		// 工具解码时 URL 覆盖 url，两个键都要检查
+		{`{"url":"https://example.com/","URL":"https://evil.test/"}`, false},
+		{`{"Url":"https://evil.test/"}`, false},
+	}
+	for _, tt := range tests {
+		if reason := p.Check(context.Background(), "http_fetch", tt.args); (reason == "") != tt.allowed {
+			t.Errorf("Check(%s) = %q, allowed want %v", tt.args, reason, tt.allowed)
+		}
+	}
+	if reason := p.CheckURL("http_fetch", "https://evil.test/"); reason == "" {
+		t.Error("CheckURL allowed a domain outside the allow list")
+	}
+	if reason := p.CheckURL("web_search", "https://evil.test/"); reason != "" {
+		t.Errorf("CheckURL checked a tool without url arguments: %q", reason)
+	}
+}
+
+func TestCheckArgumentsIgnoresKeyCase(t *testing.T) {
+	p, err := NewPolicy(&config.ToolPolicyConfig{Arguments: []config.ArgumentRule{{Tool: "shell", Argument: "command", Enum: []string{"ls"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason := p.Check(context.Background(), "shell", `{"command":"ls","COMMAND":"rm -rf /"}`); reason == "" {
+		t.Error("argument rule bypassed with a differently cased key")
+	}
+	if reason := p.Check(context.Background(), "shell", `{"command":"ls"}`); reason != "" {
+		t.Errorf("reason = %q", reason)
+	}
+}
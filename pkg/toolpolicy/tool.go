@@ -0,0 +1,31 @@
+package toolpolicy
+
+import (
+	"context"
+
+	"MoonAgent/pkg/toolwrap"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+// Wrap 为工具加上权限检查，name 为策略中使用的工具名，非 InvokableTool 原样返回
+func (p *Policy) Wrap(name string, t tool.BaseTool) tool.BaseTool {
+	it, ok := t.(tool.InvokableTool)
+	if !ok {
+		return t
+	}
+	return &guardedTool{Wrapper: toolwrap.Wrapper{Tool: it}, policy: p, name: name}
+}
+
+type guardedTool struct {
+	toolwrap.Wrapper
+	policy *Policy
+	name   string
+}
+
+func (t *guardedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if denied := t.policy.Authorize(ctx, t.name, argumentsInJSON); denied != "" {
+		return denied, nil
+	}
+	return t.Tool.InvokableRun(ctx, argumentsInJSON, opts...)
+}
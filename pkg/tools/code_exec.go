@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"MoonAgent/pkg/sandbox"
+	"context"
+	"fmt"
+	"strings"
+)
+
+type CodeExecParam struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+	// Files 执行前写入工作区的输入文件，键为相对路径
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// ExecuteCode 在沙箱中执行代码，返回退出码、输出和产出文件的格式化结果
+func ExecuteCode(ctx context.Context, r *sandbox.Runner, p *CodeExecParam) (string, error) {
+	res, err := r.Run(ctx, &sandbox.Request{
+		Language: p.Language,
+		Code:     p.Code,
+		Files:    p.Files,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("退出码: %d，耗时: %s\n", res.ExitCode, res.Duration.Round(1e6)))
+	if res.TimedOut {
+		result.WriteString("执行超时，进程已被终止\n")
+	}
+	if res.DiskLimitExceeded {
+		result.WriteString("写入的文件超过磁盘上限，进程已被终止\n")
+	}
+	if res.Stdout != "" {
+		result.WriteString("stdout:\n" + strings.TrimRight(res.Stdout, "\n") + "\n")
+	}
+	if res.Stderr != "" {
+		result.WriteString("stderr:\n" + strings.TrimRight(res.Stderr, "\n") + "\n")
+	}
+	if len(res.Files) > 0 {
+		result.WriteString("产出文件:\n")
+		for _, f := range res.Files {
+			result.WriteString(fmt.Sprintf("- %s (%d 字节)", f.Path, f.Size))
+			if f.Truncated {
+				result.WriteString("，内容已截断")
+			}
+			result.WriteString("\n")
+			if f.Content != "" {
+				result.WriteString(strings.TrimRight(f.Content, "\n") + "\n")
+			}
+		}
+	}
+	return strings.TrimSpace(result.String()), nil
+}
@@ -2,23 +2,42 @@ package tools
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"net/url"
+
+	"MoonAgent/pkg/webfetch"
 
 	"github.com/cloudwego/eino-ext/components/tool/browseruse"
 )
 
-func GoToWebPage(ctx context.Context, url string) (string, error) {
+// GoToWebPage 用浏览器打开网页。check 不为空时检查浏览器跟随重定向后最终打开的地址，
+// 拒绝时不返回页面内容，返回 *webfetch.DeniedError
+func GoToWebPage(ctx context.Context, rawURL string, check webfetch.URLCheck) (string, error) {
 	but, err := browseruse.NewBrowserUseTool(ctx, &browseruse.Config{})
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("start browser failed: %w", err)
 	}
+	defer but.Cleanup()
 
 	result, err := but.Execute(&browseruse.Param{
 		Action: browseruse.ActionGoToURL,
-		URL:    &url,
+		URL:    &rawURL,
 	})
 	if err != nil {
 		return "", err
 	}
+	if check != nil {
+		state, err := but.GetCurrentState()
+		if err != nil {
+			return "", err
+		}
+		final, err := url.Parse(state.URL)
+		if err != nil {
+			return "", err
+		}
+		if reason := check(ctx, final); reason != "" {
+			return "", &webfetch.DeniedError{URL: state.URL, Reason: reason}
+		}
+	}
 	return result.Output, nil
 }
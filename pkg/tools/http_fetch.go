@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"MoonAgent/pkg/webfetch"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// 默认返回的正文字符数和链接数
+const (
+	DefaultFetchMaxContentLength = 8000
+	DefaultFetchMaxLinks         = 20
+)
+
+type HTTPFetchParam struct {
+	URL string `json:"url"`
+	// IncludeLinks 是否在结果中附带页面链接
+	IncludeLinks bool `json:"include_links,omitempty"`
+}
+
+// HTTPFetchOptions 网页抓取结果的输出限制
+type HTTPFetchOptions struct {
+	MaxContentLength int
+	MaxLinks         int
+	// BrowserFallbackMinLength 正文少于该字符数时改用浏览器打开，0 表示不回退
+	BrowserFallbackMinLength int
+	// CheckURL 检查浏览器最终打开的地址，被拒绝时不使用浏览器的结果
+	CheckURL webfetch.URLCheck
+}
+
+// FetchWebPage 抓取网页并返回 markdown 格式的标题、正文和链接，正文过短时回退到浏览器
+func FetchWebPage(ctx context.Context, f *webfetch.Fetcher, p *HTTPFetchParam, opts HTTPFetchOptions) (string, error) {
+	if strings.TrimSpace(p.URL) == "" {
+		return "", fmt.Errorf("url cannot be empty")
+	}
+	if opts.MaxContentLength <= 0 {
+		opts.MaxContentLength = DefaultFetchMaxContentLength
+	}
+	if opts.MaxLinks <= 0 {
+		opts.MaxLinks = DefaultFetchMaxLinks
+	}
+
+	page, err := f.Fetch(ctx, p.URL)
+	if err != nil {
+		return "", err
+	}
+	content := []rune(page.Markdown)
+	// 页面依赖 JS 渲染时静态 HTML 中几乎没有正文，改用浏览器打开
+	if opts.BrowserFallbackMinLength > 0 && page.StatusCode < 400 && len(content) < opts.BrowserFallbackMinLength {
+		if output, err := GoToWebPage(ctx, page.URL, opts.CheckURL); err == nil && strings.TrimSpace(output) != "" {
+			return fmt.Sprintf("URL: %s\n(静态页面正文过少，以下为浏览器打开的结果)\n\n%s", page.URL, output), nil
+		}
+	}
+
+	var result strings.Builder
+	if page.Title != "" {
+		result.WriteString("# " + page.Title + "\n\n")
+	}
+	result.WriteString("URL: " + page.URL + "\n")
+	if page.StatusCode >= 400 {
+		result.WriteString(fmt.Sprintf("状态码: %d\n", page.StatusCode))
+	}
+	result.WriteString("\n")
+	if len(content) == 0 {
+		result.WriteString("未提取到正文，页面可能依赖 JS 渲染，可以改用网页跳转工具打开")
+	} else if len(content) > opts.MaxContentLength {
+		result.WriteString(string(content[:opts.MaxContentLength]))
+		result.WriteString(fmt.Sprintf("\n\n...(正文共 %d 字符，已截断)", len(content)))
+	} else {
+		result.WriteString(string(content))
+		if page.Truncated {
+			result.WriteString("\n\n...(页面过大，只处理了前面的部分)")
+		}
+	}
+	if p.IncludeLinks && len(page.Links) > 0 {
+		result.WriteString("\n\n链接:\n")
+		for i, link := range page.Links {
+			if i >= opts.MaxLinks {
+				result.WriteString(fmt.Sprintf("...(共 %d 个链接)\n", len(page.Links)))
+				break
+			}
+			result.WriteString(fmt.Sprintf("- [%s](%s)\n", link.Text, link.URL))
+		}
+	}
+	return strings.TrimSpace(result.String()), nil
+}
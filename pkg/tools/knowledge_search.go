@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"MoonAgent/pkg/knowledge"
+	moonretriever "MoonAgent/pkg/retriever"
+	"MoonAgent/pkg/splitter"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/retriever"
+)
+
+// MaxKnowledgeSearchTopK 单次知识库检索最多返回的片段数
+const MaxKnowledgeSearchTopK = 10
+
+type KnowledgeSearchParam struct {
+	Query string `json:"query"`
+	TopK  int    `json:"top_k,omitempty"`
+	// KnowledgeBases 检索的知识库，为空时使用默认知识库
+	KnowledgeBases []string `json:"knowledge_bases,omitempty"`
+	// Metadata 对 metadata 字段的等值过滤
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// SearchKnowledge 在知识库中检索，返回带片段ID的格式化结果
+func SearchKnowledge(ctx context.Context, r retriever.Retriever, p *KnowledgeSearchParam) (string, error) {
+	if strings.TrimSpace(p.Query) == "" {
+		return "", fmt.Errorf("query cannot be empty")
+	}
+
+	var opts []retriever.Option
+	if p.TopK > 0 {
+		topK := p.TopK
+		if topK > MaxKnowledgeSearchTopK {
+			topK = MaxKnowledgeSearchTopK
+		}
+		opts = append(opts, retriever.WithTopK(topK))
+	}
+	if len(p.KnowledgeBases) > 0 {
+		opts = append(opts, knowledge.WithKnowledgeBases(p.KnowledgeBases...))
+	}
+	if len(p.Metadata) > 0 {
+		opts = append(opts, moonretriever.WithMetadataFilter(p.Metadata))
+	}
+
+	docs, err := r.Retrieve(ctx, p.Query, opts...)
+	if err != nil {
+		return "", err
+	}
+	if len(docs) == 0 {
+		return "未检索到相关内容，可以换一种说法或放宽过滤条件后重试。", nil
+	}
+
+	var result strings.Builder
+	for _, doc := range docs {
+		result.WriteString(fmt.Sprintf("[%s]", doc.ID))
+		if kb, ok := doc.MetaData[knowledge.MetadataKeyKnowledgeBase].(string); ok {
+			result.WriteString(" 知识库: " + kb)
+		}
+		if id, ok := doc.MetaData[splitter.MetadataKeyDocumentID].(string); ok {
+			result.WriteString(" 文档: " + id)
+		}
+		result.WriteString(fmt.Sprintf(" 分数: %.4f\n%s\n\n", doc.Score(), doc.Content))
+	}
+	return strings.TrimSpace(result.String()), nil
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"MoonAgent/pkg/approval"
+	"MoonAgent/pkg/sandbox"
+	"MoonAgent/pkg/toolpolicy"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type ShellParam struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// RunShell 校验并执行白名单中的命令，需要审批的命令等待人工批准后再执行。
+// 命令被拒绝时不返回错误，把原因交给模型调整
+func RunShell(ctx context.Context, r *sandbox.ShellRunner, approvals *approval.Manager, p *ShellParam) (string, error) {
+	cmd, err := r.Check(p.Command, p.Args)
+	if errors.Is(err, sandbox.ErrCommandDenied) {
+		return fmt.Sprintf("命令未执行: %v", err), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	line := sandbox.CommandLine(p.Command, p.Args)
+	if cmd.RequireApproval {
+		d, err := approvals.Request(ctx, approval.Request{
+			Tool:      "shell",
+			Action:    line,
+			Agent:     toolpolicy.AgentFromContext(ctx),
+			SessionID: toolpolicy.SessionIDFromContext(ctx),
+		})
+		if err != nil {
+			return "", err
+		}
+		if !d.Approved {
+			reason := d.Reason
+			if reason == "" {
+				reason = "审批人拒绝"
+			}
+			return fmt.Sprintf("命令未执行: 人工审批未通过（%s），不要重复提交同一命令", reason), nil
+		}
+	}
+
+	res, err := r.Run(ctx, p.Command, p.Args)
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("$ %s\n退出码: %d，耗时: %s\n", line, res.ExitCode, res.Duration.Round(1e6)))
+	if res.TimedOut {
+		result.WriteString("执行超时，进程已被终止\n")
+	}
+	if res.Stdout != "" {
+		result.WriteString("stdout:\n" + strings.TrimRight(res.Stdout, "\n") + "\n")
+	}
+	if res.Stderr != "" {
+		result.WriteString("stderr:\n" + strings.TrimRight(res.Stderr, "\n") + "\n")
+	}
+	return strings.TrimSpace(result.String()), nil
+}
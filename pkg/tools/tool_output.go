@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"MoonAgent/pkg/tooloutput"
+	"fmt"
+	"strings"
+)
+
+type ReadToolOutputParam struct {
+	ArtifactID string `json:"artifact_id"`
+	// Offset 起始字符位置，从0开始
+	Offset int `json:"offset,omitempty"`
+	// Limit 最多读取的字符数
+	Limit int `json:"limit,omitempty"`
+}
+
+// ReadToolOutput 分页读取被截断的工具输出，maxChars 为单页的最大字符数，内容不完整时提示继续读取的位置
+func ReadToolOutput(store *tooloutput.Store, p *ReadToolOutputParam, maxChars int) (string, error) {
+	if strings.TrimSpace(p.ArtifactID) == "" {
+		return "", fmt.Errorf("artifact_id cannot be empty")
+	}
+	a, ok := store.Get(p.ArtifactID)
+	if !ok {
+		return fmt.Sprintf("%s 不存在或已过期，请重新调用原工具", p.ArtifactID), nil
+	}
+	limit := p.Limit
+	if limit <= 0 || limit > maxChars {
+		limit = maxChars
+	}
+	runes := []rune(a.Content)
+	start := max(p.Offset, 0)
+	if start >= len(runes) {
+		return fmt.Sprintf("%s 共 %d 个字符，offset %d 超出范围", a.ID, len(runes), start), nil
+	}
+	end := min(start+limit, len(runes))
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("%s（%s 的输出）第 %d-%d 个字符，共 %d 个字符\n", a.ID, a.Tool, start, end, len(runes)))
+	result.WriteString(string(runes[start:end]))
+	if end < len(runes) {
+		result.WriteString(fmt.Sprintf("\n...(可以从 offset=%d 继续读取)", end))
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"MoonAgent/pkg/tooloutput"
+)
+
+func TestReadToolOutputPaging(t *testing.T) {
+	store := tooloutput.NewStore(0, 0)
+	id := store.Put("web_fetch", "一二三四五六七八九十")
+
+	tests := []struct {
+		name     string
+		param    ReadToolOutputParam
+		maxChars int
+		content  string
+		next     string
+	}{
+		{"first page", ReadToolOutputParam{Limit: 4}, 100, "一二三四", "offset=4"},
+		{"middle page", ReadToolOutputParam{Offset: 4, Limit: 4}, 100, "五六七八", "offset=8"},
+		{"last page", ReadToolOutputParam{Offset: 8, Limit: 4}, 100, "九十", ""},
+		{"limit capped by max chars", ReadToolOutputParam{Limit: 50}, 3, "一二三", "offset=3"},
+		{"default limit", ReadToolOutputParam{}, 6, "一二三四五六", "offset=6"},
+		{"negative offset", ReadToolOutputParam{Offset: -5}, 100, "一二三四五六七八九十", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.param
+			p.ArtifactID = id
+			got, err := ReadToolOutput(store, &p, tt.maxChars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, body, _ := strings.Cut(got, "\n")
+			if !strings.Contains(header, "web_fetch") || !strings.Contains(header, "共 10 个字符") {
+				t.Errorf("header = %q", header)
+			}
+			content, _, _ := strings.Cut(body, "\n...")
+			if content != tt.content {
+				t.Errorf("content = %q, want %q", content, tt.content)
+			}
+			if tt.next == "" && strings.Contains(got, "继续读取") {
+				t.Errorf("last page asks to continue: %q", got)
+			}
+			if tt.next != "" && !strings.Contains(got, tt.next) {
+				t.Errorf("missing %q in %q", tt.next, got)
+			}
+		})
+	}
+}
+
+func TestReadToolOutputErrors(t *testing.T) {
+	store := tooloutput.NewStore(0, 0)
+	id := store.Put("web_fetch", "abc")
+
+	if _, err := ReadToolOutput(store, &ReadToolOutputParam{ArtifactID: " "}, 10); err == nil {
+		t.Error("empty artifact id accepted")
+	}
+	got, err := ReadToolOutput(store, &ReadToolOutputParam{ArtifactID: "out_missing"}, 10)
+	if err != nil || !strings.Contains(got, "不存在或已过期") {
+		t.Errorf("missing artifact = %q, %v", got, err)
+	}
+	got, err = ReadToolOutput(store, &ReadToolOutputParam{ArtifactID: id, Offset: 3}, 10)
+	if err != nil || !strings.Contains(got, "超出范围") {
+		t.Errorf("offset past end = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"MoonAgent/pkg/websearch"
+	"context"
+	"fmt"
+	"strings"
+)
+
+type WebSearchParam struct {
+	Query string `json:"query"`
+	// Num 返回的结果数
+	Num int `json:"num,omitempty"`
+}
+
+// SearchWeb 搜索网页，返回带编号的标题、地址和摘要
+func SearchWeb(ctx context.Context, s *websearch.Searcher, p *WebSearchParam) (string, error) {
+	if strings.TrimSpace(p.Query) == "" {
+		return "", fmt.Errorf("query cannot be empty")
+	}
+	results, err := s.Search(ctx, p.Query, p.Num)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "没有搜索到相关结果，可以换用其他关键词重试。", nil
+	}
+
+	var result strings.Builder
+	for _, r := range results {
+		result.WriteString(fmt.Sprintf("%d. %s\n   %s\n", r.Rank, r.Title, r.URL))
+		if r.Snippet != "" {
+			result.WriteString("   " + r.Snippet + "\n")
+		}
+	}
+	result.WriteString(fmt.Sprintf("(来源: %s)", results[0].Provider))
+	return result.String(), nil
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"MoonAgent/pkg/workspace"
+	"fmt"
+	"strings"
+)
+
+type ReadFileParam struct {
+	Path string `json:"path"`
+	// Offset 起始行号，从1开始
+	Offset int `json:"offset,omitempty"`
+	// Limit 最多读取的行数
+	Limit int `json:"limit,omitempty"`
+}
+
+type WriteFileParam struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Append  bool   `json:"append,omitempty"`
+}
+
+type ListDirParam struct {
+	Path      string `json:"path,omitempty"`
+	Recursive bool   `json:"recursive,omitempty"`
+}
+
+type GrepParam struct {
+	Pattern string `json:"pattern"`
+	Path    string `json:"path,omitempty"`
+	Glob    string `json:"glob,omitempty"`
+}
+
+// ReadFile 读取工作区中的文件，内容不完整时提示继续读取的行号
+func ReadFile(w *workspace.Workspace, p *ReadFileParam) (string, error) {
+	if strings.TrimSpace(p.Path) == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+	fc, err := w.ReadFile(p.Path, p.Offset, p.Limit)
+	if err != nil {
+		return "", err
+	}
+	if fc.TotalLines == 0 {
+		return fmt.Sprintf("%s 是空文件", fc.Path), nil
+	}
+	if fc.EndLine < fc.StartLine {
+		return fmt.Sprintf("%s 共 %d 行，起始行 %d 超出范围", fc.Path, fc.TotalLines, fc.StartLine), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("%s 第 %d-%d 行，共 %d 行\n", fc.Path, fc.StartLine, fc.EndLine, fc.TotalLines))
+	result.WriteString(strings.TrimRight(fc.Content, "\n"))
+	if fc.EndLine < fc.TotalLines {
+		if fc.Truncated {
+			result.WriteString(fmt.Sprintf("\n...(超过 %d 字节上限，", w.MaxFileBytes()))
+		} else {
+			result.WriteString("\n...(")
+		}
+		result.WriteString(fmt.Sprintf("可以从 offset=%d 继续读取)", fc.EndLine+1))
+	}
+	return result.String(), nil
+}
+
+// WriteFile 写入工作区中的文件
+func WriteFile(w *workspace.Workspace, p *WriteFileParam) (string, error) {
+	if strings.TrimSpace(p.Path) == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+	size, err := w.WriteFile(p.Path, p.Content, p.Append)
+	if err != nil {
+		return "", err
+	}
+	path, _ := w.Resolve(p.Path)
+	if p.Append {
+		return fmt.Sprintf("已追加 %d 字节到 %s，文件当前 %d 字节", len(p.Content), w.Rel(path), size), nil
+	}
+	return fmt.Sprintf("已写入 %s，共 %d 字节", w.Rel(path), size), nil
+}
+
+// ListDir 列出工作区中的目录，目录以 / 结尾
+func ListDir(w *workspace.Workspace, p *ListDirParam) (string, error) {
+	entries, truncated, err := w.ListDir(p.Path, p.Recursive)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "目录为空", nil
+	}
+	var result strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir {
+			result.WriteString(entry.Path + "/\n")
+		} else {
+			result.WriteString(fmt.Sprintf("%s (%d 字节)\n", entry.Path, entry.Size))
+		}
+	}
+	if truncated {
+		result.WriteString(fmt.Sprintf("...(只列出前 %d 项，可以指定子目录继续查看)", len(entries)))
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
+
+// Grep 在工作区中按正则搜索，每行结果格式为 路径:行号: 内容
+func Grep(w *workspace.Workspace, p *GrepParam) (string, error) {
+	if p.Pattern == "" {
+		return "", fmt.Errorf("pattern cannot be empty")
+	}
+	matches, truncated, err := w.Grep(p.Pattern, p.Path, p.Glob)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "没有匹配的内容", nil
+	}
+	var result strings.Builder
+	for _, m := range matches {
+		result.WriteString(fmt.Sprintf("%s:%d: %s\n", m.Path, m.Line, m.Text))
+	}
+	if truncated {
+		result.WriteString(fmt.Sprintf("...(只返回前 %d 条匹配，可以缩小搜索范围)", len(matches)))
+	}
+	return strings.TrimRight(result.String(), "\n"), nil
+}
@@ -0,0 +1,30 @@
+// Package toolwrap 提供包装工具时共用的委托实现，包装器嵌入 Wrapper 后只需实现 InvokableRun
+package toolwrap
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Wrapper 把工具信息、类型和回调设置委托给被包装的工具
+type Wrapper struct {
+	Tool tool.InvokableTool
+}
+
+func (w Wrapper) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return w.Tool.Info(ctx)
+}
+
+// GetType 沿用被包装工具的类型，链路追踪中显示原工具
+func (w Wrapper) GetType() string {
+	typ, _ := components.GetType(w.Tool)
+	return typ
+}
+
+// IsCallbacksEnabled 被包装的工具自己触发回调时，调用方不再重复触发
+func (w Wrapper) IsCallbacksEnabled() bool {
+	return components.IsCallbacksEnabled(w.Tool)
+}
@@ -0,0 +1,445 @@
+package webfetch
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 提取正文时直接删除的元素
+var junkTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Svg: true, atom.Canvas: true, atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+	atom.Nav: true, atom.Footer: true, atom.Aside: true,
+}
+
+var (
+	// class 或 id 命中时视为非正文区域
+	negativePattern = regexp.MustCompile(`(?i)comment|sidebar|footer|navbar|menu|share|social|advert|\bads?\b|banner|cookie|popup|modal|related|breadcrumb|subscribe|newsletter`)
+	// class 或 id 命中时视为正文区域，不会被删除
+	positivePattern = regexp.MustCompile(`(?i)article|content|main|post|entry|story|text`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+)
+
+// Document 从 HTML 中提取的正文
+type Document struct {
+	Title    string
+	Markdown string
+	Links    []Link
+}
+
+// Extract 提取 HTML 页面的标题和正文，正文转换为 markdown，相对链接按 base 转换为绝对地址
+func Extract(source string, base *url.URL) (*Document, error) {
+	root, err := html.Parse(strings.NewReader(source))
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{Title: findTitle(root)}
+	clean(root)
+
+	w := &mdWriter{base: base, seen: make(map[string]bool)}
+	w.block(mainContent(root), 0)
+	doc.Markdown = normalize(w.buf.String())
+	doc.Links = w.links
+	return doc, nil
+}
+
+// findTitle 依次使用 og:title、title 标签和第一个 h1
+func findTitle(root *html.Node) string {
+	var ogTitle, title, h1 string
+	walk(root, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Meta:
+			if prop := attr(n, "property"); prop == "og:title" && ogTitle == "" {
+				ogTitle = attr(n, "content")
+			}
+		case atom.Title:
+			if title == "" {
+				title = textContent(n)
+			}
+		case atom.H1:
+			if h1 == "" {
+				h1 = textContent(n)
+			}
+		}
+		return true
+	})
+	for _, t := range []string{ogTitle, title, h1} {
+		if t = collapseSpaces(t); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// clean 删除脚本、导航、评论区等非正文元素
+func clean(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isJunk(c)) {
+			n.RemoveChild(c)
+		} else {
+			clean(c)
+		}
+		c = next
+	}
+}
+
+func isJunk(n *html.Node) bool {
+	if junkTags[n.DataAtom] || attr(n, "hidden") != "" || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Body, atom.Article, atom.Main:
+		return false
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return negativePattern.MatchString(names) && !positivePattern.MatchString(names)
+}
+
+// mainContent 选择正文所在的元素：优先 article / main，否则按段落文本长度打分选出得分最高的元素
+func mainContent(root *html.Node) *html.Node {
+	var article, main, body *html.Node
+	walk(root, func(n *html.Node) bool {
+		switch {
+		case n.DataAtom == atom.Article && article == nil:
+			article = n
+		case (n.DataAtom == atom.Main || attr(n, "role") == "main") && main == nil:
+			main = n
+		case n.DataAtom == atom.Body && body == nil:
+			body = n
+		}
+		return true
+	})
+	if article != nil {
+		return article
+	}
+	if main != nil {
+		return main
+	}
+
+	scores := make(map[*html.Node]int)
+	walk(root, func(n *html.Node) bool {
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Blockquote {
+			return true
+		}
+		length := len([]rune(collapseSpaces(textContent(n))))
+		if length < 25 {
+			return false
+		}
+		if p := n.Parent; p != nil {
+			scores[p] += length
+			if gp := p.Parent; gp != nil {
+				scores[gp] += length / 2
+			}
+		}
+		return false
+	})
+	var best *html.Node
+	for n, score := range scores {
+		if best == nil || score > scores[best] {
+			best = n
+		}
+	}
+	if best != nil && best.DataAtom != atom.Html {
+		return best
+	}
+	if body != nil {
+		return body
+	}
+	return root
+}
+
+// mdWriter 把 HTML 节点转换为 markdown
+type mdWriter struct {
+	buf   strings.Builder
+	base  *url.URL
+	links []Link
+	seen  map[string]bool
+}
+
+// block 转换节点的所有子节点，块级元素之间用空行分隔
+func (w *mdWriter) block(n *html.Node, depth int) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c, depth)
+	}
+}
+
+func (w *mdWriter) node(n *html.Node, depth int) {
+	switch n.Type {
+	case html.TextNode:
+		w.buf.WriteString(collapseWhitespace(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		w.block(n, depth)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level, _ := strconv.Atoi(n.Data[1:])
+		if text := strings.TrimSpace(w.inline(n, depth)); text != "" {
+			w.paragraph(strings.Repeat("#", level) + " " + text)
+		}
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Header, atom.Figure, atom.Figcaption,
+		atom.Dl, atom.Dt, atom.Dd, atom.Details, atom.Summary, atom.Address:
+		w.newBlock()
+		w.block(n, depth)
+		w.newBlock()
+	case atom.Br:
+		w.buf.WriteString("\n")
+	case atom.Hr:
+		w.paragraph("---")
+	case atom.A:
+		w.buf.WriteString(w.link(n, depth))
+	case atom.Img:
+		if src := w.resolve(attr(n, "src")); src != "" {
+			w.buf.WriteString("![" + collapseSpaces(attr(n, "alt")) + "](" + src + ")")
+		}
+	case atom.Strong, atom.B:
+		w.buf.WriteString(wrap(w.inline(n, depth), "**"))
+	case atom.Em, atom.I:
+		w.buf.WriteString(wrap(w.inline(n, depth), "*"))
+	case atom.Code, atom.Kbd, atom.Samp:
+		if text := textContent(n); strings.TrimSpace(text) != "" {
+			w.buf.WriteString("`" + strings.TrimSpace(text) + "`")
+		}
+	case atom.Pre:
+		if text := strings.Trim(textContent(n), "\n"); strings.TrimSpace(text) != "" {
+			w.paragraph("```\n" + text + "\n```")
+		}
+	case atom.Ul, atom.Ol:
+		w.list(n, depth)
+	case atom.Blockquote:
+		inner := normalize(w.sub(n, depth))
+		if inner != "" {
+			w.paragraph("> " + strings.ReplaceAll(inner, "\n", "\n> "))
+		}
+	case atom.Table:
+		w.table(n, depth)
+	default:
+		w.block(n, depth)
+	}
+}
+
+// list 转换列表，嵌套列表按层级缩进
+func (w *mdWriter) list(n *html.Node, depth int) {
+	if depth == 0 {
+		w.newBlock()
+	}
+	index := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(index) + ". "
+			index++
+		}
+		item := &mdWriter{base: w.base, seen: w.seen}
+		var nested []*html.Node
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Ul || c.DataAtom == atom.Ol {
+				nested = append(nested, c)
+				continue
+			}
+			item.node(c, depth+1)
+		}
+		w.links = append(w.links, item.links...)
+		text := strings.Join(strings.Fields(item.buf.String()), " ")
+		w.buf.WriteString(strings.Repeat("  ", depth) + marker + text + "\n")
+		for _, c := range nested {
+			w.list(c, depth+1)
+		}
+	}
+	if depth == 0 {
+		w.newBlock()
+	}
+}
+
+// table 转换表格，第一行作为表头
+func (w *mdWriter) table(n *html.Node, depth int) {
+	var rows [][]string
+	walk(n, func(c *html.Node) bool {
+		if c.DataAtom != atom.Tr {
+			return true
+		}
+		var cells []string
+		for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+				text := strings.Join(strings.Fields(w.inline(cell, depth)), " ")
+				cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return
+	}
+	var sb strings.Builder
+	for i, row := range rows {
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString(strings.Repeat("| --- ", len(row)) + "|\n")
+		}
+	}
+	w.paragraph(strings.TrimRight(sb.String(), "\n"))
+}
+
+// link 转换链接并记录到链接列表，同一地址只记录一次
+func (w *mdWriter) link(n *html.Node, depth int) string {
+	text := strings.TrimSpace(w.inline(n, depth))
+	href := w.resolve(attr(n, "href"))
+	if href == "" || text == "" {
+		return text
+	}
+	if !w.seen[href] {
+		w.seen[href] = true
+		w.links = append(w.links, Link{Text: collapseSpaces(textContent(n)), URL: href})
+	}
+	return "[" + text + "](" + href + ")"
+}
+
+// inline 转换子节点为单行文本
+func (w *mdWriter) inline(n *html.Node, depth int) string {
+	return strings.Join(strings.Fields(w.sub(n, depth)), " ")
+}
+
+// sub 用独立的缓冲区转换子节点，链接合并到当前列表
+func (w *mdWriter) sub(n *html.Node, depth int) string {
+	inner := &mdWriter{base: w.base, seen: w.seen}
+	inner.block(n, depth)
+	w.links = append(w.links, inner.links...)
+	return inner.buf.String()
+}
+
+// resolve 转换为绝对地址，忽略锚点和 javascript 等链接
+func (w *mdWriter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if w.base != nil {
+		u = w.base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+func (w *mdWriter) newBlock() {
+	w.buf.WriteString("\n\n")
+}
+
+func (w *mdWriter) paragraph(text string) {
+	w.newBlock()
+	w.buf.WriteString(text)
+	w.newBlock()
+}
+
+// normalize 去掉行尾空白并合并多余的空行
+func normalize(s string) string {
+	lines := strings.Split(s, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			lines[i] = strings.TrimSpace(line)
+			continue
+		}
+		if inFence {
+			continue
+		}
+		lines[i] = strings.TrimRight(line, " \t")
+		// 非列表的行去掉由 HTML 缩进带来的行首空格
+		if trimmed := strings.TrimLeft(lines[i], " "); !strings.HasPrefix(trimmed, "- ") && !isOrderedItem(trimmed) {
+			lines[i] = trimmed
+		}
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func isOrderedItem(s string) bool {
+	i := strings.Index(s, ". ")
+	if i <= 0 {
+		return false
+	}
+	_, err := strconv.Atoi(s[:i])
+	return err == nil
+}
+
+func wrap(text, mark string) string {
+	if strings.TrimSpace(text) == "" {
+		return text
+	}
+	return mark + strings.TrimSpace(text) + mark
+}
+
+func walk(n *html.Node, fn func(*html.Node) bool) {
+	if n.Type == html.ElementNode && !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return sb.String()
+}
+
+// collapseWhitespace 把连续空白合并为一个空格，保留首尾空格以便与相邻的行内元素分隔
+func collapseWhitespace(s string) string {
+	if strings.TrimSpace(s) == "" {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+	out := strings.Join(strings.Fields(s), " ")
+	if strings.TrimLeft(s, " \t\r\n") != s {
+		out = " " + out
+	}
+	if strings.TrimRight(s, " \t\r\n") != s {
+		out += " "
+	}
+	return out
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,227 @@
+package webfetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"MoonAgent/pkg/config"
+
+	"golang.org/x/net/html/charset"
+)
+
+// 默认限制
+const (
+	DefaultTimeout      = 15 * time.Second
+	DefaultMaxBytes     = 2 * 1024 * 1024
+	DefaultMaxRedirects = 5
+	DefaultUserAgent    = "Mozilla/5.0 (compatible; MoonAgent/1.0)"
+)
+
+var (
+	ErrUnsupportedScheme      = errors.New("only http and https urls are supported")
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	ErrPrivateAddress         = errors.New("access to private network address is not allowed")
+	ErrTooManyRedirects       = errors.New("too many redirects")
+)
+
+// URLCheck 检查请求的地址和每次重定向后的地址，拒绝时返回原因
+type URLCheck func(ctx context.Context, u *url.URL) string
+
+// DeniedError 地址被 URLCheck 拒绝
+type DeniedError struct {
+	URL    string
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("access to %s is denied: %s", e.URL, e.Reason)
+}
+
+// Link 页面中的链接，地址已转换为绝对地址
+type Link struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Page 抓取并提取后的页面
+type Page struct {
+	// URL 跟随重定向后的最终地址
+	URL         string `json:"url"`
+	StatusCode  int    `json:"statusCode"`
+	ContentType string `json:"contentType"`
+	Title       string `json:"title"`
+	// Markdown 正文内容，非 HTML 的文本响应为原文
+	Markdown string `json:"markdown"`
+	Links    []Link `json:"links"`
+	// Truncated 响应体超过大小上限，只处理了前面的部分
+	Truncated bool `json:"truncated"`
+}
+
+// Fetcher 不执行 JS 的轻量网页抓取器
+type Fetcher struct {
+	client    *http.Client
+	maxBytes  int64
+	userAgent string
+	checkURL  URLCheck
+}
+
+// NewFetcher 根据配置创建抓取器，默认拒绝访问内网和回环地址
+func NewFetcher(cfg *config.HTTPFetchConfig) *Fetcher {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	maxRedirects := cfg.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+	f := &Fetcher{
+		maxBytes:  cfg.MaxBytes,
+		userAgent: cfg.UserAgent,
+	}
+	if f.maxBytes <= 0 {
+		f.maxBytes = DefaultMaxBytes
+	}
+	if f.userAgent == "" {
+		f.userAgent = DefaultUserAgent
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !cfg.AllowPrivate {
+		// 在建立连接时检查解析后的地址，重定向和 DNS 指向内网的域名同样会被拒绝
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	f.client = &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("%w: more than %d", ErrTooManyRedirects, maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrUnsupportedScheme
+			}
+			return f.check(req.Context(), req.URL)
+		},
+	}
+	return f
+}
+
+// WithURLCheck 设置地址检查，请求前和每次重定向时调用
+func (f *Fetcher) WithURLCheck(check URLCheck) *Fetcher {
+	f.checkURL = check
+	return f
+}
+
+func (f *Fetcher) check(ctx context.Context, u *url.URL) error {
+	if f.checkURL == nil {
+		return nil
+	}
+	if reason := f.checkURL(ctx, u); reason != "" {
+		return &DeniedError{URL: u.String(), Reason: reason}
+	}
+	return nil
+}
+
+// Fetch 抓取网页，HTML 提取正文并转换为 markdown，纯文本和 JSON 原样返回
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrUnsupportedScheme
+	}
+	if err := f.check(ctx, u); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,application/json;q=0.8,*/*;q=0.5")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{
+		URL:         resp.Request.URL.String(),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if int64(len(body)) > f.maxBytes {
+		body = body[:f.maxBytes]
+		page.Truncated = true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(page.ContentType)
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+		mediaType, _, _ = mime.ParseMediaType(mediaType)
+	}
+	text, err := decode(body, page.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		doc, err := Extract(text, resp.Request.URL)
+		if err != nil {
+			return nil, err
+		}
+		page.Title = doc.Title
+		page.Markdown = doc.Markdown
+		page.Links = doc.Links
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") || mediaType == "application/xml":
+		page.Markdown = text
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, mediaType)
+	}
+	return page, nil
+}
+
+// decode 按响应头、meta 标签或内容探测的编码转换为 UTF-8
+func decode(body []byte, contentType string) (string, error) {
+	enc, _, _ := charset.DetermineEncoding(body, contentType)
+	data, err := io.ReadAll(enc.NewDecoder().Reader(bytes.NewReader(body)))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast()
+}
@@ -0,0 +1,210 @@
+package webfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"MoonAgent/pkg/config"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func newTestFetcher(cfg config.HTTPFetchConfig) *Fetcher {
+	cfg.AllowPrivate = true
+	return NewFetcher(&cfg)
+}
+
+func gbk(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestFetchRejectsPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(&config.HTTPFetchConfig{})
+	if _, err := f.Fetch(context.Background(), srv.URL); !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("err = %v, want ErrPrivateAddress", err)
+	}
+}
+
+func TestFetchRejectsUnsupportedScheme(t *testing.T) {
+	f := newTestFetcher(config.HTTPFetchConfig{})
+	if _, err := f.Fetch(context.Background(), "file:///etc/passwd"); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Fatalf("err = %v, want ErrUnsupportedScheme", err)
+	}
+}
+
+func TestFetchRedirectLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Query().Get("n"), "%d", &n)
+		if n < 3 {
+			http.Redirect(w, r, fmt.Sprintf("/?n=%d", n+1), http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "done")
+	}))
+	defer srv.Close()
+
+	page, err := newTestFetcher(config.HTTPFetchConfig{MaxRedirects: 3}).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if page.Markdown != "done" || !strings.HasSuffix(page.URL, "/?n=3") {
+		t.Errorf("page = %q at %s", page.Markdown, page.URL)
+	}
+
+	_, err = newTestFetcher(config.HTTPFetchConfig{MaxRedirects: 2}).Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("err = %v, want ErrTooManyRedirects", err)
+	}
+}
+
+func TestFetchSizeCap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, strings.Repeat("a", 1000))
+	}))
+	defer srv.Close()
+
+	page, err := newTestFetcher(config.HTTPFetchConfig{MaxBytes: 100}).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if !page.Truncated || len(page.Markdown) != 100 {
+		t.Errorf("truncated = %v, length = %d, want true and 100", page.Truncated, len(page.Markdown))
+	}
+}
+
+func TestFetchDecodesCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"header", "text/html; charset=gbk", "<html><head><title>标题</title></head><body><article><p>中文正文内容</p></article></body></html>"},
+		{"meta", "text/html", `<html><head><meta charset="gbk"><title>标题</title></head><body><article><p>中文正文内容</p></article></body></html>`},
+		{"http-equiv", "text/html", `<html><head><meta http-equiv="Content-Type" content="text/html; charset=gb2312"><title>标题</title></head><body><article><p>中文正文内容</p></article></body></html>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := gbk(t, tt.body)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(body)
+			}))
+			defer srv.Close()
+
+			page, err := newTestFetcher(config.HTTPFetchConfig{}).Fetch(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if page.Title != "标题" || !strings.Contains(page.Markdown, "中文正文内容") {
+				t.Errorf("title = %q, markdown = %q", page.Title, page.Markdown)
+			}
+		})
+	}
+}
+
+func TestFetchUnsupportedContentType(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G'})
+	}))
+	defer srv.Close()
+
+	_, err := newTestFetcher(config.HTTPFetchConfig{}).Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrUnsupportedContentType) {
+		t.Fatalf("err = %v, want ErrUnsupportedContentType", err)
+	}
+}
+
+func TestExtractKeepsFormWrappedContent(t *testing.T) {
+	// ASP.NET WebForms 把整个页面放在一个 form 中
+	source := `<html><body><form id="form1" action="default.aspx" method="post">
+<input type="hidden" name="__VIEWSTATE" value="abc">
+<div class="content"><h1>公告</h1><p>这是页面正文，包含 <a href="/detail">详情链接</a>。</p></div>
+<button type="submit">提交</button>
+</form></body></html>`
+	base, _ := url.Parse("https://example.com/news/")
+	doc, err := Extract(source, base)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if !strings.Contains(doc.Markdown, "这是页面正文") || !strings.Contains(doc.Markdown, "# 公告") {
+		t.Errorf("markdown = %q", doc.Markdown)
+	}
+	if strings.Contains(doc.Markdown, "提交") {
+		t.Errorf("button text kept: %q", doc.Markdown)
+	}
+	if len(doc.Links) != 1 || doc.Links[0].URL != "https://example.com/detail" {
+		t.Errorf("links = %+v", doc.Links)
+	}
+}
+
+func TestExtractDropsJunk(t *testing.T) {
+	source := `<html><head><title>Page</title><script>var x = "script text";</script></head><body>
+<nav>home | about</nav>
+<main><p>Main paragraph with enough words to be the content.</p>
+<ul><li>one</li><li>two</li></ul></main>
+<div class="sidebar">sidebar text</div>
+<footer>footer text</footer></body></html>`
+	doc, err := Extract(source, nil)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Title != "Page" {
+		t.Errorf("title = %q", doc.Title)
+	}
+	for _, junk := range []string{"script text", "home | about", "sidebar text", "footer text"} {
+		if strings.Contains(doc.Markdown, junk) {
+			t.Errorf("markdown contains %q: %q", junk, doc.Markdown)
+		}
+	}
+	if !strings.Contains(doc.Markdown, "Main paragraph") || !strings.Contains(doc.Markdown, "- one") {
+		t.Errorf("markdown = %q", doc.Markdown)
+	}
+}
+
+func TestFetchChecksRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/blocked", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "blocked content")
+	}))
+	defer srv.Close()
+
+	var checked []string
+	f := newTestFetcher(config.HTTPFetchConfig{}).WithURLCheck(func(ctx context.Context, u *url.URL) string {
+		checked = append(checked, u.Path)
+		if u.Path == "/blocked" {
+			return "denied"
+		}
+		return ""
+	})
+	_, err := f.Fetch(context.Background(), srv.URL+"/")
+	var denied *DeniedError
+	if !errors.As(err, &denied) || denied.Reason != "denied" || !strings.HasSuffix(denied.URL, "/blocked") {
+		t.Fatalf("err = %v, want DeniedError for /blocked", err)
+	}
+	if strings.Join(checked, ",") != "/,/blocked" {
+		t.Errorf("checked = %v", checked)
+	}
+}
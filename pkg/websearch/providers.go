@@ -0,0 +1,343 @@
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// 各服务的默认地址，测试时可以替换为本地服务
+const (
+	DefaultGoogleEndpoint     = "https://www.googleapis.com/customsearch/v1"
+	DefaultBingEndpoint       = "https://api.bing.microsoft.com/v7.0/search"
+	DefaultDuckDuckGoEndpoint = "https://html.duckduckgo.com/html/"
+	userAgent                 = "Mozilla/5.0 (compatible; MoonAgent/1.0)"
+	// maxResponseBytes 搜索接口响应的最大字节数
+	maxResponseBytes = 4 * 1024 * 1024
+)
+
+// Google Google 可编程搜索引擎 JSON API
+type Google struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+	engineID string
+}
+
+func NewGoogle(client *http.Client, endpoint, apiKey, engineID string) (*Google, error) {
+	if apiKey == "" || engineID == "" {
+		return nil, errors.New("google search requires api_key and search_engine_id")
+	}
+	if endpoint == "" {
+		endpoint = DefaultGoogleEndpoint
+	}
+	return &Google{client: client, endpoint: endpoint, apiKey: apiKey, engineID: engineID}, nil
+}
+
+func (g *Google) Name() string {
+	return ProviderGoogle
+}
+
+func (g *Google) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	// 接口单次最多返回10条
+	if n > 10 {
+		n = 10
+	}
+	params := url.Values{
+		"key": {g.apiKey},
+		"cx":  {g.engineID},
+		"q":   {query},
+		"num": {strconv.Itoa(n)},
+	}
+	var resp struct {
+		Items []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"items"`
+	}
+	if err := getJSON(ctx, g.client, g.endpoint+"?"+params.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		results = append(results, newResult(g.Name(), len(results), item.Title, item.Link, item.Snippet))
+	}
+	return results, nil
+}
+
+// Bing Bing Web Search API
+type Bing struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func NewBing(client *http.Client, endpoint, apiKey string) (*Bing, error) {
+	if apiKey == "" {
+		return nil, errors.New("bing search requires api_key")
+	}
+	if endpoint == "" {
+		endpoint = DefaultBingEndpoint
+	}
+	return &Bing{client: client, endpoint: endpoint, apiKey: apiKey}, nil
+}
+
+func (b *Bing) Name() string {
+	return ProviderBing
+}
+
+func (b *Bing) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	params := url.Values{
+		"q":     {query},
+		"count": {strconv.Itoa(n)},
+	}
+	var resp struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	header := http.Header{"Ocp-Apim-Subscription-Key": {b.apiKey}}
+	if err := getJSON(ctx, b.client, b.endpoint+"?"+params.Encode(), header, &resp); err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, len(resp.WebPages.Value))
+	for _, item := range resp.WebPages.Value {
+		results = append(results, newResult(b.Name(), len(results), item.Name, item.URL, item.Snippet))
+	}
+	return results, nil
+}
+
+// SearXNG 自建的 SearXNG 实例，需要在实例配置中开启 json 格式
+type SearXNG struct {
+	client   *http.Client
+	endpoint string
+}
+
+func NewSearXNG(client *http.Client, endpoint string) (*SearXNG, error) {
+	if endpoint == "" {
+		return nil, errors.New("searxng search requires endpoint")
+	}
+	return &SearXNG{client: client, endpoint: strings.TrimRight(endpoint, "/")}, nil
+}
+
+func (s *SearXNG) Name() string {
+	return ProviderSearXNG
+}
+
+func (s *SearXNG) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	params := url.Values{
+		"q":      {query},
+		"format": {"json"},
+	}
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := getJSON(ctx, s.client, s.endpoint+"/search?"+params.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, n)
+	for _, item := range resp.Results {
+		if len(results) >= n {
+			break
+		}
+		results = append(results, newResult(s.Name(), len(results), item.Title, item.URL, item.Content))
+	}
+	return results, nil
+}
+
+// DuckDuckGo 解析 DuckDuckGo 的 HTML 搜索页面，不需要密钥
+type DuckDuckGo struct {
+	client   *http.Client
+	endpoint string
+}
+
+func NewDuckDuckGo(client *http.Client, endpoint string) *DuckDuckGo {
+	if endpoint == "" {
+		endpoint = DefaultDuckDuckGoEndpoint
+	}
+	return &DuckDuckGo{client: client, endpoint: endpoint}
+}
+
+func (d *DuckDuckGo) Name() string {
+	return ProviderDuckDuckGo
+}
+
+func (d *DuckDuckGo) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	form := url.Values{"q": {query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+	body, err := do(d.client, req)
+	if err != nil {
+		return nil, err
+	}
+	root, err := html.Parse(strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	base, _ := url.Parse(d.endpoint)
+
+	results := make([]Result, 0, n)
+	var visit func(*html.Node)
+	visit = func(node *html.Node) {
+		if len(results) >= n {
+			return
+		}
+		if node.Type == html.ElementNode && hasClass(node, "result") && !hasClass(node, "result--ad") {
+			link := findByClass(node, "result__a")
+			if link != nil {
+				snippet := ""
+				if s := findByClass(node, "result__snippet"); s != nil {
+					snippet = textOf(s)
+				}
+				if target := unwrapDuckDuckGo(base, attrOf(link, "href")); target != "" {
+					results = append(results, newResult(d.Name(), len(results), textOf(link), target, snippet))
+				}
+			}
+			return
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(root)
+	return results, nil
+}
+
+// unwrapDuckDuckGo 结果链接是 /l/?uddg=<目标地址> 形式的跳转地址，取出真实地址
+func unwrapDuckDuckGo(base *url.URL, href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+func newResult(provider string, index int, title, link, snippet string) Result {
+	return Result{
+		Title:    strings.Join(strings.Fields(title), " "),
+		URL:      strings.TrimSpace(link),
+		Snippet:  strings.Join(strings.Fields(snippet), " "),
+		Rank:     index + 1,
+		Provider: provider,
+	}
+}
+
+func getJSON(ctx context.Context, client *http.Client, endpoint string, header http.Header, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	for k, values := range header {
+		req.Header[k] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	body, err := do(client, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode search response failed: %w", err)
+	}
+	return nil
+}
+
+// do 发送请求，非 2xx 状态码视为错误，错误信息中不包含带密钥的请求地址
+func do(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return nil, urlErr.Err
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 200 {
+			msg = msg[:200]
+		}
+		return nil, fmt.Errorf("search request failed with status %d: %s", resp.StatusCode, msg)
+	}
+	return body, nil
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attrOf(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func findByClass(n *html.Node, class string) *html.Node {
+	if n.Type == html.ElementNode && hasClass(n, class) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findByClass(c, class); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attrOf(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textOf(n *html.Node) string {
+	var sb strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
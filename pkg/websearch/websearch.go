@@ -0,0 +1,274 @@
+package websearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"MoonAgent/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// 搜索服务
+const (
+	ProviderGoogle     = "google"
+	ProviderBing       = "bing"
+	ProviderSearXNG    = "searxng"
+	ProviderDuckDuckGo = "duckduckgo"
+)
+
+// 多个搜索服务的组合方式
+const (
+	// ModeFailover 按顺序尝试，使用第一个返回结果的服务
+	ModeFailover = "failover"
+	// ModeMerge 同时查询所有服务，按排名交替合并结果
+	ModeMerge = "merge"
+)
+
+// 默认限制
+const (
+	DefaultMaxResults = 8
+	DefaultTimeout    = 10 * time.Second
+	// MaxResults 单次搜索最多返回的结果数
+	MaxResults = 20
+)
+
+var ErrNoProvider = errors.New("no search provider configured")
+
+// Result 统一格式的搜索结果，Rank 从1开始
+type Result struct {
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Snippet  string `json:"snippet"`
+	Rank     int    `json:"rank"`
+	Provider string `json:"provider"`
+}
+
+// Provider 搜索服务
+type Provider interface {
+	Name() string
+	// Search 返回最多 n 条结果，按服务给出的顺序排列
+	Search(ctx context.Context, query string, n int) ([]Result, error)
+}
+
+// Searcher 组合多个搜索服务，对结果去重并重新编号
+type Searcher struct {
+	providers  []Provider
+	mode       string
+	maxResults int
+}
+
+// NewSearcher 创建组合搜索器
+func NewSearcher(providers []Provider, mode string, maxResults int) (*Searcher, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoProvider
+	}
+	switch mode {
+	case "":
+		mode = ModeFailover
+	case ModeFailover, ModeMerge:
+	default:
+		return nil, fmt.Errorf("unknown search mode: %s", mode)
+	}
+	if maxResults <= 0 {
+		maxResults = DefaultMaxResults
+	}
+	return &Searcher{providers: providers, mode: mode, maxResults: maxResults}, nil
+}
+
+// NewSearcherFromConfig 按配置创建搜索器，未配置搜索服务时，有 Google 密钥则使用 Google，否则使用 DuckDuckGo。
+// 列出的服务缺少密钥或地址时记录警告并跳过，全部不可用时返回错误
+func NewSearcherFromConfig(cfg *config.SearchConfig, browser *config.BrowserConfig) (*Searcher, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	names := cfg.Providers
+	if len(names) == 0 {
+		if googleKey(cfg, browser) != "" {
+			names = []string{ProviderGoogle}
+		} else {
+			names = []string{ProviderDuckDuckGo}
+		}
+	}
+	providers := make([]Provider, 0, len(names))
+	var skipped []error
+	for _, name := range names {
+		var p Provider
+		var err error
+		switch name {
+		case ProviderGoogle:
+			engineID := cfg.Google.SearchEngineID
+			if engineID == "" {
+				engineID = browser.SearchEngineID
+			}
+			p, err = NewGoogle(client, cfg.Google.Endpoint, googleKey(cfg, browser), engineID)
+		case ProviderBing:
+			p, err = NewBing(client, cfg.Bing.Endpoint, cfg.Bing.API_KEY)
+		case ProviderSearXNG:
+			p, err = NewSearXNG(client, cfg.SearXNG.Endpoint)
+		case ProviderDuckDuckGo:
+			p = NewDuckDuckGo(client, cfg.DuckDuckGo.Endpoint)
+		default:
+			return nil, fmt.Errorf("unknown search provider: %s", name)
+		}
+		if err != nil {
+			zap.L().Warn("Search provider is not configured, skipped", zap.String("provider", name), zap.Error(err))
+			skipped = append(skipped, err)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("%w: %w", ErrNoProvider, errors.Join(skipped...))
+	}
+	return NewSearcher(providers, cfg.Mode, cfg.MaxResults)
+}
+
+// googleKey 未单独配置时沿用浏览器配置中的 Google 密钥
+func googleKey(cfg *config.SearchConfig, browser *config.BrowserConfig) string {
+	if cfg.Google.API_KEY != "" {
+		return cfg.Google.API_KEY
+	}
+	return browser.API_KEY
+}
+
+// Providers 搜索服务名称
+func (s *Searcher) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for _, p := range s.providers {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+// Search 搜索并返回去重后的结果，n 为 0 时使用配置的结果数
+func (s *Searcher) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("query cannot be empty")
+	}
+	if n <= 0 {
+		n = s.maxResults
+	}
+	if n > MaxResults {
+		n = MaxResults
+	}
+	if s.mode == ModeMerge {
+		return s.merge(ctx, query, n)
+	}
+	return s.failover(ctx, query, n)
+}
+
+// failover 依次尝试各个服务，出错或没有结果时换下一个
+func (s *Searcher) failover(ctx context.Context, query string, n int) ([]Result, error) {
+	var errs []error
+	for _, p := range s.providers {
+		results, err := p.Search(ctx, query, n)
+		if err != nil {
+			zap.L().Warn("Search provider failed", zap.String("provider", p.Name()), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+		if results = dedupe(results, n); len(results) > 0 {
+			return results, nil
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// merge 并发查询所有服务，按排名交替合并，全部失败时返回错误
+func (s *Searcher) merge(ctx context.Context, query string, n int) ([]Result, error) {
+	lists := make([][]Result, len(s.providers))
+	errs := make([]error, len(s.providers))
+	var wg sync.WaitGroup
+	for i, p := range s.providers {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			results, err := p.Search(ctx, query, n)
+			if err != nil {
+				zap.L().Warn("Search provider failed", zap.String("provider", p.Name()), zap.Error(err))
+				errs[i] = fmt.Errorf("%s: %w", p.Name(), err)
+				return
+			}
+			lists[i] = results
+		}(i, p)
+	}
+	wg.Wait()
+
+	var merged []Result
+	for rank := 0; ; rank++ {
+		added := false
+		for _, list := range lists {
+			if rank < len(list) {
+				merged = append(merged, list[rank])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	if results := dedupe(merged, n); len(results) > 0 {
+		return results, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// dedupe 按规范化后的地址去重，保留先出现的结果并重新编号
+func dedupe(results []Result, n int) []Result {
+	seen := make(map[string]bool)
+	out := make([]Result, 0, n)
+	for _, r := range results {
+		key := normalizeURL(r.URL)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		r.Rank = len(out) + 1
+		out = append(out, r)
+		if len(out) >= n {
+			break
+		}
+	}
+	return out
+}
+
+// normalizeURL 忽略协议、www 前缀、锚点、末尾斜杠和 utm 跟踪参数，无效地址返回空
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if strings.HasPrefix(strings.ToLower(k), "utm_") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString(host)
+	sb.WriteString(strings.TrimRight(u.EscapedPath(), "/"))
+	for i, k := range keys {
+		if i == 0 {
+			sb.WriteString("?")
+		} else {
+			sb.WriteString("&")
+		}
+		sb.WriteString(k + "=" + strings.Join(query[k], ","))
+	}
+	return sb.String()
+}
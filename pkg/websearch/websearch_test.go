@@ -0,0 +1,272 @@
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"MoonAgent/pkg/config"
+)
+
+func urls(results []Result) string {
+	out := make([]string, 0, len(results))
+	for _, r := range results {
+		out = append(out, r.URL)
+	}
+	return strings.Join(out, ",")
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestGoogleSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("key") != "k" || q.Get("cx") != "cx" || q.Get("q") != "golang" || q.Get("num") != "10" {
+			http.Error(w, "bad request "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"items": []map[string]string{
+			{"title": " Go \n Home ", "link": "https://go.dev/", "snippet": "The Go  programming language"},
+			{"title": "Docs", "link": "https://go.dev/doc/", "snippet": "Documentation"},
+		}})
+	}))
+	defer srv.Close()
+
+	g, err := NewGoogle(srv.Client(), srv.URL, "k", "cx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := g.Search(context.Background(), "golang", 20)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	want := []Result{
+		{Title: "Go Home", URL: "https://go.dev/", Snippet: "The Go programming language", Rank: 1, Provider: ProviderGoogle},
+		{Title: "Docs", URL: "https://go.dev/doc/", Snippet: "Documentation", Rank: 2, Provider: ProviderGoogle},
+	}
+	if fmt.Sprint(results) != fmt.Sprint(want) {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestGoogleErrorHidesKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	g, _ := NewGoogle(srv.Client(), srv.URL, "secret-key", "cx")
+	_, err := g.Search(context.Background(), "golang", 5)
+	if err == nil || !strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "secret-key") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestBingSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Ocp-Apim-Subscription-Key") != "k" || r.URL.Query().Get("count") != "3" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, map[string]any{"webPages": map[string]any{"value": []map[string]string{
+			{"name": "Bing result", "url": "https://example.com/a", "snippet": "snippet a"},
+		}}})
+	}))
+	defer srv.Close()
+
+	b, err := NewBing(srv.Client(), srv.URL, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := b.Search(context.Background(), "q", 3)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 1 || results[0].Title != "Bing result" || results[0].Provider != ProviderBing {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestSearXNGSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, map[string]any{"results": []map[string]string{
+			{"title": "one", "url": "https://example.com/1", "content": "c1"},
+			{"title": "two", "url": "https://example.com/2", "content": "c2"},
+			{"title": "three", "url": "https://example.com/3", "content": "c3"},
+		}})
+	}))
+	defer srv.Close()
+
+	s, err := NewSearXNG(srv.Client(), srv.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.Search(context.Background(), "q", 2)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := urls(results); got != "https://example.com/1,https://example.com/2" {
+		t.Errorf("urls = %s", got)
+	}
+	if results[1].Snippet != "c2" || results[1].Rank != 2 {
+		t.Errorf("result = %+v", results[1])
+	}
+}
+
+const duckDuckGoPage = `<html><body>
+<div class="result results_links result--ad">
+  <a class="result__a" href="https://ads.example.com/">Ad</a>
+</div>
+<div class="result results_links">
+  <h2><a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fexample.com%2Fpage%3Fa%3D1&amp;rut=x">Example <b>Page</b></a></h2>
+  <a class="result__snippet">First   snippet</a>
+</div>
+<div class="result results_links">
+  <a class="result__a" href="https://direct.example.org/">Direct</a>
+</div>
+<div class="result results_links">
+  <a class="result__a" href="javascript:void(0)">Broken</a>
+</div>
+</body></html>`
+
+func TestDuckDuckGoSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("q") != "example" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, duckDuckGoPage)
+	}))
+	defer srv.Close()
+
+	results, err := NewDuckDuckGo(srv.Client(), srv.URL).Search(context.Background(), "example", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := urls(results); got != "https://example.com/page?a=1,https://direct.example.org/" {
+		t.Fatalf("urls = %s", got)
+	}
+	if results[0].Title != "Example Page" || results[0].Snippet != "First snippet" {
+		t.Errorf("result = %+v", results[0])
+	}
+}
+
+// stubProvider 返回固定结果或错误，记录调用次数
+type stubProvider struct {
+	name    string
+	results []Result
+	err     error
+	calls   int
+}
+
+func (p *stubProvider) Name() string {
+	return p.name
+}
+
+func (p *stubProvider) Search(ctx context.Context, query string, n int) ([]Result, error) {
+	p.calls++
+	return p.results, p.err
+}
+
+func stubResults(provider string, links ...string) []Result {
+	results := make([]Result, 0, len(links))
+	for i, link := range links {
+		results = append(results, newResult(provider, i, link, link, ""))
+	}
+	return results
+}
+
+func TestFailover(t *testing.T) {
+	failing := &stubProvider{name: "failing", err: errors.New("boom")}
+	empty := &stubProvider{name: "empty"}
+	working := &stubProvider{name: "working", results: stubResults("working",
+		"https://example.com/a", "http://www.example.com/a/?utm_source=x", "https://example.com/b")}
+	unused := &stubProvider{name: "unused", results: stubResults("unused", "https://example.com/c")}
+
+	s, err := NewSearcher([]Provider{failing, empty, working, unused}, ModeFailover, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.Search(context.Background(), "q", 0)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	// 规范化后相同的地址只保留第一条，并重新编号
+	if got := urls(results); got != "https://example.com/a,https://example.com/b" {
+		t.Errorf("urls = %s", got)
+	}
+	if results[1].Rank != 2 {
+		t.Errorf("rank = %d, want 2", results[1].Rank)
+	}
+	if failing.calls != 1 || empty.calls != 1 || unused.calls != 0 {
+		t.Errorf("calls = %d %d %d", failing.calls, empty.calls, unused.calls)
+	}
+}
+
+func TestFailoverAllFailed(t *testing.T) {
+	s, _ := NewSearcher([]Provider{
+		&stubProvider{name: "a", err: errors.New("first down")},
+		&stubProvider{name: "b", err: errors.New("second down")},
+	}, ModeFailover, 5)
+	_, err := s.Search(context.Background(), "q", 0)
+	if err == nil || !strings.Contains(err.Error(), "first down") || !strings.Contains(err.Error(), "second down") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := &stubProvider{name: "a", results: stubResults("a", "https://a.com/1", "https://shared.com/", "https://a.com/2")}
+	b := &stubProvider{name: "b", results: stubResults("b", "https://b.com/1", "https://shared.com")}
+	c := &stubProvider{name: "c", err: errors.New("down")}
+	s, _ := NewSearcher([]Provider{a, b, c}, ModeMerge, 10)
+	results, err := s.Search(context.Background(), "q", 0)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := urls(results); got != "https://a.com/1,https://b.com/1,https://shared.com/,https://a.com/2" {
+		t.Errorf("urls = %s", got)
+	}
+}
+
+func TestNewSearcherFromConfigSkipsUnconfigured(t *testing.T) {
+	cfg := &config.SearchConfig{Providers: []string{ProviderGoogle, ProviderBing, ProviderDuckDuckGo}}
+	s, err := NewSearcherFromConfig(cfg, &config.BrowserConfig{})
+	if err != nil {
+		t.Fatalf("NewSearcherFromConfig: %v", err)
+	}
+	if got := strings.Join(s.Providers(), ","); got != ProviderDuckDuckGo {
+		t.Errorf("providers = %s, want duckduckgo", got)
+	}
+
+	// 浏览器配置中的 Google 密钥同样可用
+	s, err = NewSearcherFromConfig(cfg, &config.BrowserConfig{API_KEY: "k", SearchEngineID: "cx"})
+	if err != nil {
+		t.Fatalf("NewSearcherFromConfig: %v", err)
+	}
+	if got := strings.Join(s.Providers(), ","); got != "google,duckduckgo" {
+		t.Errorf("providers = %s", got)
+	}
+
+	_, err = NewSearcherFromConfig(&config.SearchConfig{Providers: []string{ProviderGoogle, ProviderSearXNG}}, &config.BrowserConfig{})
+	if !errors.Is(err, ErrNoProvider) {
+		t.Errorf("err = %v, want ErrNoProvider", err)
+	}
+	_, err = NewSearcherFromConfig(&config.SearchConfig{Providers: []string{"yahoo", ProviderDuckDuckGo}}, &config.BrowserConfig{})
+	if err == nil || !strings.Contains(err.Error(), "unknown search provider") {
+		t.Errorf("err = %v, want unknown provider", err)
+	}
+}
@@ -0,0 +1,444 @@
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"MoonAgent/pkg/config"
+)
+
+// 默认限制
+const (
+	DefaultMaxFileBytes   = 1024 * 1024
+	DefaultMaxListEntries = 200
+	DefaultMaxGrepMatches = 100
+	// maxGrepLineLength 搜索结果中每行保留的最大字符数
+	maxGrepLineLength = 200
+)
+
+var (
+	ErrOutsideWorkspace = errors.New("path is outside the workspace")
+	ErrReadOnly         = errors.New("workspace is read-only")
+	ErrTooLarge         = errors.New("file exceeds the size limit")
+	ErrBinaryFile       = errors.New("binary file is not supported")
+)
+
+// maxSymlinks 解析路径时最多跟随的符号链接数，与 Linux 的 MAXSYMLINKS 一致
+const maxSymlinks = 40
+
+// Workspace 限定在根目录中的文件操作，所有路径都相对于根目录解析，符号链接也不能指向根目录之外。
+// 文件通过 os.Root 打开，解析后到打开前路径被替换成指向外部的链接时同样会被拒绝
+type Workspace struct {
+	root           string
+	dir            *os.Root
+	readOnly       bool
+	maxFileBytes   int64
+	maxListEntries int
+	maxGrepMatches int
+}
+
+// New 根据配置创建工作区，根目录不存在时创建
+func New(cfg *config.WorkspaceConfig) (*Workspace, error) {
+	if cfg.Root == "" {
+		return nil, errors.New("workspace root cannot be empty")
+	}
+	if err := os.MkdirAll(cfg.Root, 0o755); err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(cfg.Root)
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.OpenRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	w := &Workspace{
+		root:           root,
+		dir:            dir,
+		readOnly:       cfg.ReadOnly,
+		maxFileBytes:   cfg.MaxFileBytes,
+		maxListEntries: cfg.MaxListEntries,
+		maxGrepMatches: cfg.MaxGrepMatches,
+	}
+	if w.maxFileBytes <= 0 {
+		w.maxFileBytes = DefaultMaxFileBytes
+	}
+	if w.maxListEntries <= 0 {
+		w.maxListEntries = DefaultMaxListEntries
+	}
+	if w.maxGrepMatches <= 0 {
+		w.maxGrepMatches = DefaultMaxGrepMatches
+	}
+	return w, nil
+}
+
+// Root 工作区根目录
+func (w *Workspace) Root() string {
+	return w.root
+}
+
+// ReadOnly 是否只读
+func (w *Workspace) ReadOnly() bool {
+	return w.readOnly
+}
+
+// MaxFileBytes 单个文件读写的最大字节数
+func (w *Workspace) MaxFileBytes() int64 {
+	return w.maxFileBytes
+}
+
+// Resolve 把相对工作区的路径解析为绝对路径，以 / 开头的路径同样视为相对根目录
+func (w *Workspace) Resolve(name string) (string, error) {
+	name = strings.TrimLeft(filepath.FromSlash(name), string(filepath.Separator))
+	path := filepath.Join(w.root, name)
+	if !w.contains(path) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideWorkspace, name)
+	}
+	// 逐级解析符号链接，包括悬空的链接，防止通过链接访问工作区之外
+	real, err := EvalExisting(path)
+	if err != nil {
+		return "", err
+	}
+	if !w.contains(real) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideWorkspace, name)
+	}
+	return path, nil
+}
+
+// Rel 绝对路径相对于根目录的路径，使用 / 分隔
+func (w *Workspace) Rel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// rel 已解析的绝对路径相对于根目录的路径，用于 os.Root 中的操作
+func (w *Workspace) rel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return "."
+	}
+	return rel
+}
+
+// FileContent 读取的文件内容
+type FileContent struct {
+	Path string
+	// Content 从 StartLine 开始的内容
+	Content   string
+	StartLine int
+	EndLine   int
+	// TotalLines 文件总行数
+	TotalLines int
+	// Truncated 超过大小上限，只返回了部分内容
+	Truncated bool
+}
+
+// ReadFile 读取文本文件，offset 为起始行号（从1开始），limit 为最多读取的行数，0 表示不限制，返回内容不超过大小上限
+func (w *Workspace) ReadFile(name string, offset, limit int) (*FileContent, error) {
+	path, err := w.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := w.dir.Open(w.rel(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", name)
+	}
+	if offset < 1 {
+		offset = 1
+	}
+
+	fc := &FileContent{Path: w.Rel(path), StartLine: offset}
+	var buf bytes.Buffer
+	reader := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		text, err := reader.ReadString('\n')
+		if text == "" && err == io.EOF {
+			fc.TotalLines = line - 1
+			break
+		}
+		if strings.ContainsRune(text, 0) {
+			return nil, fmt.Errorf("%w: %s", ErrBinaryFile, name)
+		}
+		inRange := line >= offset && (limit <= 0 || line < offset+limit)
+		if inRange && !fc.Truncated {
+			if int64(buf.Len()+len(text)) > w.maxFileBytes {
+				fc.Truncated = true
+			} else {
+				buf.WriteString(text)
+				fc.EndLine = line
+			}
+		}
+		if err == io.EOF {
+			fc.TotalLines = line
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	fc.Content = buf.String()
+	return fc, nil
+}
+
+// WriteFile 写入文件，appendMode 为 true 时追加到文件末尾，写入后的文件不能超过大小上限
+func (w *Workspace) WriteFile(name, content string, appendMode bool) (int64, error) {
+	if w.readOnly {
+		return 0, ErrReadOnly
+	}
+	path, err := w.Resolve(name)
+	if err != nil {
+		return 0, err
+	}
+	if path == w.root {
+		return 0, errors.New("path cannot be empty")
+	}
+	size := int64(len(content))
+	if appendMode {
+		if info, err := w.dir.Stat(w.rel(path)); err == nil {
+			size += info.Size()
+		}
+	}
+	if size > w.maxFileBytes {
+		return 0, fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, size, w.maxFileBytes)
+	}
+	if err := w.mkdirAll(filepath.Dir(w.rel(path))); err != nil {
+		return 0, err
+	}
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := w.dir.OpenFile(w.rel(path), flag, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return size, f.Close()
+}
+
+// mkdirAll 在根目录中逐级创建目录
+func (w *Workspace) mkdirAll(rel string) error {
+	if rel == "." {
+		return nil
+	}
+	if err := w.mkdirAll(filepath.Dir(rel)); err != nil {
+		return err
+	}
+	if err := w.dir.Mkdir(rel, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// Entry 目录项
+type Entry struct {
+	Path  string
+	IsDir bool
+	Size  int64
+}
+
+// ListDir 列出目录内容，recursive 为 true 时递归列出子目录，超过条数上限时 truncated 为 true
+func (w *Workspace) ListDir(name string, recursive bool) (entries []Entry, truncated bool, err error) {
+	dir, err := w.Resolve(name)
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	if !info.IsDir() {
+		return nil, false, fmt.Errorf("%s is not a directory", name)
+	}
+
+	errLimit := errors.New("limit reached")
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path == dir {
+			return nil
+		}
+		// 跳过指向工作区之外的符号链接
+		if d.Type()&fs.ModeSymlink != 0 {
+			if _, err := w.Resolve(w.Rel(path)); err != nil {
+				return nil
+			}
+		}
+		if len(entries) >= w.maxListEntries {
+			truncated = true
+			return errLimit
+		}
+		entry := Entry{Path: w.Rel(path), IsDir: d.IsDir()}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+		if d.IsDir() && !recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		return nil, false, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, truncated, nil
+}
+
+// Match 搜索命中的行
+type Match struct {
+	Path string
+	Line int
+	Text string
+}
+
+// Grep 在目录下的文本文件中按正则搜索，glob 按文件名过滤，跳过二进制文件、隐藏目录和超过大小上限的文件
+func (w *Workspace) Grep(pattern, name, glob string) (matches []Match, truncated bool, err error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if glob != "" {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return nil, false, fmt.Errorf("invalid glob: %w", err)
+		}
+	}
+	start, err := w.Resolve(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	errLimit := errors.New("limit reached")
+	err = filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != start && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if glob != "" {
+			if ok, _ := filepath.Match(glob, d.Name()); !ok {
+				return nil
+			}
+		}
+		if info, err := d.Info(); err != nil || info.Size() > w.maxFileBytes {
+			return nil
+		}
+		data, err := fs.ReadFile(w.dir.FS(), w.Rel(path))
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			return nil
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if len(matches) >= w.maxGrepMatches {
+				truncated = true
+				return errLimit
+			}
+			if runes := []rune(line); len(runes) > maxGrepLineLength {
+				line = string(runes[:maxGrepLineLength]) + "..."
+			}
+			matches = append(matches, Match{Path: w.Rel(path), Line: i + 1, Text: strings.TrimRight(line, "\r")})
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		return nil, false, err
+	}
+	return matches, truncated, nil
+}
+
+func (w *Workspace) contains(path string) bool {
+	return path == w.root || strings.HasPrefix(path, w.root+string(filepath.Separator))
+}
+
+// EvalExisting 逐级解析绝对路径中的符号链接，不存在的部分原样拼接。
+// 与 filepath.EvalSymlinks 不同，指向不存在目标的悬空链接也会被解析，
+// 否则在链接处创建文件时会写到链接指向的位置
+func EvalExisting(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path is not absolute: %s", path)
+	}
+	volume := filepath.VolumeName(path)
+	resolved := volume + string(filepath.Separator)
+	parts := strings.Split(path[len(volume):], string(filepath.Separator))
+	links := 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		next := filepath.Join(resolved, part)
+		info, err := os.Lstat(next)
+		if errors.Is(err, fs.ErrNotExist) {
+			resolved = next
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		links++
+		if links > maxSymlinks {
+			return "", fmt.Errorf("too many symbolic links: %s", path)
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		target = filepath.FromSlash(target)
+		if filepath.IsAbs(target) {
+			volume = filepath.VolumeName(target)
+			resolved = volume + string(filepath.Separator)
+			target = target[len(volume):]
+		}
+		parts = append(strings.Split(target, string(filepath.Separator)), parts...)
+	}
+	return resolved, nil
+}
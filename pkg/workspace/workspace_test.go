@@ -0,0 +1,86 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"MoonAgent/pkg/config"
+)
+
+func newTestWorkspace(t *testing.T) (*Workspace, string) {
+	t.Helper()
+	w, err := New(&config.WorkspaceConfig{Root: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	outside, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, outside
+}
+
+func TestWriteFileRejectsDanglingSymlink(t *testing.T) {
+	w, outside := newTestWorkspace(t)
+	target := filepath.Join(outside, "pwned.txt")
+	links := map[string]string{
+		"absolute": target,
+		"relative": filepath.Join("..", filepath.Base(outside), "pwned.txt"),
+		"dir":      outside,
+	}
+	for name, dest := range links {
+		if err := os.Symlink(dest, filepath.Join(w.Root(), name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"absolute", "relative", "dir/pwned.txt"} {
+		if _, err := w.WriteFile(name, "x", false); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("WriteFile(%s) err = %v, want ErrOutsideWorkspace", name, err)
+		}
+	}
+	if _, err := os.Lstat(target); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file created outside the workspace: %v", err)
+	}
+}
+
+func TestSymlinkInsideWorkspace(t *testing.T) {
+	w, _ := newTestWorkspace(t)
+	if err := os.Symlink("data/new.txt", filepath.Join(w.Root(), "link")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteFile("data/new.txt", "hello\n", false); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	fc, err := w.ReadFile("link", 0, 0)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if fc.Content != "hello\n" {
+		t.Errorf("content = %q", fc.Content)
+	}
+}
+
+func TestEvalExisting(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing/child", filepath.Join(dir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("loop", filepath.Join(dir, "loop")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := EvalExisting(filepath.Join(dir, "dangling", "file"))
+	if err != nil {
+		t.Fatalf("EvalExisting: %v", err)
+	}
+	if want := filepath.Join(dir, "missing", "child", "file"); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if _, err := EvalExisting(filepath.Join(dir, "loop")); err == nil {
+		t.Error("expected error for symlink loop")
+	}
+}
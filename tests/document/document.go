@@ -2,7 +2,6 @@ package main
 
 import (
 	"MoonAgent/cmd/di"
-	"MoonAgent/pkg/splitter"
 	"context"
 	"fmt"
 	"os"
@@ -27,21 +26,10 @@ func main() {
 			Content: string(content),
 		},
 	}
-	docs, err := splitter.SplitDocs(ctx, app.Embedder, document)
+	// 按知识库的切分配置切分并写入
+	ids, err := app.KnowledgeBases.Ingest(ctx, app.KnowledgeBases.DefaultName(), document)
 	if err != nil {
 		panic(err)
 	}
-	batchsize := 10
-	for i := 0; i < len(docs); i += batchsize {
-		end := i + batchsize
-		if end > len(docs) {
-			end = len(docs)
-		}
-		batch := docs[i:end]
-		_, err = app.Indexer.Store(ctx, batch)
-		if err != nil {
-			panic(err)
-		}
-	fmt.Println("Document stored successfully")
-	}
+	fmt.Printf("Document stored successfully, %d chunks\n", len(ids))
 }
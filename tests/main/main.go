@@ -14,7 +14,7 @@ func main() {
 	}
 	defer clear()
 	userInput := "你好，可以告诉我缪尔赛思的源石技艺适应性是什么吗"
-	ctx := context.WithValue(context.Background(), "user_input", userInput)
+	ctx := context.Background()
 
 	runnable, err := pipeline.BuildAssitant(ctx, app)
 	if err != nil {